@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"dev-support-schedule/pkg"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// bot запускает Telegram-бота до SIGINT или SIGTERM. Каталог данных блокируется только
+// на время отдельных команд, поэтому CLI можно пользоваться параллельно.
+func (a *app) bot(args []string) error {
+	fs := a.newFlagSet("bot")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	token, err := a.telegramToken()
+	if err != nil {
+		return err
+	}
+	if len(a.config.Telegram.Admins) == 0 {
+		fmt.Fprintln(a.stderr, "В настройках нет чатов с администраторами (telegram.admins): бот будет игнорировать все сообщения.")
+	}
+
+	repo, err := a.openRepo()
+	if err != nil {
+		return err
+	}
+	service := pkg.NewService(repo, a.config, a.dataDir)
+	service.Teams = a.teams()
+	api := pkg.NewTelegramClient(a.config.Telegram.APIURL, token)
+	bot, err := pkg.NewBot(api, service, a.config.Telegram, a.configDir)
+	if err != nil {
+		return err
+	}
+	bot.Log = a.stderr
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintln(a.stdout, "Бот запущен. Для остановки нажмите Ctrl+C.")
+	if err := bot.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	fmt.Fprintln(a.stdout, "Бот остановлен.")
+	return nil
+}
+
+// telegramToken возвращает токен бота из переменной окружения или раздела telegram настроек.
+func (a *app) telegramToken() (string, error) {
+	token := a.config.Telegram.Token
+	if env := os.Getenv(pkg.TelegramTokenEnv); env != "" {
+		token = env
+	}
+	if token == "" {
+		return "", fmt.Errorf("не задан токен бота: укажите telegram.token в настройках или переменную %s", pkg.TelegramTokenEnv)
+	}
+	return token, nil
+}
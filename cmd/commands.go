@@ -0,0 +1,1026 @@
+package main
+
+import (
+	"context"
+	"dev-support-schedule/pkg"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// app хранит настройки хранилища и загруженное состояние, общее для всех команд.
+type app struct {
+	dataDir string // каталог выбранной команды
+	rootDir string // каталог данных из --data, в нем основная команда и каталог teams
+	team    string // имя команды из --team, пусто - основная
+	store   string // storeJSON или storeSQLite
+	dbPath  string
+	config  *pkg.Config
+	// configDir - каталог файла настроек, от него считаются пути в настройках
+	configDir string
+
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	clock  pkg.Clock
+
+	repo           pkg.Repository
+	lock           *pkg.DataLock
+	employees      *[]pkg.Employee
+	historyStorage *pkg.DutyHistoryStorage
+
+	// журнал изменений: reason - причина из --reason, command - выполняемая команда,
+	// saved - данные на момент загрузки или последнего сохранения, с ними сравнивается сохраняемое.
+	reason  string
+	command string
+	saved   *pkg.State
+}
+
+// openRepo открывает хранилище, выбранное флагом --store.
+func (a *app) openRepo() (pkg.Repository, error) {
+	if a.repo != nil {
+		return a.repo, nil
+	}
+
+	switch a.store {
+	case storeJSON:
+		a.repo = pkg.NewJSONRepository(a.dataDir)
+	case storeSQLite:
+		repo, err := pkg.OpenSQLiteRepository(a.dbPath)
+		if err != nil {
+			return nil, err
+		}
+		a.repo = repo
+	default:
+		return nil, fmt.Errorf("%w: неизвестное хранилище %q (json, sqlite)", errUsage, a.store)
+	}
+	return a.repo, nil
+}
+
+func (a *app) close() error {
+	var err error
+	if a.repo != nil {
+		if closeErr := a.repo.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if unlockErr := a.lock.Unlock(); err == nil {
+		err = unlockErr
+	}
+	return err
+}
+
+// lockData берет блокировку каталога данных до завершения команды.
+func (a *app) lockData() error {
+	if a.lock != nil {
+		return nil
+	}
+	lock, err := pkg.LockDataDir(a.dataDir)
+	if err != nil {
+		return err
+	}
+	a.lock = lock
+	return nil
+}
+
+// loadForUpdate блокирует каталог данных и загружает состояние. Блокировка держится
+// до выхода из программы, поэтому другой экземпляр не сможет сохранить данные поверх наших.
+func (a *app) loadForUpdate() error {
+	if err := a.lockData(); err != nil {
+		return err
+	}
+	return a.load()
+}
+
+// load загружает сотрудников и историю дежурств без блокировки: файлы перезаписываются атомарно,
+// поэтому для чтения она не нужна.
+func (a *app) load() error {
+	repo, err := a.openRepo()
+	if err != nil {
+		return err
+	}
+
+	employees, err := repo.LoadEmployees()
+	if err != nil {
+		return err
+	}
+
+	historyStorage, err := repo.LoadDutyHistory()
+	if err != nil {
+		return fmt.Errorf("при попытке загрузить историю дежурств произошла ошибка: %w", err)
+	}
+
+	saved, err := pkg.CloneState(&pkg.State{Employees: employees, History: historyStorage})
+	if err != nil {
+		return err
+	}
+
+	a.employees = employees
+	a.historyStorage = historyStorage
+	a.saved = saved
+	return nil
+}
+
+// newScheduler создает планировщик, считающий время по clock.
+func (a *app) newScheduler(clock pkg.Clock) *pkg.Scheduler {
+	scheduler := a.config.NewScheduler(clock)
+	scheduler.Teams = a.teams()
+	return scheduler
+}
+
+// save сохраняет сотрудников и историю вместе с событиями журнала об изменениях с прошлого сохранения.
+// Журнал пишется не позже данных, поэтому сохраненное изменение не останется без записи в нем.
+func (a *app) save() error {
+	reason := a.reason
+	if reason == "" {
+		reason = a.command
+	}
+	current := &pkg.State{Employees: a.employees, History: a.historyStorage}
+	saved, err := pkg.CloneState(current)
+	if err != nil {
+		return err
+	}
+	if err := a.repo.Save(current, pkg.DiffEvents(a.saved, current, pkg.OSActor(reason), a.clock)); err != nil {
+		return err
+	}
+	a.saved = saved
+	return nil
+}
+
+// newFlagSet создает набор флагов подкоманды, ошибки которого возвращаются как errUsage.
+func (a *app) newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.stdout)
+	return fs
+}
+
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return fmt.Errorf("%w: %s", errUsage, err)
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("%w: лишние аргументы: %s", errUsage, strings.Join(fs.Args(), " "))
+	}
+	return nil
+}
+
+// parseDate разбирает дату в формате YYYY-MM-DD.
+func parseDate(value string) (time.Time, error) {
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: неверный формат даты %q, ожидается YYYY-MM-DD", errUsage, value)
+	}
+	return date, nil
+}
+
+// parseWeek разбирает дату в формате YYYY-MM-DD и возвращает понедельник этой недели.
+func parseWeek(value string) (time.Time, error) {
+	date, err := parseDate(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return pkg.WeekStart(date), nil
+}
+
+// renderer возвращает рендерер по шаблону из флага --template или, если он не задан, из настроек.
+// Путь к файлу шаблона во флаге считается от текущего каталога.
+func (a *app) renderer(name string) (*pkg.Renderer, error) {
+	if name == "" {
+		return a.config.Renderer, nil
+	}
+	return pkg.NewRenderer(name, "")
+}
+
+// webhookPublisher возвращает публикатор из раздела publish настроек.
+func (a *app) webhookPublisher() (*pkg.WebhookPublisher, error) {
+	publisher := pkg.NewWebhookPublisher(a.config.Publish)
+	if publisher == nil {
+		return nil, fmt.Errorf("не задан адрес webhook: укажите publish.webhook_url в настройках или переменную %s", pkg.WebhookURLEnv)
+	}
+	return publisher, nil
+}
+
+// publishSchedule публикует расписание недели через webhook и сохраняет отметку о публикации.
+// При dryRun только выводит тело запроса.
+func (a *app) publishSchedule(renderer *pkg.Renderer, schedule pkg.WeekSchedule, dryRun, force bool) error {
+	publisher, err := a.webhookPublisher()
+	if err != nil {
+		return err
+	}
+	message, err := renderer.Render(schedule)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		payload, err := publisher.Payload(message)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.stdout, "POST %s\n%s\n", publisher.Target(), payload)
+		return nil
+	}
+
+	if err := pkg.PublishWeek(context.Background(), publisher, a.historyStorage, schedule.Start, message, force, a.clock); err != nil {
+		return err
+	}
+	if err := a.save(); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "Расписание опубликовано в %s.\n", publisher.Target())
+	return nil
+}
+
+// publishRenderer возвращает рендерер для публикации: из флага --template или из раздела publish настроек.
+func (a *app) publishRenderer(name string) (*pkg.Renderer, error) {
+	if name == "" {
+		return a.config.PublishRenderer, nil
+	}
+	return pkg.NewRenderer(name, "")
+}
+
+// generateSchedule формирует расписание, сбрасывает счетчики при необходимости и сохраняет результат.
+// Возвращает сформированное расписание, чтобы его можно было сразу опубликовать.
+func (a *app) generateSchedule(scheduler *pkg.Scheduler, renderer *pkg.Renderer, dryRun bool) (pkg.WeekSchedule, error) {
+	schedule, reseted, err := scheduler.GenerateWeek(a.employees, a.historyStorage)
+	if err != nil {
+		return pkg.WeekSchedule{}, err
+	}
+	message, err := renderer.Render(schedule)
+	if err != nil {
+		return pkg.WeekSchedule{}, err
+	}
+	fmt.Fprintln(a.stdout, message)
+
+	if dryRun {
+		return schedule, nil
+	}
+
+	if reseted {
+		fmt.Fprintln(a.stdout, "Счетчики дежурств сброшены.")
+	}
+	return schedule, a.save()
+}
+
+func (a *app) scheduleGenerate(args []string) error {
+	fs := a.newFlagSet("schedule generate")
+	week := fs.String("week", "", "понедельник недели в формате YYYY-MM-DD (по умолчанию следующая неделя)")
+	dryRun := fs.Bool("dry-run", false, "только показать расписание, ничего не сохраняя")
+	templateName := fs.String("template", "", "шаблон сообщения: markdown, slack, telegram, plain, html или путь к файлу")
+	publish := fs.Bool("publish", false, "опубликовать расписание через webhook (с --dry-run показать запрос)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	renderer, err := a.renderer(*templateName)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errUsage, err)
+	}
+	publishRenderer, err := a.publishRenderer(*templateName)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errUsage, err)
+	}
+	if *publish {
+		// адрес webhook проверяем до генерации, чтобы не сохранить неопубликованное расписание
+		if _, err := a.webhookPublisher(); err != nil {
+			return err
+		}
+	}
+
+	scheduler := a.newScheduler(a.clock)
+	if *week != "" {
+		monday, err := parseWeek(*week)
+		if err != nil {
+			return err
+		}
+		scheduler = a.newScheduler(pkg.FixedClock(monday))
+	}
+
+	load := a.loadForUpdate
+	if *dryRun {
+		load = a.load
+	}
+	if err := load(); err != nil {
+		return err
+	}
+	if len(*a.employees) == 0 {
+		return errors.New("список сотрудников пуст. Сначала добавьте сотрудников")
+	}
+
+	schedule, err := a.generateSchedule(scheduler, renderer, *dryRun)
+	if err != nil || !*publish {
+		return err
+	}
+	return a.publishSchedule(publishRenderer, schedule, *dryRun, false)
+}
+
+func (a *app) schedulePublish(args []string) error {
+	fs := a.newFlagSet("schedule publish")
+	week := fs.String("week", "", "понедельник недели в формате YYYY-MM-DD (по умолчанию следующая неделя)")
+	dryRun := fs.Bool("dry-run", false, "только показать запрос, ничего не отправляя")
+	force := fs.Bool("force", false, "опубликовать повторно, даже если неделя уже опубликована")
+	templateName := fs.String("template", "", "шаблон сообщения: markdown, slack, telegram, plain, html или путь к файлу")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	renderer, err := a.publishRenderer(*templateName)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errUsage, err)
+	}
+
+	scheduler := a.newScheduler(a.clock)
+	if *week != "" {
+		monday, err := parseWeek(*week)
+		if err != nil {
+			return err
+		}
+		scheduler = a.newScheduler(pkg.FixedClock(monday))
+	}
+
+	load := a.loadForUpdate
+	if *dryRun {
+		load = a.load
+	}
+	if err := load(); err != nil {
+		return err
+	}
+
+	record, ok := a.historyStorage.FindWeek(scheduler.NextWeek())
+	if !ok {
+		return fmt.Errorf("расписание на неделю с %s еще не сформировано", scheduler.NextWeek().Format("2006-01-02"))
+	}
+	schedule := scheduler.HistorySchedule(*record)
+	return a.publishSchedule(renderer, schedule, *dryRun, *force)
+}
+
+func (a *app) schedulePlan(args []string) error {
+	fs := a.newFlagSet("schedule plan")
+	weeks := fs.Int("weeks", 4, "на сколько недель вперед планировать")
+	from := fs.String("from", "", "первая неделя плана YYYY-MM-DD (по умолчанию следующая неделя)")
+	dryRun := fs.Bool("dry-run", false, "только показать план, ничего не сохраняя")
+	templateName := fs.String("template", "", "шаблон сообщения: markdown, slack, telegram, plain, html или путь к файлу")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	renderer, err := a.renderer(*templateName)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errUsage, err)
+	}
+	if *weeks <= 0 {
+		return fmt.Errorf("%w: --weeks должно быть положительным", errUsage)
+	}
+
+	scheduler := a.newScheduler(a.clock)
+	if *from != "" {
+		monday, err := parseWeek(*from)
+		if err != nil {
+			return err
+		}
+		scheduler = a.newScheduler(pkg.FixedClock(monday))
+	}
+
+	load := a.loadForUpdate
+	if *dryRun {
+		load = a.load
+	}
+	if err := load(); err != nil {
+		return err
+	}
+	if len(*a.employees) == 0 {
+		return errors.New("список сотрудников пуст. Сначала добавьте сотрудников")
+	}
+
+	plans, err := scheduler.PlanWeeks(a.employees, a.historyStorage, *weeks)
+	if err != nil {
+		return err
+	}
+	for _, plan := range plans {
+		if plan.Skipped {
+			fmt.Fprintf(a.stdout, "Неделя с %s уже есть в истории, пропущена.\n\n", plan.Week.Format("2006-01-02"))
+			continue
+		}
+		message, err := renderer.Render(plan.Schedule)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.stdout, "%s\n\n", message)
+	}
+
+	if *dryRun {
+		return nil
+	}
+	return a.save()
+}
+
+func (a *app) scheduleConfirm(args []string) error {
+	fs := a.newFlagSet("schedule confirm")
+	week := fs.String("week", "", "понедельник утверждаемой недели YYYY-MM-DD")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	monday, err := parseWeek(*week)
+	if err != nil {
+		return err
+	}
+
+	if err := a.loadForUpdate(); err != nil {
+		return err
+	}
+	reseted, err := a.newScheduler(a.clock).ConfirmWeek(a.employees, a.historyStorage, monday)
+	if err != nil {
+		return err
+	}
+	if err := a.save(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.stdout, "Неделя с %s утверждена.\n", monday.Format("2006-01-02"))
+	if reseted {
+		fmt.Fprintln(a.stdout, "Счетчики дежурств сброшены.")
+	}
+	return nil
+}
+
+func (a *app) scheduleExplain(args []string) error {
+	fs := a.newFlagSet("schedule explain")
+	week := fs.String("week", "", "понедельник недели в формате YYYY-MM-DD (по умолчанию следующая неделя)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	scheduler := a.newScheduler(a.clock)
+	if *week != "" {
+		monday, err := parseWeek(*week)
+		if err != nil {
+			return err
+		}
+		scheduler = a.newScheduler(pkg.FixedClock(monday))
+	}
+
+	if err := a.load(); err != nil {
+		return err
+	}
+	record, ok := a.historyStorage.FindWeek(scheduler.NextWeek())
+	if !ok {
+		return fmt.Errorf("расписание на неделю с %s еще не сформировано", scheduler.NextWeek().Format("2006-01-02"))
+	}
+
+	fmt.Fprint(a.stdout, scheduler.Explain(*record))
+	return nil
+}
+
+// scheduleCompare составляет следующую неделю жадным подбором и оптимизатором, не сохраняя ее,
+// и выводит оба расписания с оценками по ограничениям и штрафам оптимизатора.
+func (a *app) scheduleCompare(args []string) error {
+	fs := a.newFlagSet("schedule compare")
+	week := fs.String("week", "", "понедельник недели в формате YYYY-MM-DD (по умолчанию следующая неделя)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	scheduler, err := a.weekScheduler(*week)
+	if err != nil {
+		return err
+	}
+	if err := a.load(); err != nil {
+		return err
+	}
+
+	results, err := scheduler.CompareSolvers(a.employees, a.historyStorage)
+	if err != nil {
+		return err
+	}
+	weights := a.config.Solver.Weights
+	for n, result := range results {
+		if n > 0 {
+			fmt.Fprintln(a.stdout)
+		}
+		fmt.Fprintf(a.stdout, "Движок %s, неделя с %s:\n", result.Engine, scheduler.NextWeek().Format("2006-01-02"))
+		if result.Err != nil {
+			fmt.Fprintf(a.stdout, "  не удалось составить расписание: %s\n", result.Err)
+			continue
+		}
+		for _, assignment := range result.Schedule.Assignments {
+			fmt.Fprintf(a.stdout, "  %s | %s | %s\n", assignment.Date.Format("2006-01-02"), scheduler.DutyTitle(assignment.Duty), assignment.EmployeeName)
+		}
+		score := result.Score
+		fmt.Fprintf(a.stdout, "  Нарушений жестких ограничений: %d\n", score.Hard)
+		for _, violation := range score.Violations {
+			fmt.Fprintf(a.stdout, "    - %s\n", violation)
+		}
+		fmt.Fprintf(a.stdout, "  Назначений в обход перерыва: %d\n", score.Cooldown)
+		for _, note := range score.CooldownBreak {
+			fmt.Fprintf(a.stdout, "    - %s\n", note)
+		}
+		fmt.Fprintf(a.stdout, "  Штраф: %.2f (неравномерность %.2f x %g, подряд две недели %d x %g, пожелания %d x %g)\n",
+			score.Penalty, score.Fairness, weights.Fairness, score.BackToBack, weights.BackToBack, score.Preference, weights.Preference)
+	}
+	return nil
+}
+
+func (a *app) employeeList(args []string) error {
+	if err := parseFlags(a.newFlagSet("employee list"), args); err != nil {
+		return err
+	}
+	if err := a.load(); err != nil {
+		return err
+	}
+
+	fmt.Fprint(a.stdout, a.newScheduler(a.clock).AllEmployees(a.employees))
+	return nil
+}
+
+func (a *app) employeeAdd(args []string) error {
+	fs := a.newFlagSet("employee add")
+	name := fs.String("name", "", "имя и фамилия сотрудника")
+	member := fs.String("member", "", "ключ, общий с записями сотрудника в других командах")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if strings.TrimSpace(*name) == "" {
+		return fmt.Errorf("%w: не указано имя сотрудника (--name)", errUsage)
+	}
+
+	if err := a.loadForUpdate(); err != nil {
+		return err
+	}
+
+	a.newScheduler(a.clock).AddNewEmployee(a.employees, strings.TrimSpace(*name))
+	if *member != "" {
+		added := (*a.employees)[len(*a.employees)-1]
+		if err := pkg.LinkEmployeeMember(a.employees, added.Id, *member); err != nil {
+			return err
+		}
+	}
+	if err := a.save(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(a.stdout, "Сотрудник успешно добавлен.")
+	return nil
+}
+
+func (a *app) employeeLink(args []string) error {
+	fs := a.newFlagSet("employee link")
+	id := fs.Int("id", 0, "Id сотрудника")
+	telegramUserId := fs.Int64("telegram", 0, "id пользователя Telegram (0 - отвязать)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *id == 0 {
+		return fmt.Errorf("%w: не указан Id сотрудника (--id)", errUsage)
+	}
+
+	if err := a.loadForUpdate(); err != nil {
+		return err
+	}
+	if err := pkg.LinkEmployeeTelegram(a.employees, *id, *telegramUserId); err != nil {
+		return err
+	}
+	if err := a.save(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(a.stdout, "Привязка к Telegram обновлена.")
+	return nil
+}
+
+func (a *app) employeeMember(args []string) error {
+	fs := a.newFlagSet("employee member")
+	id := fs.Int("id", 0, "Id сотрудника")
+	member := fs.String("member", "", "ключ, общий с записями сотрудника в других командах (пусто - снять связь)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *id <= 0 {
+		return fmt.Errorf("%w: не указан Id сотрудника (--id)", errUsage)
+	}
+
+	if err := a.loadForUpdate(); err != nil {
+		return err
+	}
+	if err := pkg.LinkEmployeeMember(a.employees, *id, *member); err != nil {
+		return err
+	}
+	if err := a.save(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(a.stdout, "Связь с другими командами обновлена.")
+	return nil
+}
+
+// employeePrefs показывает или меняет пожелания сотрудника. Меняются только указанные флаги,
+// без флагов пожелания выводятся как есть.
+func (a *app) employeePrefs(args []string) error {
+	fs := a.newFlagSet("employee prefs")
+	id := fs.Int("id", 0, "Id сотрудника")
+	blackouts := fs.String("blackout", "", "дни недели через запятую, в которые сотрудник не дежурит (monday...sunday)")
+	preferred := fs.String("prefer", "", "дежурства через запятую, которые сотрудник предпочитает")
+	avoided := fs.String("avoid", "", "дежурства через запятую, которых сотрудник просит избегать")
+	avoidedDays := fs.String("avoid-days", "", "дни недели через запятую, в которые сотрудника по возможности не ставить")
+	maxPerMonth := fs.Int("max-per-month", 0, "не больше N дежурств в календарный месяц (0 - без ограничения)")
+	clearAll := fs.Bool("clear", false, "удалить все пожелания")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *id <= 0 {
+		return fmt.Errorf("%w: не указан Id сотрудника (--id)", errUsage)
+	}
+	changed := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { changed[f.Name] = true })
+	delete(changed, "id")
+
+	if len(changed) == 0 {
+		if err := a.load(); err != nil {
+			return err
+		}
+		employee := pkg.FindEmployeeById(a.employees, *id)
+		if employee == nil {
+			return fmt.Errorf("сотрудник с Id: %d не найден", *id)
+		}
+		fmt.Fprintf(a.stdout, "%s: %s\n", employee.Name, a.newScheduler(a.clock).FormatPreferences(employee.Preferences))
+		return nil
+	}
+
+	if err := a.loadForUpdate(); err != nil {
+		return err
+	}
+	employee := pkg.FindEmployeeById(a.employees, *id)
+	if employee == nil {
+		return fmt.Errorf("сотрудник с Id: %d не найден", *id)
+	}
+	var preferences pkg.Preferences
+	if employee.Preferences != nil && !*clearAll {
+		preferences = *employee.Preferences
+	}
+	if changed["blackout"] {
+		preferences.Blackouts = splitList(*blackouts)
+	}
+	if changed["prefer"] {
+		preferences.Preferred = splitList(*preferred)
+	}
+	if changed["avoid"] {
+		preferences.Avoided = splitList(*avoided)
+	}
+	if changed["avoid-days"] {
+		preferences.AvoidedDays = splitList(*avoidedDays)
+	}
+	if changed["max-per-month"] {
+		preferences.MaxPerMonth = *maxPerMonth
+	}
+
+	scheduler := a.newScheduler(a.clock)
+	if err := scheduler.SetEmployeePreferences(a.employees, *id, preferences); err != nil {
+		return err
+	}
+	if err := a.save(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.stdout, "Пожелания сотрудника обновлены: %s\n", scheduler.FormatPreferences(employee.Preferences))
+	return nil
+}
+
+// employeeSkills показывает или меняет навыки сотрудника, от которых зависит допуск к дежурствам.
+func (a *app) employeeSkills(args []string) error {
+	fs := a.newFlagSet("employee skills")
+	id := fs.Int("id", 0, "Id сотрудника")
+	add := fs.String("add", "", "навыки через запятую, которые нужно добавить")
+	remove := fs.String("remove", "", "навыки через запятую, которые нужно убрать")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *id <= 0 {
+		return fmt.Errorf("%w: не указан Id сотрудника (--id)", errUsage)
+	}
+
+	if *add == "" && *remove == "" {
+		if err := a.load(); err != nil {
+			return err
+		}
+		employee := pkg.FindEmployeeById(a.employees, *id)
+		if employee == nil {
+			return fmt.Errorf("сотрудник с Id: %d не найден", *id)
+		}
+		fmt.Fprintf(a.stdout, "%s: %s\n", employee.Name, a.newScheduler(a.clock).FormatSkills(*employee))
+		return nil
+	}
+
+	if err := a.loadForUpdate(); err != nil {
+		return err
+	}
+	employee := pkg.FindEmployeeById(a.employees, *id)
+	if employee == nil {
+		return fmt.Errorf("сотрудник с Id: %d не найден", *id)
+	}
+	removed := map[string]bool{}
+	for _, skill := range splitList(*remove) {
+		removed[skill] = true
+	}
+	var skills []string
+	for _, skill := range employee.Skills {
+		if !removed[skill] {
+			skills = append(skills, skill)
+		}
+	}
+	skills = append(skills, splitList(*add)...)
+
+	scheduler := a.newScheduler(a.clock)
+	if err := scheduler.SetEmployeeSkills(a.employees, *id, skills); err != nil {
+		return err
+	}
+	if err := a.save(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.stdout, "Навыки сотрудника обновлены: %s\n", scheduler.FormatSkills(*employee))
+	return nil
+}
+
+// splitList разбирает список через запятую без пустых элементов.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (a *app) employeeStatus(args []string) error {
+	fs := a.newFlagSet("employee status")
+	id := fs.Int("id", 0, "Id сотрудника")
+	status := fs.String("status", "", "новый статус (available, sick, vacation, fired)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *id <= 0 {
+		return fmt.Errorf("%w: не указан Id сотрудника (--id)", errUsage)
+	}
+	if !pkg.ValidStatus(*status) {
+		return fmt.Errorf("%w: неизвестный статус %q", errUsage, *status)
+	}
+
+	if err := a.loadForUpdate(); err != nil {
+		return err
+	}
+
+	if err := a.newScheduler(a.clock).UpdateEmployeeStatus(a.employees, *id, *status); err != nil {
+		return err
+	}
+	if err := a.save(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(a.stdout, "Статус сотрудника успешно обновлен.")
+	return nil
+}
+
+func (a *app) absenceAdd(args []string) error {
+	fs := a.newFlagSet("employee absence add")
+	id := fs.Int("id", 0, "Id сотрудника")
+	kind := fs.String("kind", pkg.StatusVacation, "вид отсутствия (sick, vacation)")
+	from := fs.String("from", "", "первый день отсутствия YYYY-MM-DD")
+	to := fs.String("to", "", "последний день отсутствия YYYY-MM-DD (пусто - бессрочно)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *id <= 0 {
+		return fmt.Errorf("%w: не указан Id сотрудника (--id)", errUsage)
+	}
+
+	absence := pkg.Absence{Kind: *kind}
+	var err error
+	if absence.Start, err = parseDate(*from); err != nil {
+		return err
+	}
+	if *to != "" {
+		if absence.End, err = parseDate(*to); err != nil {
+			return err
+		}
+	}
+
+	if err := a.loadForUpdate(); err != nil {
+		return err
+	}
+	if err := pkg.AddEmployeeAbsence(a.employees, *id, absence); err != nil {
+		return err
+	}
+	if err := a.save(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(a.stdout, "Отсутствие добавлено.")
+	return nil
+}
+
+func (a *app) absenceList(args []string) error {
+	fs := a.newFlagSet("employee absence list")
+	id := fs.Int("id", 0, "Id сотрудника (по умолчанию все)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := a.load(); err != nil {
+		return err
+	}
+
+	for _, employee := range *a.employees {
+		if *id != 0 && employee.Id != *id {
+			continue
+		}
+		for i, absence := range employee.Absences {
+			end := "бессрочно"
+			if !absence.OpenEnded() {
+				end = absence.End.Format("2006-01-02")
+			}
+			fmt.Fprintf(a.stdout, "%d. %s [%d] %s: %s – %s\n", employee.Id, employee.Name, i, absence.Kind, absence.Start.Format("2006-01-02"), end)
+		}
+	}
+	return nil
+}
+
+func (a *app) absenceRemove(args []string) error {
+	fs := a.newFlagSet("employee absence remove")
+	id := fs.Int("id", 0, "Id сотрудника")
+	index := fs.Int("index", -1, "номер отсутствия из employee absence list")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *id <= 0 || *index < 0 {
+		return fmt.Errorf("%w: нужно указать --id и --index", errUsage)
+	}
+
+	if err := a.loadForUpdate(); err != nil {
+		return err
+	}
+	if err := pkg.RemoveEmployeeAbsence(a.employees, *id, *index); err != nil {
+		return err
+	}
+	if err := a.save(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(a.stdout, "Отсутствие удалено.")
+	return nil
+}
+
+func (a *app) historyList(args []string) error {
+	if err := parseFlags(a.newFlagSet("history list"), args); err != nil {
+		return err
+	}
+	if err := a.load(); err != nil {
+		return err
+	}
+
+	a.printHistory()
+	return nil
+}
+
+// printHistory выводит дату последнего сброса счетчиков и все сохраненные недели.
+func (a *app) printHistory() {
+	scheduler := a.newScheduler(a.clock)
+	fmt.Fprintf(a.stdout, "Последний раз счетчики дежурств обнулялись %s\n\n", a.historyStorage.LastResetDate)
+
+	for _, record := range a.historyStorage.History {
+		status := ""
+		if record.Planned() {
+			status = " (запланировано)"
+		}
+		if record.Publication != nil {
+			status += fmt.Sprintf(" (опубликовано %s)", record.Publication.PublishedAt.Format("2006-01-02 15:04"))
+		}
+		fmt.Fprintf(a.stdout, "История за период с %s до %s%s\n", record.Date, record.Date.AddDate(0, 0, 4), status)
+		for _, assignment := range record.Assignments {
+			fmt.Fprintf(a.stdout, "%s | %s | %s\n", assignment.Date.Format("2006-01-02"), scheduler.DutyTitle(assignment.Duty), assignment.EmployeeName)
+		}
+	}
+}
+
+func (a *app) countersReset(args []string) error {
+	fs := a.newFlagSet("counters reset")
+	force := fs.Bool("force", false, "сбросить счетчики, даже если с прошлого сброса не прошло 90 дней")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := a.loadForUpdate(); err != nil {
+		return err
+	}
+
+	scheduler := a.newScheduler(a.clock)
+	if *force {
+		if !scheduler.ForceResetDutyCounters(a.employees, a.historyStorage) {
+			fmt.Fprintln(a.stdout, "Ни у одного дежурства нет reset_counters: нагрузка считается по окну справедливости, счетчики не сбрасываются.")
+			return nil
+		}
+	} else if !scheduler.ResetDutyCounters(a.employees, a.historyStorage) {
+		fmt.Fprintf(a.stdout, "С последнего сброса (%s) не прошло 90 дней, счетчики не изменены.\n", a.historyStorage.LastResetDate.Format("2006-01-02"))
+		// ResetDutyCounters мог впервые проставить дату сброса, сохраняем ее
+		return a.save()
+	}
+
+	if err := a.save(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(a.stdout, "Счетчики дежурств сброшены.")
+	return nil
+}
+
+// countersRecompute пересчитывает счетчики и даты последних дежурств по истории и показывает
+// расхождения с сохраненными; с --apply пересчитанные значения сохраняются.
+func (a *app) countersRecompute(args []string) error {
+	fs := a.newFlagSet("counters recompute")
+	from := fs.String("from", "", "считать дежурства с reset_counters начиная с даты YYYY-MM-DD (по умолчанию с последнего сброса счетчиков)")
+	to := fs.String("to", "", "считать дежурства по дату YYYY-MM-DD включительно (по умолчанию вся история)")
+	all := fs.Bool("all", false, "считать дежурства за всю историю, без учета сброса счетчиков")
+	apply := fs.Bool("apply", false, "сохранить пересчитанные значения")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *all && *from != "" {
+		return fmt.Errorf("%w: --all и --from нельзя указывать вместе", errUsage)
+	}
+
+	var fromDate, toDate time.Time
+	var err error
+	if *from != "" {
+		if fromDate, err = parseDate(*from); err != nil {
+			return err
+		}
+	}
+	if *to != "" {
+		if toDate, err = parseDate(*to); err != nil {
+			return err
+		}
+	}
+
+	if *apply {
+		err = a.loadForUpdate()
+	} else {
+		err = a.load()
+	}
+	if err != nil {
+		return err
+	}
+	if *from == "" && !*all {
+		fromDate = a.historyStorage.LastResetDate
+	}
+
+	scheduler := a.newScheduler(a.clock)
+	changes := scheduler.RecomputeStats(a.employees, a.historyStorage, fromDate, toDate)
+	if len(changes) == 0 {
+		fmt.Fprintln(a.stdout, "Счетчики и даты последних дежурств совпадают с историей.")
+		return nil
+	}
+	fmt.Fprint(a.stdout, scheduler.FormatStatsChanges(changes))
+
+	if !*apply {
+		fmt.Fprintln(a.stdout, "\nЧтобы сохранить пересчитанные значения, запустите команду с --apply.")
+		return nil
+	}
+	if err := a.save(); err != nil {
+		return err
+	}
+	fmt.Fprintln(a.stdout, "\nПересчитанные значения сохранены.")
+	return nil
+}
+
+// migrateSQLite переносит данные из JSON-файлов каталога --data в базу --db.
+func (a *app) migrateSQLite(args []string) error {
+	fs := a.newFlagSet("migrate sqlite")
+	force := fs.Bool("force", false, "перезаписать данные, если база уже не пуста")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if err := a.lockData(); err != nil {
+		return err
+	}
+
+	to, err := pkg.OpenSQLiteRepository(a.dbPath)
+	if err != nil {
+		return err
+	}
+	defer to.Close()
+
+	empty, err := to.Empty()
+	if err != nil {
+		return err
+	}
+	if !empty && !*force {
+		return fmt.Errorf("база %s уже содержит данные, используйте --force, чтобы перезаписать их", a.dbPath)
+	}
+
+	if err := pkg.MigrateJSONToSQLite(pkg.NewJSONRepository(a.dataDir), to); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.stdout, "Данные из %s перенесены в %s. Запускайте команды с --store sqlite.\n", a.dataDir, a.dbPath)
+	return nil
+}
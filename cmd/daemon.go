@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"dev-support-schedule/pkg"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// daemon формирует и публикует расписание по daemon.schedule до SIGINT или SIGTERM.
+// С --once только догоняет последний запуск по расписанию, если он был пропущен, и завершается.
+func (a *app) daemon(args []string) error {
+	fs := a.newFlagSet("daemon")
+	once := fs.Bool("once", false, "выполнить последний запуск по расписанию и выйти")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	channels, err := a.daemonChannels()
+	if err != nil {
+		return err
+	}
+	repo, err := a.openRepo()
+	if err != nil {
+		return err
+	}
+	service := pkg.NewService(repo, a.config, a.dataDir)
+	service.Teams = a.teams()
+	daemon, err := pkg.NewDaemon(service, a.config.Daemon, channels)
+	if err != nil {
+		return err
+	}
+	daemon.Log = a.stdout
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *once {
+		now := daemon.Clock.Now()
+		due, ok := daemon.Schedule.Last(now.Add(-daemon.CatchUp), now)
+		if !ok {
+			fmt.Fprintln(a.stdout, "За последнее время запусков по расписанию не было.")
+			return nil
+		}
+		return daemon.RunOnce(ctx, due)
+	}
+
+	fmt.Fprintf(a.stdout, "Демон запущен, расписание %q. Для остановки нажмите Ctrl+C.\n", a.config.Daemon.Schedule)
+	if err := daemon.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	fmt.Fprintln(a.stdout, "Демон остановлен.")
+	return nil
+}
+
+// daemonChannels создает публикаторы для каналов из daemon.publish.
+func (a *app) daemonChannels() ([]pkg.DaemonChannel, error) {
+	var channels []pkg.DaemonChannel
+	for _, name := range a.config.Daemon.Publish {
+		switch name {
+		case pkg.ChannelWebhook:
+			publisher, err := a.webhookPublisher()
+			if err != nil {
+				return nil, err
+			}
+			channels = append(channels, pkg.DaemonChannel{Publisher: publisher, Renderer: a.config.PublishRenderer})
+		case pkg.ChannelTelegram:
+			token, err := a.telegramToken()
+			if err != nil {
+				return nil, err
+			}
+			if a.config.Daemon.TelegramChatId == 0 {
+				return nil, fmt.Errorf("не задан чат для публикации: укажите daemon.telegram_chat_id в настройках")
+			}
+			renderer, parseMode, err := pkg.TelegramRenderer(a.config.Telegram, a.configDir)
+			if err != nil {
+				return nil, err
+			}
+			publisher := &pkg.TelegramPublisher{
+				API:       pkg.NewTelegramClient(a.config.Telegram.APIURL, token),
+				ChatId:    a.config.Daemon.TelegramChatId,
+				ParseMode: parseMode,
+			}
+			channels = append(channels, pkg.DaemonChannel{Publisher: publisher, Renderer: renderer})
+		default:
+			return nil, fmt.Errorf("daemon.publish: неизвестный канал %q (webhook, telegram)", name)
+		}
+	}
+	return channels, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"dev-support-schedule/pkg"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// eventsList выводит журнал изменений с отбором по сотруднику, виду события и периоду.
+func (a *app) eventsList(args []string) error {
+	fs := a.newFlagSet("events list")
+	employeeId := fs.Int("employee", 0, "только события, затрагивающие сотрудника с этим Id")
+	eventType := fs.String("type", "", "вид события: "+strings.Join(pkg.EventTypes, ", "))
+	from := fs.String("from", "", "события начиная с даты YYYY-MM-DD")
+	to := fs.String("to", "", "события по дату YYYY-MM-DD включительно")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	filter := pkg.EventFilter{EmployeeId: *employeeId, Type: *eventType}
+	if filter.Type != "" {
+		if err := pkg.ValidEventType(filter.Type); err != nil {
+			return fmt.Errorf("%w: %s", errUsage, err)
+		}
+	}
+	var err error
+	if *from != "" {
+		if filter.From, err = parseDate(*from); err != nil {
+			return err
+		}
+	}
+	if *to != "" {
+		if filter.To, err = parseDate(*to); err != nil {
+			return err
+		}
+	}
+
+	repo, err := a.openRepo()
+	if err != nil {
+		return err
+	}
+	events, err := repo.LoadEvents(filter)
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		fmt.Fprintln(a.stdout, "Событий не найдено.")
+		return nil
+	}
+
+	for _, event := range events {
+		line := fmt.Sprintf("%s | %s | %s", event.At.Local().Format("2006-01-02 15:04:05"), event.Type, event.Actor)
+		if event.Reason != "" {
+			line += " | " + event.Reason
+		}
+		if event.Week != nil {
+			line += " | неделя с " + event.Week.Format("2006-01-02")
+		}
+		if len(event.Employees) > 0 {
+			ids := make([]string, len(event.Employees))
+			for i, id := range event.Employees {
+				ids[i] = strconv.Itoa(id)
+			}
+			line += " | сотрудники: " + strings.Join(ids, ", ")
+		}
+		fmt.Fprintln(a.stdout, line)
+		if len(event.Before) > 0 {
+			fmt.Fprintf(a.stdout, "  до:    %s\n", event.Before)
+		}
+		if len(event.After) > 0 {
+			fmt.Fprintf(a.stdout, "  после: %s\n", event.After)
+		}
+	}
+	return nil
+}
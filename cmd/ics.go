@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"dev-support-schedule/pkg"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+func (a *app) icsExport(args []string) error {
+	fs := a.newFlagSet("ics export")
+	employeeId := fs.Int("employee", 0, "Id сотрудника, чьи дежурства выгрузить (по умолчанию вся команда)")
+	from := fs.String("from", "", "выгрузить недели начиная с даты YYYY-MM-DD (по умолчанию вся история)")
+	output := fs.String("output", "", "файл календаря (по умолчанию стандартный вывод)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	options := pkg.ICSOptions{Name: "Дежурства", EmployeeId: *employeeId}
+	if *from != "" {
+		date, err := parseDate(*from)
+		if err != nil {
+			return err
+		}
+		options.From = date
+	}
+
+	if err := a.load(); err != nil {
+		return err
+	}
+	if *employeeId != 0 {
+		employee := pkg.FindEmployeeById(a.employees, *employeeId)
+		if employee == nil {
+			return fmt.Errorf("сотрудник с Id: %d не найден", *employeeId)
+		}
+		options.Name = "Дежурства: " + employee.Name
+	}
+
+	var calendar bytes.Buffer
+	if err := a.newScheduler(a.clock).WriteICS(&calendar, a.historyStorage, options); err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err := a.stdout.Write(calendar.Bytes())
+		return err
+	}
+	if err := os.WriteFile(*output, calendar.Bytes(), 0644); err != nil {
+		return fmt.Errorf("не удалось записать календарь: %w", err)
+	}
+	fmt.Fprintf(a.stdout, "Календарь сохранен в %s.\n", *output)
+	return nil
+}
+
+// icsServe раздает календари по HTTP до SIGINT или SIGTERM.
+func (a *app) icsServe(args []string) error {
+	fs := a.newFlagSet("ics serve")
+	addr := fs.String("addr", ":8080", "адрес HTTP-сервера")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	repo, err := a.openRepo()
+	if err != nil {
+		return err
+	}
+	service := pkg.NewService(repo, a.config, a.dataDir)
+	service.Teams = a.teams()
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           pkg.ICSHandler(service),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	fmt.Fprintf(a.stdout, "Календари доступны на %s: /calendar.ics - команда, /calendar/N.ics - сотрудник с Id N.\n", *addr)
+	return listenAndServe(server)
+}
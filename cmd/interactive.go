@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"dev-support-schedule/pkg"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// errQuit возвращается из choiceSwitcher, когда пользователь выбрал выход.
+var errQuit = errors.New("выход")
+
+func (a *app) displayMenu() {
+	fmt.Fprintf(a.stdout, "\n--------------------------------------------------------------------------------------\n")
+	fmt.Fprintln(a.stdout, "Добро пожаловать в программу расписания дежурств!")
+	fmt.Fprintln(a.stdout, "Выберите действие:")
+	fmt.Fprintln(a.stdout, "1. Сформировать расписание на следующую неделю")
+	fmt.Fprintln(a.stdout, "2. Обновить статус сотрудников (на больничном, в отпуске, доступен для дежурства)")
+	fmt.Fprintln(a.stdout, "3. Просмотреть историю дежурств")
+	fmt.Fprintln(a.stdout, "4. Добавить сотрудников")
+	fmt.Fprintln(a.stdout, "5. Выход")
+	fmt.Fprintf(a.stdout, "\n--------------------------------------------------------------------------------------\n")
+}
+
+// interactive запускает интерактивное меню с нумерованными действиями.
+// Каталог данных заблокирован, пока открыто меню.
+func (a *app) interactive() error {
+	if err := a.loadForUpdate(); err != nil {
+		return err
+	}
+
+	in := bufio.NewReader(a.stdin)
+	scheduler := a.newScheduler(a.clock)
+
+	if len(*a.employees) == 0 {
+		fmt.Fprintln(a.stdout, "Список сотрудников пуст. Сначала добавьте сотрудников.")
+		if err := a.choiceSwitcher(4, in, scheduler); err != nil {
+			return err
+		}
+	}
+
+	for {
+		a.displayMenu()
+
+		var choice int
+		if _, err := fmt.Fscan(in, &choice); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			// пропускаем нечисловой ввод до конца строки
+			in.ReadString('\n')
+			choice = 0
+		}
+
+		err := a.choiceSwitcher(choice, in, scheduler)
+		if errors.Is(err, errQuit) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (a *app) choiceSwitcher(choice int, in *bufio.Reader, scheduler *pkg.Scheduler) error {
+	switch choice {
+	case 1:
+		schedule, err := a.generateSchedule(scheduler, a.config.Renderer, false)
+		if err != nil {
+			return err
+		}
+
+		publisher := pkg.NewWebhookPublisher(a.config.Publish)
+		if publisher == nil {
+			return nil
+		}
+		fmt.Fprintf(a.stdout, "Опубликовать расписание в %s? (y/n)\n", publisher.Target())
+		var answer string
+		if _, err := fmt.Fscan(in, &answer); err != nil || !strings.EqualFold(answer, "y") {
+			return nil
+		}
+		if err := a.publishSchedule(a.config.PublishRenderer, schedule, false, false); err != nil {
+			// неудачная публикация не повод выходить из меню, расписание уже сохранено
+			fmt.Fprintln(a.stdout, err)
+		}
+	case 2:
+		// Здесь можно запросить имя сотрудника и новый статус, затем обновить его данные.
+
+		employeesStr := scheduler.AllEmployees(a.employees)
+		fmt.Fprintln(a.stdout, employeesStr)
+		fmt.Fprintln(a.stdout, "Введите ID сотрудника, статус которого хотите изменить:")
+		var employeeId int
+		if _, err := fmt.Fscan(in, &employeeId); err != nil {
+			return err
+		}
+		fmt.Fprintln(a.stdout, "Введите новый статус сотрудника (available, sick, vacation, fired):")
+		var newStatus string
+		if _, err := fmt.Fscan(in, &newStatus); err != nil {
+			return err
+		}
+		if err := scheduler.UpdateEmployeeStatus(a.employees, employeeId, newStatus); err != nil {
+			// опечатка в Id или статусе не повод завершать программу
+			fmt.Fprintln(a.stdout, err)
+			return nil
+		}
+		if err := a.save(); err != nil {
+			return err
+		}
+		fmt.Fprintln(a.stdout, "Статус сотрудника успешно обновлен.")
+	case 3:
+		// Просмотреть историю дежурств
+		a.printHistory()
+		fmt.Fprintln(a.stdout)
+		fmt.Fprintln(a.stdout)
+		fmt.Fprintln(a.stdout)
+	case 4:
+		// Добавление новых сотрудников
+		employeesStr := scheduler.AllEmployees(a.employees)
+		fmt.Fprintf(a.stdout, "%s\n", employeesStr)
+
+		fmt.Fprintln(a.stdout, "Введите имя нового сотрудника:")
+		line, err := in.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		// после Fscan в буфере мог остаться перевод строки от предыдущего ввода
+		if line == "\n" {
+			line, err = in.ReadString('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				return err
+			}
+		}
+		name := strings.Join(strings.Fields(line), " ")
+		if name == "" {
+			fmt.Fprintln(a.stdout, "Имя сотрудника не может быть пустым.")
+			return nil
+		}
+		scheduler.AddNewEmployee(a.employees, name)
+		if err := a.save(); err != nil {
+			return err
+		}
+		fmt.Fprintln(a.stdout, "Сотрудник успешно добавлен.")
+	case 5:
+		fmt.Fprintln(a.stdout, "Выход из программы...")
+		return errQuit
+	default:
+		fmt.Fprintln(a.stdout, "Неизвестный выбор. Пожалуйста, попробуйте снова.")
+	}
+
+	return nil
+}
@@ -2,144 +2,312 @@ package main
 
 import (
 	"dev-support-schedule/pkg"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
+// Поддерживаемые хранилища данных.
 const (
-	employeesFilePath = "data/employees.json"
-	historyFilePath   = "data/history.json"
+	storeJSON   = "json"
+	storeSQLite = "sqlite"
 )
 
-func displayMenu() {
-	fmt.Printf("\n--------------------------------------------------------------------------------------\n")
-	fmt.Println("Добро пожаловать в программу расписания дежурств!")
-	fmt.Println("Выберите действие:")
-	fmt.Println("1. Сформировать расписание на следующую неделю")
-	fmt.Println("2. Обновить статус сотрудников (на больничном, в отпуске, доступен для дежурства)")
-	fmt.Println("3. Просмотреть историю дежурств")
-	fmt.Println("4. Добавить сотрудников")
-	fmt.Println("5. Выход")
-	fmt.Printf("\n--------------------------------------------------------------------------------------\n")
+// Коды завершения программы.
+const (
+	exitOK    = 0 // команда выполнена успешно
+	exitError = 1 // ошибка при выполнении команды
+	exitUsage = 2 // неверные аргументы командной строки
+)
+
+// errUsage оборачивает ошибки разбора аргументов, чтобы вернуть exitUsage.
+var errUsage = errors.New("неверные аргументы")
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, `Использование: dev-support-schedule [--data DIR] [--team NAME] [--config FILE] [--store json|sqlite] [--db FILE] [--reason TEXT] <команда> [аргументы]
+
+Виды дежурств задаются в config.json (поле duty_types), без него используются Express Release,
+Instances release и Support. Праздники задаются в поле holidays, шаблон сообщения - в поле template
+(встроенные: markdown, slack, telegram, plain, html).
+
+С --team команда работает с данными команды из каталога DIR/teams/NAME: у каждой команды свои сотрудники,
+виды дежурств, история, журнал и каналы публикации (config.json в ее каталоге). Без --team - основная
+команда в самом DIR. Один человек может состоять в нескольких командах: записи, связанные одним ключом
+(employee member), не назначаются дежурными в разных командах в один день.
+
+Команды:
+  interactive                                  интерактивное меню (по умолчанию)
+  schedule generate [--week YYYY-MM-DD] [--dry-run] [--template NAME|FILE] [--publish]
+                                               сформировать расписание на неделю
+  schedule plan [--weeks N] [--from YYYY-MM-DD] [--dry-run] [--template NAME|FILE]
+                                               запланировать N недель вперед (запланированные недели перегенерируются)
+  schedule confirm --week YYYY-MM-DD           утвердить запланированную неделю
+  schedule publish [--week YYYY-MM-DD] [--dry-run] [--force] [--template NAME|FILE]
+                                               опубликовать расписание через webhook Slack или Mattermost
+                                               (publish.webhook_url в настройках или SCHEDULE_WEBHOOK_URL)
+  schedule pin add --duty NAME --id N [--week YYYY-MM-DD] [--date YYYY-MM-DD] [--force]
+                                               закрепить сотрудника за дежурством до формирования расписания;
+                                               остальные слоты подбираются вокруг закреплений, с --force - даже
+                                               если сотрудник отсутствует
+  schedule pin list [--week YYYY-MM-DD]        закрепления недели
+  schedule pin remove --index I [--week YYYY-MM-DD]
+                                               снять закрепление
+  schedule swap --duty NAME --id N --with M --by WHO [--date YYYY-MM-DD] [--with-duty NAME] [--with-date YYYY-MM-DD]
+               [--week YYYY-MM-DD] [--publish] [--template NAME|FILE]
+                                               поменять дежурства двух сотрудников в сформированной неделе;
+                                               счетчики переносятся, изменение записывается в журнал недели,
+                                               с --publish исправленное расписание публикуется повторно
+  schedule replace --duty NAME --id N --with M --by WHO [--date YYYY-MM-DD] [--week YYYY-MM-DD] [--publish]
+                                               передать дежурство сотрудника N сотруднику M
+  schedule explain [--week YYYY-MM-DD]         почему назначены именно эти дежурные: кандидаты каждого слота
+                                               в порядке очереди, причины отказа и правило выбора
+  schedule compare [--week YYYY-MM-DD]         составить неделю жадным подбором и оптимизатором, не сохраняя,
+                                               и сравнить их штрафы; движок для формирования - solver.engine
+                                               в настройках (greedy или optimize)
+  employee list                                список сотрудников
+  employee add --name "Имя Фамилия" [--member KEY]
+                                               добавить сотрудника
+  employee status --id N --status STATUS       изменить статус (available, sick, vacation, fired);
+                                               sick и vacation заводят бессрочное отсутствие с сегодняшнего дня
+  employee link --id N --telegram USER_ID      привязать сотрудника к пользователю Telegram (0 - отвязать)
+  employee member --id N --member KEY         связать сотрудника с его записями в других командах общим ключом
+                                               (пустой ключ - снять связь)
+  employee prefs --id N [--blackout DAYS] [--prefer DUTIES] [--avoid DUTIES] [--avoid-days DAYS]
+                 [--max-per-month N] [--clear]
+                                               пожелания сотрудника (списки через запятую): в дни --blackout и сверх
+                                               --max-per-month он не назначается, --prefer, --avoid и --avoid-days
+                                               учитываются при равной нагрузке и оптимизатором; без флагов изменения -
+                                               показать пожелания
+  employee skills --id N [--add SKILLS] [--remove SKILLS]
+                                               навыки сотрудника (списки через запятую): дежурными назначаются только
+                                               сотрудники с навыками из required_skills дежурства, остальные встают
+                                               рядом стажерами и после shadow_shifts смен получают навыки; без флагов -
+                                               показать навыки и смены стажером
+  employee absence add --id N --kind KIND --from YYYY-MM-DD [--to YYYY-MM-DD]
+                                               добавить период отсутствия (sick, vacation)
+  employee absence list [--id N]               периоды отсутствия
+  employee absence remove --id N --index I     удалить период отсутствия
+  history list                                 история дежурств
+  team list                                    команды установки (* - выбранная)
+  team add --name NAME                         создать команду с копией настроек основной
+  team conflicts [--from YYYY-MM-DD | --all]   дни, в которые общий сотрудник дежурит в нескольких командах
+                                               (по умолчанию начиная с сегодняшнего дня)
+  counters reset [--force]                     сбросить счетчики дежурств с reset_counters (раз в 90 дней или принудительно)
+  counters recompute [--from YYYY-MM-DD | --all] [--to YYYY-MM-DD] [--apply]
+                                               пересчитать счетчики и даты последних дежурств по истории (дежурства
+                                               с reset_counters - по умолчанию с последнего сброса) и показать расхождения;
+                                               с --apply - сохранить их
+  ics export [--employee N] [--from YYYY-MM-DD] [--output FILE]
+                                               выгрузить дежурства в календарь iCalendar (команда или один сотрудник)
+  ics serve [--addr :8080]                     раздавать календари по HTTP: /calendar.ics и /calendar/N.ics
+  bot                                          Telegram-бот (long polling): telegram.token в настройках
+                                               или SCHEDULE_TELEGRAM_TOKEN, администраторы чатов в telegram.admins
+  serve [--addr :8080]                         веб-интерфейс, REST API (JSON) с описанием OpenAPI в /api/v1/openapi.json и календари;
+                                               токены с ролями read и admin в api.tokens или SCHEDULE_API_TOKEN (admin)
+  daemon [--once]                              формировать расписание на следующую неделю по daemon.schedule (cron,
+                                               например "0 15 * * fri", пояс в daemon.timezone) и публиковать
+                                               в каналы daemon.publish (webhook, telegram); пропущенный запуск
+                                               догоняется при старте, с --once - только он
+  events list [--employee N] [--type TYPE] [--from YYYY-MM-DD] [--to YYYY-MM-DD]
+                                               журнал изменений: кто, когда, зачем и что поменял; каждое изменение
+                                               записывается от имени пользователя ОС с причиной из --reason
+  migrate sqlite [--force]                     перенести employees.json и history.json в базу SQLite`)
 }
 
 func main() {
-	employees, err := pkg.LoadEmployees(employeesFilePath)
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run разбирает аргументы, выполняет команду и возвращает код завершения.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	global := flag.NewFlagSet("dev-support-schedule", flag.ContinueOnError)
+	global.SetOutput(stderr)
+	global.Usage = func() { usage(stderr) }
+	dataDir := global.String("data", "data", "каталог с данными")
+	team := global.String("team", "", "команда из каталога DIR/teams/NAME (по умолчанию основная)")
+	store := global.String("store", storeJSON, "хранилище данных: json или sqlite")
+	dbPath := global.String("db", "", "файл базы SQLite (по умолчанию DIR/schedule.db)")
+	configPath := global.String("config", "", "файл настроек (по умолчанию DIR/config.json)")
+	reason := global.String("reason", "", "причина изменения для журнала (по умолчанию - команда)")
+	if err := global.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
 	}
 
-	historyStorage, err := pkg.LoadDutyHistory(historyFilePath)
-	if err != nil {
-		fmt.Println("При попытке загрузить историю дежурств произошла ошибка. ", err)
+	rootDir := *dataDir
+	if *team != "" {
+		if err := pkg.ValidTeamName(*team); err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitUsage
+		}
+		*dataDir = pkg.TeamDir(rootDir, *team)
+		if _, err := os.Stat(*dataDir); err != nil {
+			fmt.Fprintf(stderr, "команда %s не найдена, создайте ее: team add --name %s\n", *team, *team)
+			return exitError
+		}
+	}
+
+	if *dbPath == "" {
+		*dbPath = filepath.Join(*dataDir, "schedule.db")
 	}
 
-	if len(*employees) == 0 {
-		fmt.Println("Список сотрудников пуст. Сначала добавьте сотрудников.")
-		choiceSwitcher(4, employees, historyStorage)
+	if *configPath == "" {
+		*configPath = filepath.Join(*dataDir, "config.json")
+	}
+	config, err := pkg.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
 	}
 
-	for {
-		displayMenu()
+	a := &app{
+		config:    config,
+		configDir: filepath.Dir(*configPath),
+		dataDir:   *dataDir,
+		rootDir:   rootDir,
+		team:      *team,
+		store:     *store,
+		dbPath:    *dbPath,
+		reason:    *reason,
+		stdin:     stdin,
+		stdout:    stdout,
+		stderr:    stderr,
+		clock:     pkg.SystemClock{},
+	}
 
-		var choice int
-		fmt.Scan(&choice)
-		choiceSwitcher(choice, employees, historyStorage)
+	err = a.dispatch(global.Args())
+	if closeErr := a.close(); err == nil {
+		err = closeErr
+	}
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errUsage):
+		fmt.Fprintln(stderr, err)
+		usage(stderr)
+		return exitUsage
+	default:
+		fmt.Fprintln(stderr, err)
+		return exitError
 	}
 }
 
-func choiceSwitcher(choice int, employees *[]pkg.Employee, historyStorage *pkg.DutyHistoryStorage) {
-	switch choice {
-	case 1:
-		scheduleStr, schedule, err := pkg.GetSchedule(employees)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		fmt.Println(scheduleStr)
+func (a *app) dispatch(args []string) error {
+	if len(args) == 0 {
+		a.command = "interactive"
+		return a.interactive()
+	}
 
-		// Сброс счетчиков и сохранение текущего состояния в историческое хранилище (если прошло более 90 дней с момента последнего сброса)
-		reseted := pkg.ResetDutyCounters(employees, historyStorage)
-		if reseted {
-			fmt.Println("Счетчики дежурств сброшены.")
-		}
+	command, rest := args[0], args[1:]
+	a.command = commandName(args)
+	if command == "interactive" {
+		return a.interactive()
+	}
+	if command == "bot" {
+		return a.bot(rest)
+	}
+	if command == "serve" {
+		return a.serve(rest)
+	}
+	if command == "daemon" {
+		return a.daemon(rest)
+	}
+	if command == "help" {
+		usage(a.stdout)
+		return nil
+	}
 
-		err = pkg.SaveEmployees(employeesFilePath, employees)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
+	if len(rest) == 0 {
+		return fmt.Errorf("%w: не указана подкоманда для %q", errUsage, command)
+	}
+	sub, rest := rest[0], rest[1:]
 
-		pkg.AddScheduleToHistory(schedule, historyStorage)
-		err = pkg.SaveDutyHistory(historyFilePath, historyStorage)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-	case 2:
-		// Здесь можно запросить имя сотрудника и новый статус, затем обновить его данные.
-
-		employeesStr := pkg.AllEmployees(employees)
-		fmt.Println(employeesStr)
-		fmt.Println("Введите ID сотрудника, статус которого хотите изменить:")
-		var employeeId int
-		fmt.Scan(&employeeId)
-		fmt.Println("Введите новый статус сотрудника (available, sick, vacation, fired):")
-		var newStatus string
-		fmt.Scan(&newStatus)
-		err := pkg.UpdateEmployeeStatus(employees, employeeId, newStatus)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		err = pkg.SaveEmployees(employeesFilePath, employees)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		fmt.Println("Статус сотрудника успешно обновлен.")
-	case 3:
-		// Просмотреть историю дежурств
-		// вывести когда последний раз обнуляли счетчики
-		fmt.Printf("Последний раз счетчики дежурств обнулялись %s\n\n", historyStorage.LastResetDate)
-
-		for _, record := range historyStorage.History {
-			fmt.Printf("История за период с %s до %s\n", record.Date, record.Date.AddDate(0, 0, 4))
-			for _, employee := range record.Employees {
-				fmt.Printf("%s | Last support: %s | Last release: %s (Support: %d, Express: %d, Instances: %d)\n", employee.Name, employee.SupportLastDuty, employee.ReleaseLastDuty, employee.SupportDutyCount, employee.ExpressDutyCount, employee.InstancesDutyCount)
-			}
-		}
-		fmt.Println()
-		fmt.Println()
-		fmt.Println()
-	case 4:
-		// Добавление новых сотрудников
-		employeesStr := pkg.AllEmployees(employees)
-		fmt.Printf("%s\n", employeesStr)
-
-		fmt.Println("Введите имя нового сотрудника:")
-		var name, surname string
-		n, err := fmt.Scan(&name, &surname)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		if n > 1 {
-			// склеить в имя и фамилию
-			name = name + " " + surname
-		}
-		pkg.AddNewEmployee(employees, name)
-		err = pkg.SaveEmployees(employeesFilePath, employees)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		fmt.Println("Сотрудник успешно добавлен.")
-	case 5:
-		fmt.Println("Выход из программы...")
-		os.Exit(0)
-	default:
-		fmt.Println("Неизвестный выбор. Пожалуйста, попробуйте снова.")
+	switch command + " " + sub {
+	case "schedule generate":
+		return a.scheduleGenerate(rest)
+	case "schedule plan":
+		return a.schedulePlan(rest)
+	case "schedule confirm":
+		return a.scheduleConfirm(rest)
+	case "schedule publish":
+		return a.schedulePublish(rest)
+	case "schedule explain":
+		return a.scheduleExplain(rest)
+	case "schedule compare":
+		return a.scheduleCompare(rest)
+	case "schedule pin":
+		return a.dispatchPin(rest)
+	case "schedule swap":
+		return a.scheduleSwap(rest)
+	case "schedule replace":
+		return a.scheduleReplace(rest)
+	case "employee link":
+		return a.employeeLink(rest)
+	case "employee prefs":
+		return a.employeePrefs(rest)
+	case "employee skills":
+		return a.employeeSkills(rest)
+	case "ics export":
+		return a.icsExport(rest)
+	case "ics serve":
+		return a.icsServe(rest)
+	case "employee list":
+		return a.employeeList(rest)
+	case "employee add":
+		return a.employeeAdd(rest)
+	case "employee status":
+		return a.employeeStatus(rest)
+	case "employee absence":
+		return a.dispatchAbsence(rest)
+	case "employee member":
+		return a.employeeMember(rest)
+	case "team list", "team add", "team conflicts":
+		return a.dispatchTeam(append([]string{sub}, rest...))
+	case "history list":
+		return a.historyList(rest)
+	case "counters reset":
+		return a.countersReset(rest)
+	case "counters recompute":
+		return a.countersRecompute(rest)
+	case "migrate sqlite":
+		return a.migrateSQLite(rest)
+	case "events list":
+		return a.eventsList(rest)
+	}
+
+	return fmt.Errorf("%w: неизвестная команда %q", errUsage, command+" "+sub)
+}
+
+// commandName возвращает команду без флагов, например "schedule pin add", для журнала изменений.
+func commandName(args []string) string {
+	n := 0
+	for n < len(args) && !strings.HasPrefix(args[n], "-") {
+		n++
 	}
+	return strings.Join(args[:n], " ")
+}
+
+func (a *app) dispatchAbsence(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: не указана подкоманда для \"employee absence\"", errUsage)
+	}
+
+	switch args[0] {
+	case "add":
+		return a.absenceAdd(args[1:])
+	case "list":
+		return a.absenceList(args[1:])
+	case "remove":
+		return a.absenceRemove(args[1:])
+	}
+
+	return fmt.Errorf("%w: неизвестная команда \"employee absence %s\"", errUsage, args[0])
 }
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"dev-support-schedule/pkg"
+	"fmt"
+)
+
+func (a *app) dispatchPin(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: не указана подкоманда для \"schedule pin\"", errUsage)
+	}
+
+	switch args[0] {
+	case "add":
+		return a.pinAdd(args[1:])
+	case "list":
+		return a.pinList(args[1:])
+	case "remove":
+		return a.pinRemove(args[1:])
+	}
+
+	return fmt.Errorf("%w: неизвестная команда \"schedule pin %s\"", errUsage, args[0])
+}
+
+// weekScheduler возвращает планировщик, для которого следующей будет неделя week (YYYY-MM-DD),
+// или планировщик на текущую дату, если неделя не указана.
+func (a *app) weekScheduler(week string) (*pkg.Scheduler, error) {
+	if week == "" {
+		return a.newScheduler(a.clock), nil
+	}
+	monday, err := parseWeek(week)
+	if err != nil {
+		return nil, err
+	}
+	return a.newScheduler(pkg.FixedClock(monday)), nil
+}
+
+func (a *app) pinAdd(args []string) error {
+	fs := a.newFlagSet("schedule pin add")
+	week := fs.String("week", "", "понедельник недели в формате YYYY-MM-DD (по умолчанию следующая неделя)")
+	duty := fs.String("duty", "", "дежурство: имя или название из настроек")
+	date := fs.String("date", "", "день дежурства YYYY-MM-DD (для еженедельного можно не указывать)")
+	id := fs.Int("id", 0, "Id сотрудника")
+	force := fs.Bool("force", false, "закрепить, даже если сотрудник отсутствует в период дежурства")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *id <= 0 || *duty == "" {
+		return fmt.Errorf("%w: нужно указать --duty и --id", errUsage)
+	}
+
+	scheduler, err := a.weekScheduler(*week)
+	if err != nil {
+		return err
+	}
+	dutyType, ok := scheduler.FindDutyType(*duty)
+	if !ok {
+		return fmt.Errorf("%w: неизвестное дежурство %q", errUsage, *duty)
+	}
+	pin := pkg.Pin{Duty: dutyType.Name, EmployeeId: *id, Force: *force}
+	if *date != "" {
+		if pin.Date, err = parseDate(*date); err != nil {
+			return err
+		}
+	}
+
+	if err := a.loadForUpdate(); err != nil {
+		return err
+	}
+	pin, err = scheduler.AddPin(a.employees, a.historyStorage, pin)
+	if err != nil {
+		return err
+	}
+	if err := a.save(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.stdout, "Сотрудник закреплен за дежурством %s %s.\n", dutyType.Title, pin.Date.Format("2006-01-02"))
+	if _, ok := a.historyStorage.FindWeek(pin.Week); ok {
+		fmt.Fprintln(a.stdout, "Расписание на эту неделю уже сформировано: закрепление применится, когда его сформируют заново.")
+	}
+	return nil
+}
+
+func (a *app) pinList(args []string) error {
+	fs := a.newFlagSet("schedule pin list")
+	week := fs.String("week", "", "понедельник недели в формате YYYY-MM-DD (по умолчанию следующая неделя)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	scheduler, err := a.weekScheduler(*week)
+	if err != nil {
+		return err
+	}
+	if err := a.load(); err != nil {
+		return err
+	}
+
+	for i, pin := range a.historyStorage.PinsFor(scheduler.NextWeek()) {
+		name := fmt.Sprintf("Id %d", pin.EmployeeId)
+		for _, employee := range *a.employees {
+			if employee.Id == pin.EmployeeId {
+				name = employee.Name
+			}
+		}
+		force := ""
+		if pin.Force {
+			force = " (принудительно)"
+		}
+		fmt.Fprintf(a.stdout, "[%d] %s | %s | %s%s\n", i, pin.Date.Format("2006-01-02"), scheduler.DutyTitle(pin.Duty), name, force)
+	}
+	return nil
+}
+
+func (a *app) pinRemove(args []string) error {
+	fs := a.newFlagSet("schedule pin remove")
+	week := fs.String("week", "", "понедельник недели в формате YYYY-MM-DD (по умолчанию следующая неделя)")
+	index := fs.Int("index", -1, "номер закрепления из schedule pin list")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *index < 0 {
+		return fmt.Errorf("%w: не указан номер закрепления (--index)", errUsage)
+	}
+	scheduler, err := a.weekScheduler(*week)
+	if err != nil {
+		return err
+	}
+
+	if err := a.loadForUpdate(); err != nil {
+		return err
+	}
+	if err := pkg.RemovePin(a.historyStorage, scheduler.NextWeek(), *index); err != nil {
+		return err
+	}
+	if err := a.save(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(a.stdout, "Закрепление снято.")
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"dev-support-schedule/pkg"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// serve запускает веб-интерфейс и REST API до SIGINT или SIGTERM. Без токенов сервер не запускается,
+// чтобы API не оказался открытым случайно.
+func (a *app) serve(args []string) error {
+	fs := a.newFlagSet("serve")
+	addr := fs.String("addr", ":8080", "адрес HTTP-сервера")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	tokens, err := a.config.API.APITokens()
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return fmt.Errorf("не заданы токены API: укажите api.tokens в настройках или переменную %s", pkg.APITokenEnv)
+	}
+
+	repo, err := a.openRepo()
+	if err != nil {
+		return err
+	}
+	service := pkg.NewService(repo, a.config, a.dataDir)
+	service.Teams = a.teams()
+	api := pkg.NewAPI(service, tokens)
+	mux := http.NewServeMux()
+	mux.Handle("/api/", api)
+	mux.Handle("/calendar.ics", api)
+	mux.Handle("/calendar/", api)
+	mux.Handle("/", pkg.NewWebUI(service, tokens))
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	fmt.Fprintf(a.stdout, "Веб-интерфейс доступен на %s/, API - на %s/api/v1, описание - %s/api/v1/openapi.json.\n", *addr, *addr, *addr)
+	return listenAndServe(server)
+}
+
+// listenAndServe обслуживает запросы до SIGINT или SIGTERM, затем останавливает сервер и ждет,
+// пока завершатся начатые запросы: иначе хранилище закроется под незаконченной записью.
+func listenAndServe(server *http.Server) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	done := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		done <- server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	if err := <-done; err != nil {
+		return fmt.Errorf("не удалось дождаться завершения запросов: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"dev-support-schedule/pkg"
+	"flag"
+	"fmt"
+)
+
+// changeFlags - флаги, общие для обмена и замены дежурных.
+type changeFlags struct {
+	week, duty, date, by, templateName *string
+	id, with                           *int
+	publish                            *bool
+}
+
+func (a *app) newChangeFlags(fs *flag.FlagSet) changeFlags {
+	return changeFlags{
+		week:         fs.String("week", "", "понедельник недели в формате YYYY-MM-DD (по умолчанию следующая неделя)"),
+		duty:         fs.String("duty", "", "дежурство: имя или название из настроек"),
+		date:         fs.String("date", "", "день дежурства YYYY-MM-DD (можно не указывать, если такое дежурство у сотрудника одно)"),
+		id:           fs.Int("id", 0, "Id сотрудника, который отдает дежурство"),
+		with:         fs.Int("with", 0, "Id сотрудника, который принимает дежурство"),
+		by:           fs.String("by", "", "кто попросил изменить расписание (для журнала)"),
+		publish:      fs.Bool("publish", false, "опубликовать исправленное расписание через webhook"),
+		templateName: fs.String("template", "", "шаблон сообщения для публикации: markdown, slack, telegram, plain, html или путь к файлу"),
+	}
+}
+
+// slotRef собирает ссылку на назначение из флагов дежурства и дня.
+func (a *app) slotRef(scheduler *pkg.Scheduler, duty, date string, id int) (pkg.SlotRef, error) {
+	dutyType, ok := scheduler.FindDutyType(duty)
+	if !ok {
+		return pkg.SlotRef{}, fmt.Errorf("%w: неизвестное дежурство %q", errUsage, duty)
+	}
+	ref := pkg.SlotRef{Duty: dutyType.Name, EmployeeId: id}
+	if date != "" {
+		var err error
+		if ref.Date, err = parseDate(date); err != nil {
+			return pkg.SlotRef{}, err
+		}
+	}
+	return ref, nil
+}
+
+func (a *app) scheduleSwap(args []string) error {
+	fs := a.newFlagSet("schedule swap")
+	flags := a.newChangeFlags(fs)
+	withDuty := fs.String("with-duty", "", "дежурство второго сотрудника (по умолчанию то же)")
+	withDate := fs.String("with-date", "", "день дежурства второго сотрудника YYYY-MM-DD")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *withDuty == "" {
+		*withDuty = *flags.duty
+	}
+	return a.changeWeek(flags, func(scheduler *pkg.Scheduler, ref pkg.SlotRef) (pkg.WeekChange, error) {
+		second, err := a.slotRef(scheduler, *withDuty, *withDate, *flags.with)
+		if err != nil {
+			return pkg.WeekChange{}, err
+		}
+		return scheduler.SwapAssignments(a.employees, a.historyStorage, scheduler.NextWeek(), ref, second, *flags.by)
+	})
+}
+
+func (a *app) scheduleReplace(args []string) error {
+	fs := a.newFlagSet("schedule replace")
+	flags := a.newChangeFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	return a.changeWeek(flags, func(scheduler *pkg.Scheduler, ref pkg.SlotRef) (pkg.WeekChange, error) {
+		return scheduler.ReplaceAssignment(a.employees, a.historyStorage, scheduler.NextWeek(), ref, *flags.with, *flags.by)
+	})
+}
+
+// changeWeek проверяет флаги, вносит изменение change в неделю, сохраняет данные и при --publish
+// публикует исправленное расписание повторно.
+func (a *app) changeWeek(flags changeFlags, change func(scheduler *pkg.Scheduler, ref pkg.SlotRef) (pkg.WeekChange, error)) error {
+	if *flags.id <= 0 || *flags.with <= 0 || *flags.duty == "" {
+		return fmt.Errorf("%w: нужно указать --duty, --id и --with", errUsage)
+	}
+	if *flags.by == "" {
+		return fmt.Errorf("%w: укажите, кто попросил изменить расписание (--by)", errUsage)
+	}
+	var renderer *pkg.Renderer
+	if *flags.publish {
+		var err error
+		if renderer, err = a.publishRenderer(*flags.templateName); err != nil {
+			return fmt.Errorf("%w: %s", errUsage, err)
+		}
+		// проверяем webhook до изменения недели, чтобы не сохранить ее без публикации
+		if _, err := a.webhookPublisher(); err != nil {
+			return err
+		}
+	}
+
+	scheduler, err := a.weekScheduler(*flags.week)
+	if err != nil {
+		return err
+	}
+	ref, err := a.slotRef(scheduler, *flags.duty, *flags.date, *flags.id)
+	if err != nil {
+		return err
+	}
+
+	if err := a.loadForUpdate(); err != nil {
+		return err
+	}
+	weekChange, err := change(scheduler, ref)
+	if err != nil {
+		return err
+	}
+	if err := a.save(); err != nil {
+		return err
+	}
+
+	for _, move := range weekChange.Moves {
+		fmt.Fprintf(a.stdout, "%s %s: %s -> %s\n", scheduler.DutyTitle(move.Duty), move.Date.Format("2006-01-02"), move.FromName, move.ToName)
+	}
+	if !*flags.publish {
+		return nil
+	}
+	record, _ := a.historyStorage.FindWeek(scheduler.NextWeek())
+	return a.publishSchedule(renderer, scheduler.HistorySchedule(*record), false, true)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"dev-support-schedule/pkg"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func (a *app) dispatchTeam(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: не указана подкоманда для \"team\"", errUsage)
+	}
+
+	switch args[0] {
+	case "list":
+		return a.teamList(args[1:])
+	case "add":
+		return a.teamAdd(args[1:])
+	case "conflicts":
+		return a.teamConflicts(args[1:])
+	}
+
+	return fmt.Errorf("%w: неизвестная команда \"team %s\"", errUsage, args[0])
+}
+
+// teams возвращает команды каталога данных; их хранилища открываются так же, как хранилище текущей команды.
+func (a *app) teams() *pkg.Teams {
+	return &pkg.Teams{DataDir: a.rootDir, Current: a.team, Open: a.openTeamRepo}
+}
+
+// openTeamRepo открывает данные команды из каталога dir только для чтения или возвращает nil,
+// если данных в нем еще нет.
+func (a *app) openTeamRepo(dir string) (pkg.TeamReader, error) {
+	switch a.store {
+	case storeJSON:
+		return pkg.OpenTeamJSON(dir)
+	case storeSQLite:
+		path := filepath.Join(dir, "schedule.db")
+		if dir == a.dataDir {
+			path = a.dbPath
+		}
+		repo, err := pkg.OpenSQLiteReader(path)
+		if repo == nil {
+			// nil-указатель в интерфейсе не был бы равен nil
+			return nil, err
+		}
+		return repo, nil
+	}
+	return nil, fmt.Errorf("%w: неизвестное хранилище %q (json, sqlite)", errUsage, a.store)
+}
+
+func (a *app) teamList(args []string) error {
+	fs := a.newFlagSet("team list")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	names, err := pkg.ListTeams(a.rootDir)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		mark := " "
+		if name == a.team {
+			mark = "*"
+		}
+		fmt.Fprintf(a.stdout, "%s %s\n", mark, pkg.TeamTitle(name))
+	}
+	return nil
+}
+
+// teamAdd создает каталог команды. Настройки основной команды копируются в него как отправная точка:
+// виды дежурств и каналы публикации новой команды правятся в ее config.json.
+func (a *app) teamAdd(args []string) error {
+	fs := a.newFlagSet("team add")
+	name := fs.String("name", "", "имя команды: латинские буквы в нижнем регистре, цифры, - и _")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *name == "" {
+		return fmt.Errorf("%w: не указано имя команды (--name)", errUsage)
+	}
+	if err := pkg.ValidTeamName(*name); err != nil {
+		return fmt.Errorf("%w: %s", errUsage, err)
+	}
+
+	dir := pkg.TeamDir(a.rootDir, *name)
+	if _, err := os.Stat(dir); err == nil {
+		return fmt.Errorf("команда %s уже существует", *name)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("не удалось создать каталог команды: %w", err)
+	}
+
+	config, err := os.ReadFile(filepath.Join(a.rootDir, "config.json"))
+	switch {
+	case err == nil:
+		if err := os.WriteFile(filepath.Join(dir, "config.json"), config, 0o644); err != nil {
+			return fmt.Errorf("не удалось скопировать настройки: %w", err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("не удалось прочитать настройки: %w", err)
+	}
+
+	fmt.Fprintf(a.stdout, "Команда %s создана: %s. Команды для нее запускаются с --team %s.\n", *name, dir, *name)
+	return nil
+}
+
+// teamConflicts выводит дни, в которые общий сотрудник дежурит сразу в нескольких командах.
+func (a *app) teamConflicts(args []string) error {
+	fs := a.newFlagSet("team conflicts")
+	from := fs.String("from", "", "искать конфликты начиная с даты YYYY-MM-DD (по умолчанию с сегодняшнего дня)")
+	all := fs.Bool("all", false, "искать конфликты за всю историю")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *all && *from != "" {
+		return fmt.Errorf("%w: --all и --from нельзя указывать вместе", errUsage)
+	}
+
+	fromDate := a.clock.Now()
+	switch {
+	case *all:
+		fromDate = time.Time{}
+	case *from != "":
+		date, err := parseDate(*from)
+		if err != nil {
+			return err
+		}
+		fromDate = date
+	}
+
+	duties, err := a.teams().Duties(true)
+	if err != nil {
+		return err
+	}
+	conflicts := pkg.TeamConflicts(duties, fromDate)
+	if len(conflicts) == 0 {
+		fmt.Fprintln(a.stdout, "Конфликтов между командами нет.")
+		return nil
+	}
+	fmt.Fprint(a.stdout, pkg.FormatTeamConflicts(conflicts))
+	return nil
+}
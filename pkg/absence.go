@@ -0,0 +1,139 @@
+package pkg
+
+import (
+	"fmt"
+	"time"
+)
+
+// dayOf отбрасывает время и оставляет только календарную дату (в UTC, как и даты недель расписания).
+func dayOf(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// OpenEnded сообщает, что у отсутствия нет даты окончания.
+func (a Absence) OpenEnded() bool {
+	return a.End.IsZero()
+}
+
+// Overlaps проверяет, пересекается ли отсутствие с периодом [from, to] (даты включительно).
+func (a Absence) Overlaps(from, to time.Time) bool {
+	if dayOf(to).Before(dayOf(a.Start)) {
+		return false
+	}
+	if !a.OpenEnded() && dayOf(from).After(dayOf(a.End)) {
+		return false
+	}
+	return true
+}
+
+// AbsenceDuring возвращает отсутствие сотрудника, пересекающееся с периодом [from, to].
+// Статусы sick и vacation из старого формата считаются бессрочным отсутствием.
+func (e Employee) AbsenceDuring(from, to time.Time) (Absence, bool) {
+	if e.Status == StatusSick || e.Status == StatusVacation {
+		return Absence{Kind: e.Status}, true
+	}
+	for _, absence := range e.Absences {
+		if absence.Overlaps(from, to) {
+			return absence, true
+		}
+	}
+	return Absence{}, false
+}
+
+// AvailableDuring проверяет, может ли сотрудник дежурить в каждый день периода [from, to].
+func (e Employee) AvailableDuring(from, to time.Time) bool {
+	if e.Status == StatusFired {
+		return false
+	}
+	_, absent := e.AbsenceDuring(from, to)
+	return !absent
+}
+
+// StatusOn возвращает фактический статус сотрудника на указанный день с учетом отсутствий.
+func (e Employee) StatusOn(day time.Time) string {
+	if e.Status == StatusFired {
+		return StatusFired
+	}
+	if absence, ok := e.AbsenceDuring(day, day); ok {
+		return absence.Kind
+	}
+	return StatusAvailable
+}
+
+// AddEmployeeAbsence добавляет сотруднику период отсутствия.
+func AddEmployeeAbsence(employees *[]Employee, id int, absence Absence) error {
+	if absence.Kind != StatusSick && absence.Kind != StatusVacation {
+		return fmt.Errorf("неизвестный вид отсутствия: %s", absence.Kind)
+	}
+	if absence.Start.IsZero() {
+		return fmt.Errorf("не указана дата начала отсутствия")
+	}
+	if !absence.OpenEnded() && absence.End.Before(absence.Start) {
+		return fmt.Errorf("дата окончания отсутствия раньше даты начала")
+	}
+
+	for i, employee := range *employees {
+		if employee.Id == id {
+			(*employees)[i].Absences = append((*employees)[i].Absences, absence)
+			return nil
+		}
+	}
+	return fmt.Errorf("сотрудник с Id: %d не найден", id)
+}
+
+// RemoveEmployeeAbsence удаляет период отсутствия сотрудника по его порядковому номеру.
+func RemoveEmployeeAbsence(employees *[]Employee, id int, index int) error {
+	for i, employee := range *employees {
+		if employee.Id != id {
+			continue
+		}
+		if index < 0 || index >= len(employee.Absences) {
+			return fmt.Errorf("у сотрудника %s нет отсутствия с номером %d", employee.Name, index)
+		}
+		(*employees)[i].Absences = append(employee.Absences[:index:index], employee.Absences[index+1:]...)
+		return nil
+	}
+	return fmt.Errorf("сотрудник с Id: %d не найден", id)
+}
+
+// closeOpenAbsences завершает бессрочные отсутствия днем раньше today.
+// Отсутствия, которые еще не начались, удаляются целиком.
+func closeOpenAbsences(employee *Employee, today time.Time) {
+	absences := employee.Absences[:0]
+	for _, absence := range employee.Absences {
+		if absence.OpenEnded() {
+			if !dayOf(absence.Start).Before(dayOf(today)) {
+				continue
+			}
+			absence.End = dayOf(today).AddDate(0, 0, -1)
+		}
+		absences = append(absences, absence)
+	}
+	employee.Absences = absences
+}
+
+// EndEmployeeAbsence завершает текущее отсутствие сотрудника: все отсутствия, идущие сегодня,
+// заканчиваются вчерашним днем, будущие отсутствия остаются как есть.
+func (s *Scheduler) EndEmployeeAbsence(employees *[]Employee, id int) error {
+	employee := FindEmployeeById(employees, id)
+	if employee == nil {
+		return fmt.Errorf("сотрудник с Id: %d не найден", id)
+	}
+
+	today := dayOf(s.now())
+	absences := employee.Absences[:0]
+	for _, absence := range employee.Absences {
+		if absence.Overlaps(today, today) {
+			if !dayOf(absence.Start).Before(today) {
+				continue
+			}
+			absence.End = today.AddDate(0, 0, -1)
+		}
+		absences = append(absences, absence)
+	}
+	employee.Absences = absences
+	if employee.Status != StatusFired {
+		employee.Status = StatusAvailable
+	}
+	return nil
+}
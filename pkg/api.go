@@ -0,0 +1,791 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Роли токенов API.
+const (
+	RoleRead  = "read"  // только чтение и предпросмотр расписания
+	RoleAdmin = "admin" // любые изменения
+)
+
+// APITokenEnv - переменная окружения с токеном администратора, чтобы не хранить его в config.json.
+const APITokenEnv = "SCHEDULE_API_TOKEN"
+
+// APIToken - токен доступа к API.
+type APIToken struct {
+	Name  string `json:"name"` // кому выдан токен, для журналов
+	Token string `json:"token"`
+	Role  string `json:"role"` // RoleRead или RoleAdmin
+}
+
+// APIConfig - раздел api в config.json.
+type APIConfig struct {
+	Tokens []APIToken `json:"tokens"`
+}
+
+// APITokens возвращает токены из настроек вместе с токеном администратора из SCHEDULE_API_TOKEN.
+func (c APIConfig) APITokens() ([]APIToken, error) {
+	tokens := append([]APIToken(nil), c.Tokens...)
+	if env := os.Getenv(APITokenEnv); env != "" {
+		tokens = append(tokens, APIToken{Name: APITokenEnv, Token: env, Role: RoleAdmin})
+	}
+	for _, token := range tokens {
+		if token.Token == "" {
+			return nil, fmt.Errorf("api.tokens: у токена %q пустое значение", token.Name)
+		}
+		if token.Role != RoleRead && token.Role != RoleAdmin {
+			return nil, fmt.Errorf("api.tokens: у токена %q неизвестная роль %q (read, admin)", token.Name, token.Role)
+		}
+	}
+	return tokens, nil
+}
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+// API - JSON REST API поверх Service. Описание - в openapi.json, доступно по /api/v1/openapi.json.
+type API struct {
+	Service *Service
+	Tokens  []APIToken
+	Clock   Clock
+}
+
+// NewAPI создает API. Без токенов доступ к API закрыт.
+func NewAPI(service *Service, tokens []APIToken) *API {
+	return &API{Service: service, Tokens: tokens, Clock: SystemClock{}}
+}
+
+// apiError - ошибка с HTTP-статусом ответа.
+type apiError struct {
+	status int
+	err    error
+}
+
+func (e *apiError) Error() string { return e.err.Error() }
+func (e *apiError) Unwrap() error { return e.err }
+
+func badRequest(format string, args ...interface{}) error {
+	return &apiError{status: http.StatusBadRequest, err: fmt.Errorf(format, args...)}
+}
+
+func notFound(format string, args ...interface{}) error {
+	return &apiError{status: http.StatusNotFound, err: fmt.Errorf(format, args...)}
+}
+
+// invalid помечает ошибку проверки данных из pkg как ошибку запроса.
+func invalid(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &apiError{status: http.StatusUnprocessableEntity, err: err}
+}
+
+// request - разобранный запрос к API.
+type request struct {
+	*http.Request
+	path  []string // части пути после /api/v1/
+	token APIToken
+}
+
+// ServeHTTP маршрутизирует запросы /api/v1/... и календари /calendar.ics, /calendar/N.ics.
+func (api *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/api/v1/openapi.json" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openAPISpec)
+		return
+	}
+
+	token, ok := api.authenticate(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="dev-support-schedule"`)
+		writeError(w, &apiError{status: http.StatusUnauthorized, err: errors.New("нужен токен: заголовок Authorization: Bearer TOKEN")})
+		return
+	}
+
+	if r.URL.Path == "/calendar.ics" || strings.HasPrefix(r.URL.Path, "/calendar/") {
+		ICSHandler(api.Service).ServeHTTP(w, r)
+		return
+	}
+
+	if !strings.HasPrefix(r.URL.Path, "/api/v1/") {
+		writeError(w, notFound("неизвестный адрес %s", r.URL.Path))
+		return
+	}
+	req := &request{Request: r, token: token, path: strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/"), "/"), "/")}
+
+	// изменения доступны только администраторам; предпросмотр расписания не меняет данных
+	readOnly := r.Method == http.MethodGet || r.Method == http.MethodHead || (r.Method == http.MethodPost && req.route() == "schedule")
+	if !readOnly && token.Role != RoleAdmin {
+		writeError(w, &apiError{status: http.StatusForbidden, err: errors.New("для изменений нужен токен с ролью admin")})
+		return
+	}
+
+	result, status, err := api.route(req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, status, result)
+}
+
+// authenticate ищет токен из заголовка Authorization или параметра token (для календарных программ,
+// которые не умеют передавать заголовки).
+func (api *API) authenticate(r *http.Request) (APIToken, bool) {
+	value := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if value == "" {
+		value = r.URL.Query().Get("token")
+	}
+	return findToken(api.Tokens, value)
+}
+
+// findToken ищет токен по значению. Значения сравниваются за постоянное время.
+func findToken(tokens []APIToken, value string) (APIToken, bool) {
+	if value == "" {
+		return APIToken{}, false
+	}
+	for _, token := range tokens {
+		if subtle.ConstantTimeCompare([]byte(token.Token), []byte(value)) == 1 {
+			return token, true
+		}
+	}
+	return APIToken{}, false
+}
+
+// ReasonHeader - заголовок с причиной изменения для журнала событий.
+const ReasonHeader = "X-Reason"
+
+// actor возвращает автора изменения для журнала: имя токена и причину из ReasonHeader,
+// а если она не указана - метод и путь запроса.
+func (req *request) actor() Actor {
+	reason := req.Header.Get(ReasonHeader)
+	if reason == "" {
+		reason = req.Method + " " + req.URL.Path
+	}
+	return Actor{Name: "api:" + req.token.Name, Reason: reason}
+}
+
+// route возвращает шаблон пути запроса, в котором числа и даты заменены на {id}, {index} и {week}.
+func (req *request) route() string {
+	parts := make([]string, len(req.path))
+	for i, part := range req.path {
+		switch {
+		case i == 1 && req.path[0] == "employees":
+			parts[i] = "{id}"
+		case i == 3 && req.path[2] == "absences":
+			parts[i] = "{index}"
+		case i == 1 && req.path[0] == "history":
+			parts[i] = "{week}"
+		default:
+			parts[i] = part
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func (api *API) route(req *request) (interface{}, int, error) {
+	route := req.Method + " " + req.route()
+	switch route {
+	case "GET employees":
+		return api.listEmployees()
+	case "POST employees":
+		return api.createEmployee(req)
+	case "GET employees/{id}":
+		return api.getEmployee(req)
+	case "PATCH employees/{id}":
+		return api.updateEmployee(req)
+	case "DELETE employees/{id}":
+		return api.fireEmployee(req)
+	case "PUT employees/{id}/status":
+		return api.setStatus(req)
+	case "GET employees/{id}/absences":
+		return api.listAbsences(req)
+	case "POST employees/{id}/absences":
+		return api.addAbsence(req)
+	case "DELETE employees/{id}/absences/{index}":
+		return api.removeAbsence(req)
+	case "GET duty-types":
+		return api.Service.Config.DutyTypes, http.StatusOK, nil
+	case "POST schedule":
+		return api.schedule(req)
+	case "GET history":
+		return api.history(req)
+	case "GET history/{week}":
+		return api.historyWeek(req)
+	case "POST counters/reset":
+		return api.resetCounters(req)
+	case "GET events":
+		return api.events(req)
+	}
+
+	for _, known := range apiRoutes {
+		if strings.HasSuffix(known, " "+req.route()) {
+			return nil, 0, &apiError{status: http.StatusMethodNotAllowed, err: fmt.Errorf("метод %s не поддерживается для %s", req.Method, req.URL.Path)}
+		}
+	}
+	return nil, 0, notFound("неизвестный адрес %s", req.URL.Path)
+}
+
+// apiRoutes - все маршруты API, чтобы отличать неизвестный адрес от неподдерживаемого метода.
+var apiRoutes = []string{
+	"GET employees", "POST employees",
+	"GET employees/{id}", "PATCH employees/{id}", "DELETE employees/{id}",
+	"PUT employees/{id}/status",
+	"GET employees/{id}/absences", "POST employees/{id}/absences",
+	"DELETE employees/{id}/absences/{index}",
+	"GET duty-types", "POST schedule",
+	"GET history", "GET history/{week}",
+	"POST counters/reset",
+	"GET events",
+}
+
+func (api *API) scheduler() *Scheduler {
+	return api.Service.Scheduler(api.Clock)
+}
+
+// employeeView - сотрудник в ответах API.
+type employeeView struct {
+	Employee
+	StatusToday string `json:"status_today"`
+}
+
+func (api *API) employeeView(employee Employee) employeeView {
+	return employeeView{Employee: employee, StatusToday: employee.StatusOn(api.Clock.Now())}
+}
+
+func (api *API) listEmployees() (interface{}, int, error) {
+	var result []employeeView
+	err := api.Service.View(func(state *State) error {
+		result = make([]employeeView, 0, len(*state.Employees))
+		for _, employee := range *state.Employees {
+			result = append(result, api.employeeView(employee))
+		}
+		return nil
+	})
+	return result, http.StatusOK, err
+}
+
+// employeeId разбирает Id сотрудника из пути.
+func (req *request) employeeId() (int, error) {
+	id, err := strconv.Atoi(req.path[1])
+	if err != nil {
+		return 0, notFound("неверный Id сотрудника: %s", req.path[1])
+	}
+	return id, nil
+}
+
+// findEmployee возвращает сотрудника из пути запроса.
+func (req *request) findEmployee(state *State) (*Employee, error) {
+	id, err := req.employeeId()
+	if err != nil {
+		return nil, err
+	}
+	employee := FindEmployeeById(state.Employees, id)
+	if employee == nil {
+		return nil, notFound("сотрудник с Id: %d не найден", id)
+	}
+	return employee, nil
+}
+
+func (api *API) getEmployee(req *request) (interface{}, int, error) {
+	var result employeeView
+	err := api.Service.View(func(state *State) error {
+		employee, err := req.findEmployee(state)
+		if err != nil {
+			return err
+		}
+		result = api.employeeView(*employee)
+		return nil
+	})
+	return result, http.StatusOK, err
+}
+
+type employeeInput struct {
+	Name           *string `json:"name"`
+	TelegramUserId *int64  `json:"telegram_user_id"`
+	Member         *string `json:"member"` // пустая строка снимает связь с другими командами
+	// Preferences заменяет пожелания целиком; пустой объект их удаляет.
+	Preferences *Preferences `json:"preferences"`
+	Skills      *[]string    `json:"skills"` // заменяет навыки целиком
+}
+
+func (input employeeInput) apply(scheduler *Scheduler, employees *[]Employee, employee *Employee) error {
+	if input.Name != nil {
+		name := strings.Join(strings.Fields(*input.Name), " ")
+		if name == "" {
+			return badRequest("имя сотрудника не может быть пустым")
+		}
+		employee.Name = name
+	}
+	if input.TelegramUserId != nil {
+		if *input.TelegramUserId < 0 {
+			return badRequest("telegram_user_id не может быть отрицательным")
+		}
+		if err := LinkEmployeeTelegram(employees, employee.Id, *input.TelegramUserId); err != nil {
+			return invalid(err)
+		}
+	}
+	if input.Member != nil {
+		if err := LinkEmployeeMember(employees, employee.Id, *input.Member); err != nil {
+			return invalid(err)
+		}
+	}
+	if input.Skills != nil {
+		if err := scheduler.SetEmployeeSkills(employees, employee.Id, *input.Skills); err != nil {
+			return invalid(err)
+		}
+	}
+	if input.Preferences != nil {
+		return invalid(scheduler.SetEmployeePreferences(employees, employee.Id, *input.Preferences))
+	}
+	return nil
+}
+
+func (api *API) createEmployee(req *request) (interface{}, int, error) {
+	var input employeeInput
+	if err := decodeBody(req, &input); err != nil {
+		return nil, 0, err
+	}
+	if input.Name == nil {
+		return nil, 0, badRequest("не указано имя сотрудника (name)")
+	}
+
+	var result employeeView
+	err := api.Service.Update(req.actor(), func(state *State) error {
+		api.scheduler().AddNewEmployee(state.Employees, *input.Name)
+		// AddNewEmployee добавляет сотрудника в конец списка
+		employee := &(*state.Employees)[len(*state.Employees)-1]
+		if err := input.apply(api.scheduler(), state.Employees, employee); err != nil {
+			return err
+		}
+		result = api.employeeView(*employee)
+		return nil
+	})
+	return result, http.StatusCreated, err
+}
+
+func (api *API) updateEmployee(req *request) (interface{}, int, error) {
+	var input employeeInput
+	if err := decodeBody(req, &input); err != nil {
+		return nil, 0, err
+	}
+
+	var result employeeView
+	err := api.Service.Update(req.actor(), func(state *State) error {
+		employee, err := req.findEmployee(state)
+		if err != nil {
+			return err
+		}
+		if err := input.apply(api.scheduler(), state.Employees, employee); err != nil {
+			return err
+		}
+		result = api.employeeView(*employee)
+		return nil
+	})
+	return result, http.StatusOK, err
+}
+
+// fireEmployee увольняет сотрудника. Запись не удаляется, чтобы история дежурств осталась целой.
+func (api *API) fireEmployee(req *request) (interface{}, int, error) {
+	var result employeeView
+	err := api.Service.Update(req.actor(), func(state *State) error {
+		employee, err := req.findEmployee(state)
+		if err != nil {
+			return err
+		}
+		if err := api.scheduler().UpdateEmployeeStatus(state.Employees, employee.Id, StatusFired); err != nil {
+			return invalid(err)
+		}
+		result = api.employeeView(*employee)
+		return nil
+	})
+	return result, http.StatusOK, err
+}
+
+func (api *API) setStatus(req *request) (interface{}, int, error) {
+	var input struct {
+		Status string `json:"status"`
+	}
+	if err := decodeBody(req, &input); err != nil {
+		return nil, 0, err
+	}
+	if !ValidStatus(input.Status) {
+		return nil, 0, badRequest("неизвестный статус %q (available, sick, vacation, fired)", input.Status)
+	}
+
+	var result employeeView
+	err := api.Service.Update(req.actor(), func(state *State) error {
+		employee, err := req.findEmployee(state)
+		if err != nil {
+			return err
+		}
+		if err := api.scheduler().UpdateEmployeeStatus(state.Employees, employee.Id, input.Status); err != nil {
+			return invalid(err)
+		}
+		result = api.employeeView(*employee)
+		return nil
+	})
+	return result, http.StatusOK, err
+}
+
+func (api *API) listAbsences(req *request) (interface{}, int, error) {
+	var result []Absence
+	err := api.Service.View(func(state *State) error {
+		employee, err := req.findEmployee(state)
+		if err != nil {
+			return err
+		}
+		result = append([]Absence{}, employee.Absences...)
+		return nil
+	})
+	return result, http.StatusOK, err
+}
+
+func (api *API) addAbsence(req *request) (interface{}, int, error) {
+	var input struct {
+		Kind  string `json:"kind"`
+		Start string `json:"start"`
+		End   string `json:"end"`
+	}
+	if err := decodeBody(req, &input); err != nil {
+		return nil, 0, err
+	}
+	absence := Absence{Kind: input.Kind}
+	var err error
+	if absence.Start, err = parseAPIDate("start", input.Start, true); err != nil {
+		return nil, 0, err
+	}
+	if absence.End, err = parseAPIDate("end", input.End, false); err != nil {
+		return nil, 0, err
+	}
+
+	var result []Absence
+	err = api.Service.Update(req.actor(), func(state *State) error {
+		employee, err := req.findEmployee(state)
+		if err != nil {
+			return err
+		}
+		if err := AddEmployeeAbsence(state.Employees, employee.Id, absence); err != nil {
+			return invalid(err)
+		}
+		result = employee.Absences
+		return nil
+	})
+	return result, http.StatusCreated, err
+}
+
+func (api *API) removeAbsence(req *request) (interface{}, int, error) {
+	index, err := strconv.Atoi(req.path[3])
+	if err != nil {
+		return nil, 0, notFound("неверный номер отсутствия: %s", req.path[3])
+	}
+
+	var result []Absence
+	err = api.Service.Update(req.actor(), func(state *State) error {
+		employee, err := req.findEmployee(state)
+		if err != nil {
+			return err
+		}
+		if err := RemoveEmployeeAbsence(state.Employees, employee.Id, index); err != nil {
+			return &apiError{status: http.StatusNotFound, err: err}
+		}
+		result = append([]Absence{}, employee.Absences...)
+		return nil
+	})
+	return result, http.StatusOK, err
+}
+
+// scheduleResult - сформированное расписание в ответе API.
+type scheduleResult struct {
+	Week        string            `json:"week"`
+	Committed   bool              `json:"committed"`
+	Reseted     bool              `json:"counters_reset"`
+	Assignments []Assignment      `json:"assignments"`
+	Shadows     []ShadowShift     `json:"shadows,omitempty"`
+	Sections    []ScheduleSection `json:"sections"`
+	Message     string            `json:"message"`
+}
+
+// schedule формирует расписание. Без commit - предпросмотр: данные не сохраняются.
+func (api *API) schedule(req *request) (interface{}, int, error) {
+	var input struct {
+		Week     string `json:"week"`
+		Commit   bool   `json:"commit"`
+		Template string `json:"template"`
+	}
+	if err := decodeBody(req, &input); err != nil {
+		return nil, 0, err
+	}
+	if input.Commit && req.token.Role != RoleAdmin {
+		return nil, 0, &apiError{status: http.StatusForbidden, err: errors.New("для сохранения расписания нужен токен с ролью admin")}
+	}
+
+	scheduler := api.scheduler()
+	if input.Week != "" {
+		week, err := parseAPIDate("week", input.Week, true)
+		if err != nil {
+			return nil, 0, err
+		}
+		scheduler = api.Service.Scheduler(FixedClock(WeekStart(week)))
+	}
+
+	renderer := api.Service.Config.Renderer
+	if input.Template != "" {
+		// из API доступны только встроенные шаблоны, чтобы не читать произвольные файлы сервера
+		if strings.ContainsAny(input.Template, `/\.`) {
+			return nil, 0, badRequest("template: укажите один из встроенных шаблонов (%s)", strings.Join(BuiltinTemplates(), ", "))
+		}
+		var err error
+		if renderer, err = NewRenderer(input.Template, ""); err != nil {
+			return nil, 0, badRequest("%s", err)
+		}
+	}
+
+	run := api.Service.View
+	if input.Commit {
+		run = func(fn func(state *State) error) error { return api.Service.Update(req.actor(), fn) }
+	}
+
+	var result scheduleResult
+	err := run(func(state *State) error {
+		schedule, reseted, err := scheduler.GenerateWeek(state.Employees, state.History)
+		if err != nil {
+			return invalid(err)
+		}
+		message, err := renderer.Render(schedule)
+		if err != nil {
+			return err
+		}
+		result = scheduleResult{
+			Week:        schedule.Start.Format("2006-01-02"),
+			Committed:   input.Commit,
+			Reseted:     reseted && input.Commit,
+			Assignments: schedule.Assignments,
+			Shadows:     schedule.Shadows,
+			Sections:    schedule.Sections,
+			Message:     message,
+		}
+		return nil
+	})
+	status := http.StatusOK
+	if input.Commit {
+		status = http.StatusCreated
+	}
+	return result, status, err
+}
+
+// historyQuery - отбор недель истории по параметрам запроса from, to, employee_id и duty.
+type historyQuery struct {
+	from, to   time.Time
+	employeeId int
+	duty       string
+}
+
+func parseHistoryQuery(req *request) (historyQuery, error) {
+	var query historyQuery
+	values := req.URL.Query()
+	var err error
+	if query.from, err = parseAPIDate("from", values.Get("from"), false); err != nil {
+		return query, err
+	}
+	if query.to, err = parseAPIDate("to", values.Get("to"), false); err != nil {
+		return query, err
+	}
+	if value := values.Get("employee_id"); value != "" {
+		if query.employeeId, err = strconv.Atoi(value); err != nil {
+			return query, badRequest("employee_id: ожидается число")
+		}
+	}
+	query.duty = values.Get("duty")
+	return query, nil
+}
+
+// filter возвращает неделю с назначениями, подходящими под отбор, или false, если неделя не подходит.
+func (query historyQuery) filter(record DutyHistory) (DutyHistory, bool) {
+	if !query.from.IsZero() && record.Date.Before(WeekStart(query.from)) {
+		return record, false
+	}
+	if !query.to.IsZero() && record.Date.After(query.to) {
+		return record, false
+	}
+	if query.employeeId == 0 && query.duty == "" {
+		return record, true
+	}
+
+	filtered := record
+	filtered.Assignments = nil
+	for _, assignment := range record.Assignments {
+		if query.employeeId != 0 && assignment.EmployeeId != query.employeeId {
+			continue
+		}
+		if query.duty != "" && assignment.Duty != query.duty {
+			continue
+		}
+		filtered.Assignments = append(filtered.Assignments, assignment)
+	}
+	return filtered, len(filtered.Assignments) > 0
+}
+
+type historyResult struct {
+	LastResetDate time.Time     `json:"last_reset_date"`
+	Weeks         []DutyHistory `json:"weeks"`
+}
+
+func (api *API) history(req *request) (interface{}, int, error) {
+	query, err := parseHistoryQuery(req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	result := historyResult{Weeks: []DutyHistory{}}
+	err = api.Service.View(func(state *State) error {
+		result.LastResetDate = state.History.LastResetDate
+		for _, record := range state.History.History {
+			if filtered, ok := query.filter(record); ok {
+				result.Weeks = append(result.Weeks, filtered)
+			}
+		}
+		sort.SliceStable(result.Weeks, func(i, j int) bool { return result.Weeks[i].Date.Before(result.Weeks[j].Date) })
+		return nil
+	})
+	return result, http.StatusOK, err
+}
+
+func (api *API) historyWeek(req *request) (interface{}, int, error) {
+	week, err := parseAPIDate("week", req.path[1], true)
+	if err != nil {
+		return nil, 0, notFound("неверная неделя %q, ожидается YYYY-MM-DD", req.path[1])
+	}
+
+	var result DutyHistory
+	err = api.Service.View(func(state *State) error {
+		record, ok := state.History.FindWeek(WeekStart(week))
+		if !ok {
+			return notFound("неделя с %s не найдена в истории", WeekStart(week).Format("2006-01-02"))
+		}
+		result = *record
+		return nil
+	})
+	return result, http.StatusOK, err
+}
+
+func (api *API) resetCounters(req *request) (interface{}, int, error) {
+	var input struct {
+		Force bool `json:"force"`
+	}
+	if err := decodeBody(req, &input); err != nil {
+		return nil, 0, err
+	}
+
+	var result struct {
+		Reseted       bool      `json:"counters_reset"`
+		LastResetDate time.Time `json:"last_reset_date"`
+	}
+	err := api.Service.Update(req.actor(), func(state *State) error {
+		scheduler := api.scheduler()
+		if input.Force {
+			result.Reseted = scheduler.ForceResetDutyCounters(state.Employees, state.History)
+		} else {
+			result.Reseted = scheduler.ResetDutyCounters(state.Employees, state.History)
+		}
+		result.LastResetDate = state.History.LastResetDate
+		return nil
+	})
+	return result, http.StatusOK, err
+}
+
+// events возвращает журнал изменений с отбором по employee_id, type, from и to.
+func (api *API) events(req *request) (interface{}, int, error) {
+	values := req.URL.Query()
+	filter := EventFilter{Type: values.Get("type")}
+	var err error
+	if filter.Type != "" {
+		if err := ValidEventType(filter.Type); err != nil {
+			return nil, 0, badRequest("type: %s", err)
+		}
+	}
+	if filter.From, err = parseAPIDate("from", values.Get("from"), false); err != nil {
+		return nil, 0, err
+	}
+	if filter.To, err = parseAPIDate("to", values.Get("to"), false); err != nil {
+		return nil, 0, err
+	}
+	if value := values.Get("employee_id"); value != "" {
+		if filter.EmployeeId, err = strconv.Atoi(value); err != nil {
+			return nil, 0, badRequest("employee_id: ожидается число")
+		}
+	}
+
+	events, err := api.Service.Repo.LoadEvents(filter)
+	if events == nil {
+		events = []Event{}
+	}
+	return events, http.StatusOK, err
+}
+
+// decodeBody разбирает JSON из тела запроса. Неизвестные поля - ошибка, пустое тело - пустой объект.
+func decodeBody(req *request, target interface{}) error {
+	body, err := io.ReadAll(io.LimitReader(req.Body, 1<<20))
+	if err != nil {
+		return badRequest("не удалось прочитать тело запроса: %s", err)
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(target); err != nil {
+		return badRequest("неверный JSON в теле запроса: %s", err)
+	}
+	return nil
+}
+
+// parseAPIDate разбирает дату YYYY-MM-DD из поля name.
+func parseAPIDate(name, value string, required bool) (time.Time, error) {
+	if value == "" {
+		if required {
+			return time.Time{}, badRequest("не указано поле %s", name)
+		}
+		return time.Time{}, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, badRequest("%s: неверная дата %q, ожидается YYYY-MM-DD", name, value)
+	}
+	return date, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(value)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var apiErr *apiError
+	switch {
+	case errors.As(err, &apiErr):
+		status = apiErr.status
+	case errors.Is(err, ErrLocked):
+		// данные сейчас меняет другой экземпляр программы
+		status = http.StatusConflict
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,154 @@
+package pkg
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	testReadToken  = "read-secret"
+	testAdminToken = "admin-secret"
+)
+
+// testAPI создает API над JSON-хранилищем с сотрудниками testEmployees и двумя токенами.
+func testAPI(t *testing.T) (*API, *Service) {
+	employees := testEmployees("Анна", "Борис", "Вера", "Глеб", "Дина", "Ефим")
+	repo := NewJSONRepository(t.TempDir())
+	if err := repo.SaveEmployees(&employees); err != nil {
+		t.Fatal(err)
+	}
+
+	service := NewService(repo, nil, "")
+	api := NewAPI(service, []APIToken{
+		{Name: "reader", Token: testReadToken, Role: RoleRead},
+		{Name: "admin", Token: testAdminToken, Role: RoleAdmin},
+	})
+	// пятница перед неделей testMonday
+	api.Clock = FixedClock(testMonday.AddDate(0, 0, -3))
+	return api, service
+}
+
+func apiRequest(api *API, method, path, token, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, r)
+	return w
+}
+
+func TestAPIStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   string
+		status int
+	}{
+		{name: "без токена", method: "GET", path: "/api/v1/employees", status: http.StatusUnauthorized},
+		{name: "неизвестный токен", method: "GET", path: "/api/v1/employees", token: "wrong", status: http.StatusUnauthorized},
+		{name: "чтение", method: "GET", path: "/api/v1/employees", token: testReadToken, status: http.StatusOK},
+		{name: "токен в параметре", method: "GET", path: "/api/v1/history?token=" + testReadToken, status: http.StatusOK},
+		{name: "описание без токена", method: "GET", path: "/api/v1/openapi.json", status: http.StatusOK},
+
+		{name: "чтение: новый сотрудник", method: "POST", path: "/api/v1/employees", token: testReadToken, body: `{"name":"Жанна"}`, status: http.StatusForbidden},
+		{name: "чтение: изменение сотрудника", method: "PATCH", path: "/api/v1/employees/1", token: testReadToken, body: `{"name":"Анна"}`, status: http.StatusForbidden},
+		{name: "чтение: увольнение", method: "DELETE", path: "/api/v1/employees/1", token: testReadToken, status: http.StatusForbidden},
+		{name: "чтение: статус", method: "PUT", path: "/api/v1/employees/1/status", token: testReadToken, body: `{"status":"sick"}`, status: http.StatusForbidden},
+		{name: "чтение: отсутствие", method: "POST", path: "/api/v1/employees/1/absences", token: testReadToken, body: `{"kind":"sick","start":"2026-10-19"}`, status: http.StatusForbidden},
+		{name: "чтение: сброс счетчиков", method: "POST", path: "/api/v1/counters/reset", token: testReadToken, status: http.StatusForbidden},
+		{name: "чтение: предпросмотр расписания", method: "POST", path: "/api/v1/schedule", token: testReadToken, status: http.StatusOK},
+		{name: "чтение: сохранение расписания", method: "POST", path: "/api/v1/schedule", token: testReadToken, body: `{"commit":true}`, status: http.StatusForbidden},
+
+		{name: "неподдерживаемый метод", method: "PUT", path: "/api/v1/employees", token: testAdminToken, status: http.StatusMethodNotAllowed},
+		{name: "неподдерживаемый метод у вложенного адреса", method: "GET", path: "/api/v1/counters/reset", token: testAdminToken, status: http.StatusMethodNotAllowed},
+		{name: "неизвестный адрес", method: "GET", path: "/api/v1/unknown", token: testAdminToken, status: http.StatusNotFound},
+		{name: "адрес вне API", method: "GET", path: "/index.html", token: testAdminToken, status: http.StatusNotFound},
+		{name: "нет сотрудника", method: "GET", path: "/api/v1/employees/99", token: testAdminToken, status: http.StatusNotFound},
+		{name: "Id не число", method: "GET", path: "/api/v1/employees/anna", token: testAdminToken, status: http.StatusNotFound},
+		{name: "нет недели", method: "GET", path: "/api/v1/history/2026-10-19", token: testAdminToken, status: http.StatusNotFound},
+		{name: "нет отсутствия", method: "DELETE", path: "/api/v1/employees/1/absences/0", token: testAdminToken, status: http.StatusNotFound},
+
+		{name: "без имени", method: "POST", path: "/api/v1/employees", token: testAdminToken, body: `{}`, status: http.StatusBadRequest},
+		{name: "пустое имя", method: "POST", path: "/api/v1/employees", token: testAdminToken, body: `{"name":"  "}`, status: http.StatusBadRequest},
+		{name: "неизвестное поле", method: "POST", path: "/api/v1/employees", token: testAdminToken, body: `{"name":"Жанна","age":30}`, status: http.StatusBadRequest},
+		{name: "неверный JSON", method: "PATCH", path: "/api/v1/employees/1", token: testAdminToken, body: `{"name":`, status: http.StatusBadRequest},
+		{name: "отрицательный telegram_user_id", method: "PATCH", path: "/api/v1/employees/1", token: testAdminToken, body: `{"telegram_user_id":-1}`, status: http.StatusBadRequest},
+		{name: "неизвестный статус", method: "PUT", path: "/api/v1/employees/1/status", token: testAdminToken, body: `{"status":"drunk"}`, status: http.StatusBadRequest},
+		{name: "неверная дата", method: "POST", path: "/api/v1/employees/1/absences", token: testAdminToken, body: `{"kind":"sick","start":"2026-13-01"}`, status: http.StatusBadRequest},
+		{name: "окончание раньше начала", method: "POST", path: "/api/v1/employees/1/absences", token: testAdminToken, body: `{"kind":"sick","start":"2026-10-20","end":"2026-10-19"}`, status: http.StatusUnprocessableEntity},
+		{name: "шаблон из файла", method: "POST", path: "/api/v1/schedule", token: testAdminToken, body: `{"template":"../config.json"}`, status: http.StatusBadRequest},
+		{name: "неверный employee_id", method: "GET", path: "/api/v1/history?employee_id=anna", token: testAdminToken, status: http.StatusBadRequest},
+		{name: "неизвестный вид события", method: "GET", path: "/api/v1/events?type=unknown", token: testAdminToken, status: http.StatusBadRequest},
+
+		{name: "новый сотрудник", method: "POST", path: "/api/v1/employees", token: testAdminToken, body: `{"name":"Жанна"}`, status: http.StatusCreated},
+		{name: "сохранение расписания", method: "POST", path: "/api/v1/schedule", token: testAdminToken, body: `{"commit":true}`, status: http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api, _ := testAPI(t)
+			w := apiRequest(api, tt.method, tt.path, tt.token, tt.body)
+			if w.Code != tt.status {
+				t.Errorf("%s %s: статус %d, ожидался %d: %s", tt.method, tt.path, w.Code, tt.status, w.Body)
+			}
+		})
+	}
+}
+
+func TestAPIReadTokenDoesNotChangeData(t *testing.T) {
+	api, service := testAPI(t)
+	apiRequest(api, "POST", "/api/v1/schedule", testReadToken, `{"commit":true}`)
+	apiRequest(api, "POST", "/api/v1/schedule", testReadToken, `{}`)
+	apiRequest(api, "PUT", "/api/v1/employees/1/status", testReadToken, `{"status":"sick"}`)
+
+	err := service.View(func(state *State) error {
+		if len(state.History.History) != 0 {
+			t.Errorf("в истории %d недель после запросов с токеном чтения", len(state.History.History))
+		}
+		if status := (*state.Employees)[0].StatusOn(api.Clock.Now()); status != StatusAvailable {
+			t.Errorf("статус сотрудника изменился на %q", status)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events, err := service.Repo.LoadEvents(EventFilter{}); err != nil || len(events) != 0 {
+		t.Errorf("в журнале %d событий (ошибка %v), ожидалось 0", len(events), err)
+	}
+}
+
+func TestAPICommitSchedule(t *testing.T) {
+	api, service := testAPI(t)
+	w := apiRequest(api, "POST", "/api/v1/schedule", testAdminToken, `{"commit":true}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("статус %d: %s", w.Code, w.Body)
+	}
+	var result scheduleResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if !result.Committed || result.Week != "2026-10-19" || len(result.Assignments) == 0 {
+		t.Errorf("ответ: %+v", result)
+	}
+
+	err := service.View(func(state *State) error {
+		record, ok := state.History.FindWeek(testMonday)
+		if !ok {
+			t.Fatal("неделя не сохранена в истории")
+		}
+		if !sameJSON(record.Assignments, result.Assignments) {
+			t.Errorf("в истории %+v, в ответе %+v", record.Assignments, result.Assignments)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
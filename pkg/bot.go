@@ -0,0 +1,405 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotAdmin возвращается, если команду бота вызвал не администратор чата.
+var ErrNotAdmin = errors.New("команда доступна только администраторам этого чата")
+
+const botHelp = `Команды:
+/sick - я заболел(а)
+/vacation 2026-11-01..2026-11-10 - отпуск на период
+/back - я снова на работе
+/history [N] - дежурства за последние N недель (по умолчанию 2)
+/whois support [today|tomorrow|YYYY-MM-DD] - кто дежурит
+/generate - сформировать расписание на следующую неделю (администраторы)
+/publish [force] - опубликовать расписание следующей недели в этот чат (администраторы)
+
+Администраторы могут указать Id сотрудника последним аргументом /sick, /vacation и /back.`
+
+// BotReply - ответ бота на сообщение.
+type BotReply struct {
+	Text      string
+	ParseMode string
+}
+
+// Bot - интерфейс к расписанию через Telegram. Сотрудник узнается по TelegramUserId.
+type Bot struct {
+	API         TelegramAPI
+	Service     *Service
+	Renderer    *Renderer
+	ParseMode   string            // режим разметки сообщений с расписанием
+	Admins      map[int64][]int64 // id чата -> id пользователей-администраторов
+	PollTimeout time.Duration
+	Clock       Clock
+	Log         io.Writer
+}
+
+// NewBot создает бота по разделу telegram настроек. Путь к своему шаблону считается от configDir.
+func NewBot(api TelegramAPI, service *Service, config TelegramConfig, configDir string) (*Bot, error) {
+	admins := map[int64][]int64{}
+	for chat, users := range config.Admins {
+		chatId, err := strconv.ParseInt(chat, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("telegram.admins: неверный id чата %q", chat)
+		}
+		admins[chatId] = users
+	}
+
+	renderer, parseMode, err := TelegramRenderer(config, configDir)
+	if err != nil {
+		return nil, err
+	}
+
+	pollTimeout := 30 * time.Second
+	if config.PollTimeoutSeconds > 0 {
+		pollTimeout = time.Duration(config.PollTimeoutSeconds) * time.Second
+	}
+
+	return &Bot{
+		API:         api,
+		Service:     service,
+		Renderer:    renderer,
+		ParseMode:   parseMode,
+		Admins:      admins,
+		PollTimeout: pollTimeout,
+		Clock:       SystemClock{},
+		Log:         io.Discard,
+	}, nil
+}
+
+// Run получает сообщения через long polling и отвечает на них, пока не отменен ctx.
+func (b *Bot) Run(ctx context.Context) error {
+	var offset int64
+	for {
+		updates, err := b.API.GetUpdates(ctx, offset, b.PollTimeout)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintf(b.Log, "не удалось получить обновления: %s\n", err)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(5 * time.Second):
+			}
+			continue
+		}
+
+		for _, update := range updates {
+			offset = update.UpdateId + 1
+			if update.Message == nil || update.Message.From == nil {
+				continue
+			}
+
+			reply := b.Handle(ctx, *update.Message)
+			if reply.Text == "" {
+				continue
+			}
+			if err := b.API.SendMessage(ctx, update.Message.Chat.Id, reply.Text, reply.ParseMode); err != nil {
+				fmt.Fprintf(b.Log, "не удалось ответить в чат %d: %s\n", update.Message.Chat.Id, err)
+			}
+		}
+	}
+}
+
+// Handle выполняет команду из сообщения и возвращает ответ. Сообщения без команды и сообщения
+// из чатов, не перечисленных в telegram.admins, игнорируются.
+func (b *Bot) Handle(ctx context.Context, message TelegramMessage) BotReply {
+	fields := strings.Fields(message.Text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") || message.From == nil {
+		return BotReply{}
+	}
+	if _, ok := b.Admins[message.Chat.Id]; !ok {
+		return BotReply{}
+	}
+	// в группах команда приходит как /sick@schedule_bot
+	command, _, _ := strings.Cut(strings.ToLower(fields[0][1:]), "@")
+	args := fields[1:]
+
+	var reply BotReply
+	var err error
+	switch command {
+	case "start", "help":
+		reply.Text = botHelp
+	case "sick":
+		reply.Text, err = b.sick(message, args)
+	case "vacation":
+		reply.Text, err = b.vacation(message, args)
+	case "back":
+		reply.Text, err = b.back(message, args)
+	case "history":
+		reply.Text, err = b.history(args)
+	case "whois":
+		reply.Text, err = b.whois(args)
+	case "generate":
+		reply, err = b.generate(message)
+	case "publish":
+		reply.Text, err = b.publish(ctx, message, args)
+	default:
+		reply.Text = "Неизвестная команда.\n\n" + botHelp
+	}
+
+	if err != nil {
+		return BotReply{Text: err.Error()}
+	}
+	return reply
+}
+
+func (b *Bot) scheduler() *Scheduler {
+	return b.Service.Scheduler(b.Clock)
+}
+
+// isAdmin проверяет, что пользователь - администратор чата.
+func (b *Bot) isAdmin(message TelegramMessage) bool {
+	for _, userId := range b.Admins[message.Chat.Id] {
+		if userId == message.From.Id {
+			return true
+		}
+	}
+	return false
+}
+
+// actor возвращает автора изменения для журнала: пользователя Telegram и его команду.
+func (b *Bot) actor(message TelegramMessage) Actor {
+	name := fmt.Sprintf("telegram:%d", message.From.Id)
+	if message.From.Username != "" {
+		name += " (@" + message.From.Username + ")"
+	}
+	return Actor{Name: name, Reason: message.Text}
+}
+
+// targetEmployee возвращает сотрудника, для которого выполняется команда: по Id из аргумента
+// (только для администраторов) или автора сообщения по его TelegramUserId.
+func (b *Bot) targetEmployee(state *State, message TelegramMessage, idArg string) (*Employee, error) {
+	if idArg != "" {
+		if !b.isAdmin(message) {
+			return nil, ErrNotAdmin
+		}
+		id, err := strconv.Atoi(idArg)
+		if err != nil {
+			return nil, fmt.Errorf("неверный Id сотрудника: %s", idArg)
+		}
+		employee := FindEmployeeById(state.Employees, id)
+		if employee == nil {
+			return nil, fmt.Errorf("сотрудник с Id: %d не найден", id)
+		}
+		return employee, nil
+	}
+
+	employee, ok := FindEmployeeByTelegram(state.Employees, message.From.Id)
+	if !ok {
+		return nil, fmt.Errorf("вы не привязаны к сотруднику. Попросите администратора выполнить: employee link --id N --telegram %d", message.From.Id)
+	}
+	return employee, nil
+}
+
+func (b *Bot) sick(message TelegramMessage, args []string) (string, error) {
+	if len(args) > 1 {
+		return "", fmt.Errorf("использование: /sick [Id]")
+	}
+
+	var name string
+	err := b.Service.Update(b.actor(message), func(state *State) error {
+		employee, err := b.targetEmployee(state, message, optionalArg(args, 0))
+		if err != nil {
+			return err
+		}
+		name = employee.Name
+		return b.scheduler().UpdateEmployeeStatus(state.Employees, employee.Id, StatusSick)
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s: больничный с сегодняшнего дня. Выздоравливайте! Когда вернетесь, отправьте /back.", name), nil
+}
+
+func (b *Bot) vacation(message TelegramMessage, args []string) (string, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return "", fmt.Errorf("использование: /vacation YYYY-MM-DD..YYYY-MM-DD [Id]")
+	}
+	from, to, ok := strings.Cut(args[0], "..")
+	if !ok {
+		return "", fmt.Errorf("период отпуска указывается как YYYY-MM-DD..YYYY-MM-DD")
+	}
+	start, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return "", fmt.Errorf("неверная дата начала отпуска %q, ожидается YYYY-MM-DD", from)
+	}
+	end, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return "", fmt.Errorf("неверная дата окончания отпуска %q, ожидается YYYY-MM-DD", to)
+	}
+
+	var name string
+	err = b.Service.Update(b.actor(message), func(state *State) error {
+		employee, err := b.targetEmployee(state, message, optionalArg(args, 1))
+		if err != nil {
+			return err
+		}
+		name = employee.Name
+		return AddEmployeeAbsence(state.Employees, employee.Id, Absence{Kind: StatusVacation, Start: start, End: end})
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s: отпуск с %s по %s записан.", name, from, to), nil
+}
+
+func (b *Bot) back(message TelegramMessage, args []string) (string, error) {
+	if len(args) > 1 {
+		return "", fmt.Errorf("использование: /back [Id]")
+	}
+
+	var name string
+	err := b.Service.Update(b.actor(message), func(state *State) error {
+		employee, err := b.targetEmployee(state, message, optionalArg(args, 0))
+		if err != nil {
+			return err
+		}
+		name = employee.Name
+		return b.scheduler().EndEmployeeAbsence(state.Employees, employee.Id)
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s: с возвращением! Снова доступен(на) для дежурств.", name), nil
+}
+
+func (b *Bot) history(args []string) (string, error) {
+	weeks := 2
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 {
+			return "", fmt.Errorf("использование: /history [число недель]")
+		}
+		weeks = n
+	}
+
+	var result strings.Builder
+	err := b.Service.View(func(state *State) error {
+		scheduler := b.scheduler()
+		history := state.History.History
+		if len(history) > weeks {
+			history = history[len(history)-weeks:]
+		}
+		for _, record := range history {
+			fmt.Fprintf(&result, "Неделя с %s\n", record.Date.Format("2006-01-02"))
+			for _, assignment := range record.Assignments {
+				fmt.Fprintf(&result, "%s | %s | %s\n", assignment.Date.Format("2006-01-02"), scheduler.DutyTitle(assignment.Duty), assignment.EmployeeName)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if result.Len() == 0 {
+		return "История дежурств пуста.", nil
+	}
+	return result.String(), nil
+}
+
+func (b *Bot) whois(args []string) (string, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return "", fmt.Errorf("использование: /whois ДЕЖУРСТВО [today|tomorrow|YYYY-MM-DD]")
+	}
+
+	scheduler := b.scheduler()
+	dutyType, ok := scheduler.FindDutyType(args[0])
+	if !ok {
+		return "", fmt.Errorf("неизвестное дежурство %q", args[0])
+	}
+
+	day := dayOf(b.Clock.Now())
+	switch when := strings.ToLower(optionalArg(args, 1)); when {
+	case "", "today":
+	case "tomorrow":
+		day = day.AddDate(0, 0, 1)
+	default:
+		date, err := time.Parse("2006-01-02", when)
+		if err != nil {
+			return "", fmt.Errorf("неверная дата %q: ожидается today, tomorrow или YYYY-MM-DD", when)
+		}
+		day = date
+	}
+
+	var names []string
+	err := b.Service.View(func(state *State) error {
+		for _, assignment := range scheduler.OnDuty(state.History, dutyType, day) {
+			names = append(names, assignment.EmployeeName)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(names) == 0 {
+		return fmt.Sprintf("%s %s: никто не назначен.", dutyType.Title, day.Format("2006-01-02")), nil
+	}
+	return fmt.Sprintf("%s %s: %s", dutyType.Title, day.Format("2006-01-02"), strings.Join(names, ", ")), nil
+}
+
+func (b *Bot) generate(message TelegramMessage) (BotReply, error) {
+	if !b.isAdmin(message) {
+		return BotReply{}, ErrNotAdmin
+	}
+
+	var schedule WeekSchedule
+	err := b.Service.Update(b.actor(message), func(state *State) error {
+		var err error
+		schedule, _, err = b.scheduler().GenerateWeek(state.Employees, state.History)
+		return err
+	})
+	if err != nil {
+		return BotReply{}, err
+	}
+
+	text, err := b.Renderer.Render(schedule)
+	if err != nil {
+		return BotReply{}, err
+	}
+	return BotReply{Text: text, ParseMode: b.ParseMode}, nil
+}
+
+func (b *Bot) publish(ctx context.Context, message TelegramMessage, args []string) (string, error) {
+	if !b.isAdmin(message) {
+		return "", ErrNotAdmin
+	}
+	force := optionalArg(args, 0) == "force"
+
+	scheduler := b.scheduler()
+	week := scheduler.NextWeek()
+	publisher := &TelegramPublisher{API: b.API, ChatId: message.Chat.Id, ParseMode: b.ParseMode}
+
+	err := b.Service.Update(b.actor(message), func(state *State) error {
+		record, ok := state.History.FindWeek(week)
+		if !ok {
+			return fmt.Errorf("расписание на неделю с %s еще не сформировано, отправьте /generate", week.Format("2006-01-02"))
+		}
+		text, err := b.Renderer.Render(scheduler.HistorySchedule(*record))
+		if err != nil {
+			return err
+		}
+		return PublishWeek(ctx, publisher, state.History, week, text, force, b.Clock)
+	})
+	if err != nil {
+		return "", err
+	}
+	// само расписание уже отправлено в чат
+	return "", nil
+}
+
+func optionalArg(args []string, i int) string {
+	if i < len(args) {
+		return args[i]
+	}
+	return ""
+}
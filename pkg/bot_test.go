@@ -0,0 +1,140 @@
+package pkg
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTelegram - Bot API в памяти: первый GetUpdates отдает updates, следующий останавливает бота.
+type fakeTelegram struct {
+	updates []TelegramUpdate
+	stop    context.CancelFunc
+
+	mu   sync.Mutex
+	sent []sentMessage
+}
+
+type sentMessage struct {
+	chatId int64
+	text   string
+}
+
+func (f *fakeTelegram) GetUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]TelegramUpdate, error) {
+	if f.updates == nil {
+		f.stop()
+		return nil, ctx.Err()
+	}
+	updates := f.updates
+	f.updates = nil
+	return updates, nil
+}
+
+func (f *fakeTelegram) SendMessage(ctx context.Context, chatId int64, text, parseMode string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, sentMessage{chatId: chatId, text: text})
+	return nil
+}
+
+const (
+	testChat      = int64(100)
+	testAdmin     = int64(1)
+	testLinked    = int64(555) // привязан к первому сотруднику
+	testStranger  = int64(777) // ни к кому не привязан
+	testOtherChat = int64(200)
+)
+
+// testBot создает бота над JSON-хранилищем во временном каталоге.
+func testBot(t *testing.T, api TelegramAPI) (*Bot, *Service) {
+	employees := testEmployees("Анна", "Борис", "Вера", "Глеб", "Дина", "Ефим")
+	employees[0].TelegramUserId = testLinked
+	repo := NewJSONRepository(t.TempDir())
+	if err := repo.SaveEmployees(&employees); err != nil {
+		t.Fatal(err)
+	}
+
+	service := NewService(repo, nil, "")
+	bot, err := NewBot(api, service, TelegramConfig{Admins: map[string][]int64{"100": {testAdmin}}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// пятница перед неделей testMonday
+	bot.Clock = FixedClock(testMonday.AddDate(0, 0, -3))
+	return bot, service
+}
+
+func message(chat, from int64, text string) *TelegramMessage {
+	return &TelegramMessage{From: &TelegramUser{Id: from}, Chat: TelegramChat{Id: chat}, Text: text}
+}
+
+func TestBotRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		message *TelegramMessage
+		reply   string // подстрока ответа, пустая - бот не отвечает
+	}{
+		{name: "администратор формирует расписание", message: message(testChat, testAdmin, "/generate"), reply: "Анна"},
+		{name: "не администратор не формирует расписание", message: message(testChat, testLinked, "/generate"), reply: ErrNotAdmin.Error()},
+		{name: "сообщение из чужого чата игнорируется", message: message(testOtherChat, testAdmin, "/generate")},
+		{name: "не администратор не меняет статус другого", message: message(testChat, testLinked, "/sick 2"), reply: ErrNotAdmin.Error()},
+		{name: "администратор меняет статус другого", message: message(testChat, testAdmin, "/sick 2"), reply: "Борис: больничный"},
+		{name: "сотрудник сам сообщает о болезни", message: message(testChat, testLinked, "/sick"), reply: "Анна: больничный"},
+		{name: "неизвестный пользователь", message: message(testChat, testStranger, "/sick"), reply: "вы не привязаны к сотруднику"},
+		{name: "команда с именем бота", message: message(testChat, testLinked, "/back@schedule_bot"), reply: "Анна: с возвращением"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			api := &fakeTelegram{updates: []TelegramUpdate{{UpdateId: 1, Message: tt.message}}, stop: cancel}
+			bot, _ := testBot(t, api)
+
+			if err := bot.Run(ctx); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if tt.reply == "" {
+				if len(api.sent) != 0 {
+					t.Errorf("бот ответил на сообщение: %+v", api.sent)
+				}
+				return
+			}
+			if len(api.sent) != 1 {
+				t.Fatalf("отправлено %d сообщений, ожидалось 1: %+v", len(api.sent), api.sent)
+			}
+			if api.sent[0].chatId != tt.message.Chat.Id {
+				t.Errorf("ответ в чат %d, ожидался %d", api.sent[0].chatId, tt.message.Chat.Id)
+			}
+			if !strings.Contains(api.sent[0].text, tt.reply) {
+				t.Errorf("ответ %q не содержит %q", api.sent[0].text, tt.reply)
+			}
+		})
+	}
+}
+
+func TestBotSickSelfService(t *testing.T) {
+	bot, service := testBot(t, &fakeTelegram{})
+	today := bot.Clock.Now()
+
+	bot.Handle(context.Background(), *message(testChat, testLinked, "/sick"))
+	bot.Handle(context.Background(), *message(testChat, testStranger, "/sick"))
+
+	err := service.View(func(state *State) error {
+		for _, employee := range *state.Employees {
+			want := StatusAvailable
+			if employee.TelegramUserId == testLinked {
+				want = StatusSick
+			}
+			if got := employee.StatusOn(today); got != want {
+				t.Errorf("%s: статус %q, ожидался %q", employee.Name, got, want)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,37 @@
+package pkg
+
+import "time"
+
+// Clock возвращает текущее время. Все расчеты планировщика идут относительно него,
+// поэтому подменив Clock можно построить расписание на любую неделю.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock возвращает реальное текущее время.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// FixedClock всегда возвращает одну и ту же дату.
+type FixedClock time.Time
+
+func (c FixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+// WeekStart возвращает понедельник недели, в которую попадает дата t.
+func WeekStart(t time.Time) time.Time {
+	daysFromMonday := (int(t.Weekday()) + 6) % 7
+	return startOfDay(t).AddDate(0, 0, -daysFromMonday)
+}
+
+// startOfDay возвращает начало дня, в который t попадает в своем часовом поясе. Дни расписания
+// хранятся полуночью UTC, как и даты YYYY-MM-DD из командной строки, поэтому и здесь начало дня в UTC:
+// Truncate считал бы день по UTC и для времени вроде 01:00 по Москве возвращал бы предыдущий.
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,94 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Config - настройки программы из config.json в каталоге данных.
+type Config struct {
+	DutyTypes []DutyType    `json:"duty_types"`
+	Holidays  HolidayConfig `json:"holidays"`
+	// Template - имя встроенного шаблона сообщения (markdown, slack, telegram, plain, html)
+	// или путь к своему файлу text/template относительно каталога config.json.
+	Template string         `json:"template"`
+	Publish  PublishConfig  `json:"publish"`
+	Telegram TelegramConfig `json:"telegram"`
+	API      APIConfig      `json:"api"`
+	Daemon   DaemonConfig   `json:"daemon"`
+	Solver   SolverConfig   `json:"solver"`
+
+	// Calendar - нерабочие дни, собранные по разделу holidays при загрузке настроек.
+	Calendar *Calendar `json:"-"`
+	// Renderer - рендерер сообщения по шаблону Template.
+	Renderer *Renderer `json:"-"`
+	// PublishRenderer - рендерер сообщения для публикации по шаблону Publish.Template.
+	PublishRenderer *Renderer `json:"-"`
+}
+
+// DefaultConfig возвращает настройки, которые используются, если config.json отсутствует.
+func DefaultConfig() *Config {
+	renderer, err := NewRenderer(DefaultTemplate, "")
+	if err != nil {
+		// встроенный шаблон разбирается всегда, иначе это ошибка сборки
+		panic(err)
+	}
+	return &Config{DutyTypes: DefaultDutyTypes(), Template: DefaultTemplate, Solver: DefaultSolverConfig(), Renderer: renderer, PublishRenderer: renderer}
+}
+
+// LoadConfig загружает настройки из JSON-файла. Отсутствующий файл не ошибка - возвращаются настройки по умолчанию.
+func LoadConfig(filePath string) (*Config, error) {
+	data, err := os.ReadFile(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return DefaultConfig(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать настройки: %w", err)
+	}
+
+	config := DefaultConfig()
+	config.DutyTypes = nil
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("не удалось декодировать настройки %s: %w", filePath, err)
+	}
+
+	if len(config.DutyTypes) == 0 {
+		config.DutyTypes = DefaultDutyTypes()
+	}
+	if err := ValidateDutyTypes(config.DutyTypes); err != nil {
+		return nil, fmt.Errorf("ошибка в настройках %s: %w", filePath, err)
+	}
+	if err := config.Solver.Validate(); err != nil {
+		return nil, fmt.Errorf("ошибка в настройках %s: %w", filePath, err)
+	}
+
+	config.Calendar, err = LoadCalendar(config.Holidays, filepath.Dir(filePath))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка в настройках %s: %w", filePath, err)
+	}
+
+	config.Renderer, err = NewRenderer(config.Template, filepath.Dir(filePath))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка в настройках %s: %w", filePath, err)
+	}
+	config.PublishRenderer = config.Renderer
+	if config.Publish.Template != "" {
+		config.PublishRenderer, err = NewRenderer(config.Publish.Template, filepath.Dir(filePath))
+		if err != nil {
+			return nil, fmt.Errorf("ошибка в настройках %s: %w", filePath, err)
+		}
+	}
+
+	return config, nil
+}
+
+// NewScheduler создает планировщик с видами дежурств и календарем праздников из настроек.
+func (c *Config) NewScheduler(clock Clock) *Scheduler {
+	scheduler := NewScheduler(clock, c.DutyTypes)
+	scheduler.Calendar = c.Calendar
+	scheduler.Solver = c.Solver
+	return scheduler
+}
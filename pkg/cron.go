@@ -0,0 +1,174 @@
+package pkg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule - расписание запусков в формате cron из пяти полей: минуты, часы, день месяца,
+// месяц, день недели. Поддерживаются *, списки через запятую, диапазоны и шаг (*/15, 1-5/2),
+// названия месяцев и дней недели (jan, fri). Время считается в часовом поясе Location.
+type CronSchedule struct {
+	Spec     string
+	Location *time.Location
+
+	minutes, hours, days, months, weekdays uint64 // битовые маски допустимых значений
+	anyDay, anyWeekday                     bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+	names    []string // названия значений начиная с min
+}
+
+var cronFields = []cronField{
+	{name: "минуты", min: 0, max: 59},
+	{name: "часы", min: 0, max: 23},
+	{name: "день месяца", min: 1, max: 31},
+	{name: "месяц", min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	// 7 - тоже воскресенье
+	{name: "день недели", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// ParseCronSchedule разбирает выражение cron. Если location равен nil, используется локальное время.
+func ParseCronSchedule(spec string, location *time.Location) (*CronSchedule, error) {
+	if location == nil {
+		location = time.Local
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("неверное расписание %q: нужно 5 полей (минуты часы день месяц день_недели), например \"0 15 * * fri\"", spec)
+	}
+
+	masks := make([]uint64, len(fields))
+	for i, field := range fields {
+		mask, err := cronFields[i].parse(field)
+		if err != nil {
+			return nil, fmt.Errorf("неверное расписание %q: %w", spec, err)
+		}
+		masks[i] = mask
+	}
+	weekdays := masks[4]
+	if weekdays&(1<<7) != 0 {
+		weekdays |= 1
+	}
+
+	return &CronSchedule{
+		Spec:       spec,
+		Location:   location,
+		minutes:    masks[0],
+		hours:      masks[1],
+		days:       masks[2],
+		months:     masks[3],
+		weekdays:   weekdays,
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}, nil
+}
+
+func (f cronField) parse(field string) (uint64, error) {
+	var mask uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			rangePart = part[:i]
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%s: неверный шаг в %q", f.name, part)
+			}
+			step = n
+		}
+
+		low, high := f.min, f.max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = f.value(bounds[0]); err != nil {
+				return 0, err
+			}
+			high = low
+			if len(bounds) == 2 {
+				if high, err = f.value(bounds[1]); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				// "5/10" - с 5 до конца диапазона
+				high = f.max
+			}
+			if low > high {
+				return 0, fmt.Errorf("%s: пустой диапазон %q", f.name, part)
+			}
+		}
+
+		for v := low; v <= high; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+	return mask, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("%s: значение %q вне диапазона %d-%d", f.name, s, f.min, f.max)
+	}
+	return v, nil
+}
+
+// dayMatches проверяет день месяца и день недели. Как в cron, если ограничены оба поля,
+// достаточно совпадения любого из них.
+func (c *CronSchedule) dayMatches(t time.Time) bool {
+	day := c.days&(1<<uint(t.Day())) != 0
+	weekday := c.weekdays&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.anyDay && c.anyWeekday:
+		return true
+	case c.anyDay:
+		return weekday
+	case c.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
+
+// Next возвращает первое время запуска строго после after. Если расписание не срабатывает
+// в ближайшие пять лет (например, 30 февраля), возвращается нулевое время.
+func (c *CronSchedule) Next(after time.Time) time.Time {
+	t := after.In(c.Location).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		year, month, day := t.Date()
+		switch {
+		case c.months&(1<<uint(month)) == 0:
+			t = time.Date(year, month+1, 1, 0, 0, 0, 0, c.Location)
+		case !c.dayMatches(t):
+			t = time.Date(year, month, day+1, 0, 0, 0, 0, c.Location)
+		case c.hours&(1<<uint(t.Hour())) == 0:
+			t = time.Date(year, month, day, t.Hour()+1, 0, 0, 0, c.Location)
+		case c.minutes&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// Last возвращает последнее время запуска в промежутке (since, until] или false, если запусков не было.
+func (c *CronSchedule) Last(since, until time.Time) (time.Time, bool) {
+	var last time.Time
+	for t := c.Next(since); !t.IsZero() && !t.After(until); t = c.Next(t) {
+		last = t
+	}
+	return last, !last.IsZero()
+}
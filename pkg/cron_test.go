@@ -0,0 +1,87 @@
+package pkg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronScheduleNext(t *testing.T) {
+	msk := time.FixedZone("MSK", 3*60*60)
+	tests := []struct {
+		spec     string
+		after    time.Time
+		expected time.Time // нулевое - расписание не срабатывает
+	}{
+		{spec: "0 15 * * fri", after: time.Date(2026, 10, 21, 10, 0, 0, 0, time.UTC), expected: time.Date(2026, 10, 23, 15, 0, 0, 0, time.UTC)},
+		// запуск строго после after
+		{spec: "0 15 * * fri", after: time.Date(2026, 10, 23, 15, 0, 0, 0, time.UTC), expected: time.Date(2026, 10, 30, 15, 0, 0, 0, time.UTC)},
+		{spec: "0 15 * * fri", after: time.Date(2026, 10, 23, 14, 59, 59, 0, time.UTC), expected: time.Date(2026, 10, 23, 15, 0, 0, 0, time.UTC)},
+		{spec: "*/15 * * * *", after: time.Date(2026, 10, 21, 10, 7, 30, 0, time.UTC), expected: time.Date(2026, 10, 21, 10, 15, 0, 0, time.UTC)},
+		{spec: "30 9-18/3 * * mon-fri", after: time.Date(2026, 10, 23, 19, 0, 0, 0, time.UTC), expected: time.Date(2026, 10, 26, 9, 30, 0, 0, time.UTC)},
+		// ограничены день месяца и день недели - достаточно любого
+		{spec: "0 9 1 * mon", after: time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC), expected: time.Date(2026, 10, 26, 9, 0, 0, 0, time.UTC)},
+		{spec: "0 9 1 * mon", after: time.Date(2026, 10, 27, 0, 0, 0, 0, time.UTC), expected: time.Date(2026, 11, 1, 9, 0, 0, 0, time.UTC)},
+		{spec: "0 12 * * 7", after: time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), expected: time.Date(2026, 10, 25, 12, 0, 0, 0, time.UTC)},
+		{spec: "0 0 1 jan *", after: time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), expected: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 29 2 *", after: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), expected: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 30 2 *", after: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		schedule, err := ParseCronSchedule(tt.spec, time.UTC)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.spec, err)
+		}
+		if got := schedule.Next(tt.after); !got.Equal(tt.expected) {
+			t.Errorf("%q после %s: %s, ожидалось %s", tt.spec, tt.after, got, tt.expected)
+		}
+	}
+
+	// время расписания считается в его часовом поясе, а не в поясе after
+	schedule, err := ParseCronSchedule("0 15 * * fri", msk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := time.Date(2026, 10, 23, 11, 59, 0, 0, time.UTC)
+	if got, want := schedule.Next(after), time.Date(2026, 10, 23, 12, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("по Москве после %s: %s, ожидалось %s", after, got, want)
+	}
+}
+
+func TestCronScheduleLast(t *testing.T) {
+	schedule, err := ParseCronSchedule("0 15 * * fri", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	friday := time.Date(2026, 10, 23, 15, 0, 0, 0, time.UTC)
+	week := 7 * 24 * time.Hour
+
+	// так демон ищет пропущенный запуск при старте: в промежутке (now-CatchUp, now]
+	tests := []struct {
+		name     string
+		now      time.Time
+		catchUp  time.Duration
+		expected time.Time // нулевое - догонять нечего
+	}{
+		{name: "запуск пропущен вчера", now: friday.Add(19 * time.Hour), catchUp: week, expected: friday},
+		{name: "старт в момент запуска", now: friday, catchUp: week, expected: friday},
+		{name: "до запуска на этой неделе догоняется прошлый", now: friday.Add(-time.Hour), catchUp: week, expected: friday.AddDate(0, 0, -7)},
+		{name: "из нескольких пропущенных берется последний", now: friday.Add(time.Hour), catchUp: 3 * week, expected: friday},
+		{name: "пропущенный запуск старше CatchUp", now: friday.Add(25 * time.Hour), catchUp: 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		got, ok := schedule.Last(tt.now.Add(-tt.catchUp), tt.now)
+		if ok != !tt.expected.IsZero() || !got.Equal(tt.expected) {
+			t.Errorf("%s: %s (%v), ожидалось %s", tt.name, got, ok, tt.expected)
+		}
+	}
+}
+
+func TestParseCronScheduleErrors(t *testing.T) {
+	for _, spec := range []string{"", "0 15 * *", "60 * * * *", "0 24 * * *", "0 0 0 * *", "0 0 * 13 *", "0 0 * * 8", "*/0 * * * *", "5-1 * * * *", "0 15 * * friday"} {
+		if _, err := ParseCronSchedule(spec, time.UTC); err == nil {
+			t.Errorf("%q: ожидалась ошибка", spec)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// DaemonConfig - раздел daemon в config.json: когда формировать расписание и куда его публиковать.
+type DaemonConfig struct {
+	// Schedule - время запуска в формате cron, например "0 15 * * fri" - каждую пятницу в 15:00.
+	Schedule string `json:"schedule"`
+	// Timezone - часовой пояс расписания, например Europe/Moscow; по умолчанию локальный.
+	Timezone string `json:"timezone"`
+	// Publish - каналы публикации: webhook (раздел publish) и telegram (чат TelegramChatId).
+	// Пусто - расписание только сохраняется.
+	Publish        []string `json:"publish"`
+	TelegramChatId int64    `json:"telegram_chat_id"`
+	CatchUpHours   int      `json:"catch_up_hours"` // насколько давний пропущенный запуск догонять при старте, по умолчанию 168
+	RetryMinutes   int      `json:"retry_minutes"`  // пауза перед повтором неудачного запуска, по умолчанию 10
+}
+
+// Каналы публикации в DaemonConfig.Publish.
+const (
+	ChannelWebhook  = "webhook"
+	ChannelTelegram = "telegram"
+)
+
+// DaemonChannel - канал публикации со своим шаблоном сообщения.
+type DaemonChannel struct {
+	Publisher Publisher
+	Renderer  *Renderer
+}
+
+// Daemon по расписанию формирует расписание на следующую неделю, сохраняет его и публикует.
+// Запуск идемпотентен: неделя, которая уже есть в истории, не перегенерируется, а опубликованная
+// в канал - не отправляется туда повторно. Поэтому пропущенный за время простоя запуск можно
+// безопасно догнать при старте.
+type Daemon struct {
+	Service  *Service
+	Schedule *CronSchedule
+	Channels []DaemonChannel
+	CatchUp  time.Duration
+	Retry    time.Duration
+	Clock    Clock
+	Log      io.Writer
+}
+
+// NewDaemon создает демон по разделу daemon настроек.
+func NewDaemon(service *Service, config DaemonConfig, channels []DaemonChannel) (*Daemon, error) {
+	if config.Schedule == "" {
+		return nil, fmt.Errorf("не задано расписание запуска: укажите daemon.schedule в настройках, например \"0 15 * * fri\"")
+	}
+	location := time.Local
+	if config.Timezone != "" {
+		var err error
+		location, err = time.LoadLocation(config.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("daemon.timezone: неизвестный часовой пояс %q", config.Timezone)
+		}
+	}
+	schedule, err := ParseCronSchedule(config.Schedule, location)
+	if err != nil {
+		return nil, fmt.Errorf("daemon.schedule: %w", err)
+	}
+	clock := SystemClock{}
+	if schedule.Next(clock.Now()).IsZero() {
+		return nil, fmt.Errorf("daemon.schedule: расписание %q никогда не срабатывает", config.Schedule)
+	}
+
+	catchUp := 7 * 24 * time.Hour
+	if config.CatchUpHours > 0 {
+		catchUp = time.Duration(config.CatchUpHours) * time.Hour
+	}
+	retry := 10 * time.Minute
+	if config.RetryMinutes > 0 {
+		retry = time.Duration(config.RetryMinutes) * time.Minute
+	}
+
+	return &Daemon{
+		Service:  service,
+		Schedule: schedule,
+		Channels: channels,
+		CatchUp:  catchUp,
+		Retry:    retry,
+		Clock:    clock,
+		Log:      io.Discard,
+	}, nil
+}
+
+func (d *Daemon) logf(format string, args ...interface{}) {
+	fmt.Fprintf(d.Log, d.Clock.Now().Format("2006-01-02 15:04:05")+" "+format+"\n", args...)
+}
+
+// Run выполняет запуски по расписанию, пока не отменен ctx. Сначала догоняется последний
+// запуск за CatchUp, если он был пропущен. Неудачный запуск повторяется через Retry.
+func (d *Daemon) Run(ctx context.Context) error {
+	now := d.Clock.Now()
+	due, pending := d.Schedule.Last(now.Add(-d.CatchUp), now)
+	if pending {
+		d.logf("последний запуск по расписанию: %s", due.Format("2006-01-02 15:04 MST"))
+	}
+
+	for {
+		if pending {
+			if err := d.RunOnce(ctx, due); err != nil {
+				d.logf("запуск за %s не удался: %s; повтор через %s", due.Format("2006-01-02 15:04 MST"), err, d.Retry)
+				if err := d.sleepUntil(ctx, d.Clock.Now().Add(d.Retry)); err != nil {
+					return err
+				}
+				// пока повторяли, мог наступить следующий запуск
+				if last, ok := d.Schedule.Last(due, d.Clock.Now()); ok {
+					due = last
+				}
+				continue
+			}
+		}
+
+		due = d.Schedule.Next(d.Clock.Now())
+		pending = true
+		d.logf("следующий запуск: %s", due.Format("2006-01-02 15:04 MST"))
+		if err := d.sleepUntil(ctx, due); err != nil {
+			return err
+		}
+	}
+}
+
+// sleepUntil ждет наступления времени t. Ожидание разбито на отрезки не длиннее минуты, чтобы
+// перевод системных часов или сон машины не сдвигали запуск.
+func (d *Daemon) sleepUntil(ctx context.Context, t time.Time) error {
+	for {
+		wait := t.Sub(d.Clock.Now())
+		if wait <= 0 {
+			return nil
+		}
+		if wait > time.Minute {
+			wait = time.Minute
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// actor возвращает автора изменений запуска, назначенного на время due, для журнала.
+func (d *Daemon) actor(due time.Time) Actor {
+	return Actor{Name: "daemon", Reason: "запуск по расписанию " + due.Format("2006-01-02 15:04")}
+}
+
+// RunOnce выполняет запуск, назначенный на время due: формирует расписание на неделю, следующую
+// за due, если его еще нет в истории, и публикует его в каналы, куда оно еще не отправлено.
+// Запланированная заранее неделя не перегенерируется, а утверждается.
+func (d *Daemon) RunOnce(ctx context.Context, due time.Time) error {
+	// планировщик видит время запуска так же, как при ручном запуске в этот момент
+	scheduler := d.Service.Scheduler(FixedClock(due.In(time.Local)))
+	week := scheduler.NextWeek()
+	if !week.AddDate(0, 0, 7).After(d.Clock.Now()) {
+		d.logf("неделя с %s уже прошла, запуск пропущен", week.Format("2006-01-02"))
+		return nil
+	}
+
+	err := d.Service.Update(d.actor(due), func(state *State) error {
+		record, ok := state.History.FindWeek(week)
+		switch {
+		case ok && record.Planned():
+			reseted, err := scheduler.ConfirmWeek(state.Employees, state.History, week)
+			if err != nil {
+				return err
+			}
+			d.logf("неделя с %s была запланирована, расписание утверждено без изменений", week.Format("2006-01-02"))
+			if reseted {
+				d.logf("счетчики дежурств сброшены")
+			}
+			return nil
+		case ok:
+			d.logf("расписание на неделю с %s уже сформировано", week.Format("2006-01-02"))
+			return nil
+		}
+
+		_, reseted, err := scheduler.GenerateWeek(state.Employees, state.History)
+		if err != nil {
+			return err
+		}
+		d.logf("сформировано расписание на неделю с %s", week.Format("2006-01-02"))
+		if reseted {
+			d.logf("счетчики дежурств сброшены")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(d.Channels) == 0 {
+		return nil
+	}
+	return d.publish(ctx, scheduler, week)
+}
+
+// publish отправляет неделю в каналы, которых еще нет в отметке о публикации. Сообщения отправляются
+// без блокировки данных: сетевые повторы могут идти минутами, и все это время CLI, бот и сервер
+// не смогли бы ничего изменить. Отметка о каждом успешном канале сохраняется сразу после отправки,
+// чтобы при частичной неудаче повтор не отправлял сообщение туда, где оно уже есть.
+func (d *Daemon) publish(ctx context.Context, scheduler *Scheduler, week time.Time) error {
+	var targets []string
+	var schedule WeekSchedule
+	err := d.Service.View(func(state *State) error {
+		record, ok := state.History.FindWeek(week)
+		if !ok {
+			return fmt.Errorf("неделя с %s не найдена в истории", week.Format("2006-01-02"))
+		}
+		targets = record.publishedTargets()
+		schedule = scheduler.HistorySchedule(*record)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var publishErr error
+	for _, channel := range d.Channels {
+		target := channel.Publisher.Target()
+		if containsString(targets, target) {
+			continue
+		}
+		message, err := channel.Renderer.Render(schedule)
+		if err == nil {
+			err = channel.Publisher.Publish(ctx, message)
+		}
+		if err != nil {
+			d.logf("не удалось опубликовать в %s: %s", target, err)
+			publishErr = fmt.Errorf("публикация в %s: %w", target, err)
+			continue
+		}
+		d.logf("расписание на неделю с %s опубликовано в %s", week.Format("2006-01-02"), target)
+		if err := d.markPublished(scheduler, week, target); err != nil {
+			return err
+		}
+	}
+	return publishErr
+}
+
+// markPublished добавляет канал target в отметку о публикации недели.
+func (d *Daemon) markPublished(scheduler *Scheduler, week time.Time, target string) error {
+	return d.Service.Update(d.actor(scheduler.now()), func(state *State) error {
+		record, ok := state.History.FindWeek(week)
+		if !ok {
+			return fmt.Errorf("неделя с %s не найдена в истории", week.Format("2006-01-02"))
+		}
+		targets := record.publishedTargets()
+		if !containsString(targets, target) {
+			targets = append(targets, target)
+		}
+		record.Publication = &Publication{PublishedAt: d.Clock.Now(), Target: strings.Join(targets, ", ")}
+		return nil
+	})
+}
+
+// publishedTargets возвращает каналы, в которые неделя уже опубликована.
+func (record *DutyHistory) publishedTargets() []string {
+	if record.Publication == nil {
+		return nil
+	}
+	return strings.Split(record.Publication.Target, ", ")
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package pkg
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestDaemonConfirmsPlannedWeekWithCounterReset(t *testing.T) {
+	due := testMonday.AddDate(0, 0, -3).Add(15 * time.Hour)
+	config := DefaultConfig()
+	config.DutyTypes = resetDutyTypes("express", "instances", "support")
+	employees := testEmployees("Анна", "Борис", "Вера", "Глеб", "Дина", "Ефим")
+	storage := &DutyHistoryStorage{}
+	if _, err := config.NewScheduler(FixedClock(due.AddDate(0, 0, -7))).PlanWeeks(&employees, storage, 2); err != nil {
+		t.Fatalf("PlanWeeks: %v", err)
+	}
+	storage.LastResetDate = due.AddDate(0, 0, -100)
+
+	repo := NewJSONRepository(t.TempDir())
+	if err := repo.Save(&State{Employees: &employees, History: storage}, nil); err != nil {
+		t.Fatal(err)
+	}
+	schedule, err := ParseCronSchedule("0 15 * * fri", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	daemon := &Daemon{Service: NewService(repo, config, ""), Schedule: schedule, Clock: FixedClock(due), Log: io.Discard}
+
+	if err := daemon.RunOnce(context.Background(), due); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+
+	state := loadState(t, repo)
+	record, ok := state.History.FindWeek(testMonday)
+	if !ok || record.Planned() {
+		t.Fatalf("неделя с %s не утверждена", testMonday.Format("2006-01-02"))
+	}
+	// утверждение запланированной недели сбрасывает счетчики так же, как GenerateWeek:
+	// в новом периоде остаются только дежурства утвержденной недели
+	if !state.History.LastResetDate.Equal(due) {
+		t.Errorf("дата сброса %s, ожидалась %s", state.History.LastResetDate, due)
+	}
+	scheduler := config.NewScheduler(FixedClock(due))
+	if changes := scheduler.RecomputeStats(state.Employees, state.History, due, time.Time{}); len(changes) > 0 {
+		t.Errorf("счетчики после сброса расходятся с неделей:\n%s", scheduler.FormatStatsChanges(changes))
+	}
+}
@@ -0,0 +1,341 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Периодичность дежурства.
+const (
+	CadenceDaily  = "daily"  // отдельный дежурный на каждый из дней Days
+	CadenceWeekly = "weekly" // один дежурный на неделю, дежурство в день Weekday
+	// дежурный на каждый праздник, выпавший на один из дней Days; в обычные дни не назначается
+	CadenceHoliday = "holiday"
+)
+
+// DutyType описывает вид дежурства: как часто оно бывает, сколько людей на него нужно
+// и через сколько дней после дежурства сотрудника можно назначать снова.
+type DutyType struct {
+	Name          string   `json:"name"`           // ключ в счетчиках сотрудника и в истории
+	Title         string   `json:"title"`          // название в расписании
+	Section       string   `json:"section"`        // заголовок блока в сообщении с расписанием
+	Cadence       string   `json:"cadence"`        // CadenceDaily, CadenceWeekly или CadenceHoliday
+	Weekday       string   `json:"weekday"`        // день недели дежурства для CadenceWeekly (monday...sunday)
+	Days          []string `json:"days"`           // дни недели для CadenceDaily и CadenceHoliday, по умолчанию с понедельника по пятницу
+	CooldownDays  int      `json:"cooldown_days"`  // сколько дней должно пройти с прошлого дежурства
+	CooldownGroup string   `json:"cooldown_group"` // виды дежурств одной группы делят дату последнего дежурства
+	Slots         int      `json:"slots"`          // сколько сотрудников назначать на один день (неделю)
+	Weight        int      `json:"weight"`         // на сколько увеличивается счетчик сотрудника за одно дежурство
+	ExcludeDuties []string `json:"exclude_duties"` // не назначать тех, кто на этой неделе уже назначен на эти виды
+	EventTime     string   `json:"event_time"`     // время начала события в календаре (HH:MM), пусто - событие на весь день
+	EventMinutes  int      `json:"event_minutes"`  // длительность события в календаре, по умолчанию 60 минут
+	// Нагрузка сотрудника, по которой выбирается дежурный, считается по истории: FairnessWindowDays -
+	// сумма весов дежурств за столько последних дней (по умолчанию DefaultFairnessWindowDays),
+	// FairnessHalfLifeDays - сумма весов, вклад которых уменьшается вдвое за столько дней.
+	// ResetCounters возвращает прежний порядок: нагрузка - счетчик, который сбрасывается раз в 90 дней.
+	FairnessWindowDays   int  `json:"fairness_window_days"`
+	FairnessHalfLifeDays int  `json:"fairness_half_life_days"`
+	ResetCounters        bool `json:"reset_counters"`
+	// RequiredSkills - навыки (Employee.Skills), без которых сотрудника не назначают дежурным.
+	RequiredSkills []string `json:"required_skills"`
+	// ShadowShifts - сколько смен стажером рядом с дежурным нужно отстоять сотруднику без навыков,
+	// чтобы получить RequiredSkills. 0 - стажеров на дежурство не ставят.
+	ShadowShifts int `json:"shadow_shifts"`
+}
+
+// DefaultDutyTypes возвращает дежурства, с которыми программа работала до появления настроек:
+// Express Release и Instances release по четвергам и ежедневный Support.
+func DefaultDutyTypes() []DutyType {
+	return []DutyType{
+		{
+			Name:          "express",
+			Title:         "Express Release",
+			Section:       "Релизы",
+			Cadence:       CadenceWeekly,
+			Weekday:       "thursday",
+			CooldownDays:  14,
+			CooldownGroup: "release",
+			Slots:         1,
+			Weight:        2,
+		},
+		{
+			Name:          "instances",
+			Title:         "Instances release",
+			Section:       "Релизы",
+			Cadence:       CadenceWeekly,
+			Weekday:       "thursday",
+			CooldownDays:  14,
+			CooldownGroup: "release",
+			Slots:         1,
+			Weight:        1,
+			ExcludeDuties: []string{"express"},
+		},
+		{
+			Name:         "support",
+			Title:        "Support",
+			Section:      "Саппорт",
+			Cadence:      CadenceDaily,
+			Days:         []string{"monday", "tuesday", "wednesday", "thursday", "friday"},
+			CooldownDays: 7,
+			Slots:        1,
+			Weight:       2,
+		},
+	}
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+	"sunday":    time.Sunday,
+}
+
+// weekdaysRu - названия дней недели для сообщений.
+var weekdaysRu = map[time.Weekday]string{
+	time.Monday:    "понедельник",
+	time.Tuesday:   "вторник",
+	time.Wednesday: "среда",
+	time.Thursday:  "четверг",
+	time.Friday:    "пятница",
+	time.Saturday:  "суббота",
+	time.Sunday:    "воскресенье",
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	weekday, ok := weekdayNames[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("неизвестный день недели: %q", name)
+	}
+	return weekday, nil
+}
+
+// daysFromMonday возвращает смещение дня недели от понедельника.
+func daysFromMonday(weekday time.Weekday) int {
+	return (int(weekday) + 6) % 7
+}
+
+// ValidateDutyTypes проверяет настройки дежурств и подставляет значения по умолчанию.
+func ValidateDutyTypes(dutyTypes []DutyType) error {
+	if len(dutyTypes) == 0 {
+		return fmt.Errorf("не задано ни одного вида дежурства")
+	}
+
+	names := map[string]bool{}
+	for i := range dutyTypes {
+		dutyType := &dutyTypes[i]
+		if dutyType.Name == "" {
+			return fmt.Errorf("у дежурства №%d не указано имя", i+1)
+		}
+		if names[dutyType.Name] {
+			return fmt.Errorf("дежурство %q описано дважды", dutyType.Name)
+		}
+		names[dutyType.Name] = true
+
+		if dutyType.Title == "" {
+			dutyType.Title = dutyType.Name
+		}
+		if dutyType.Section == "" {
+			dutyType.Section = dutyType.Title
+		}
+		if dutyType.Slots <= 0 {
+			dutyType.Slots = 1
+		}
+		if dutyType.Weight <= 0 {
+			dutyType.Weight = 1
+		}
+		if dutyType.CooldownDays < 0 {
+			return fmt.Errorf("дежурство %q: cooldown_days не может быть отрицательным", dutyType.Name)
+		}
+		if dutyType.FairnessWindowDays < 0 || dutyType.FairnessHalfLifeDays < 0 {
+			return fmt.Errorf("дежурство %q: fairness_window_days и fairness_half_life_days не могут быть отрицательными", dutyType.Name)
+		}
+		if dutyType.FairnessWindowDays > 0 && dutyType.FairnessHalfLifeDays > 0 {
+			return fmt.Errorf("дежурство %q: укажите либо fairness_window_days, либо fairness_half_life_days", dutyType.Name)
+		}
+		if dutyType.ResetCounters && (dutyType.FairnessWindowDays > 0 || dutyType.FairnessHalfLifeDays > 0) {
+			return fmt.Errorf("дежурство %q: reset_counters не сочетается с fairness_window_days и fairness_half_life_days", dutyType.Name)
+		}
+		if dutyType.ShadowShifts < 0 {
+			return fmt.Errorf("дежурство %q: shadow_shifts не может быть отрицательным", dutyType.Name)
+		}
+		if dutyType.ShadowShifts > 0 && len(dutyType.RequiredSkills) == 0 {
+			return fmt.Errorf("дежурство %q: стажеры (shadow_shifts) нужны только дежурству с required_skills", dutyType.Name)
+		}
+
+		if dutyType.EventTime != "" {
+			if _, err := time.Parse("15:04", dutyType.EventTime); err != nil {
+				return fmt.Errorf("дежурство %q: неверное время события %q, ожидается HH:MM", dutyType.Name, dutyType.EventTime)
+			}
+		}
+
+		switch dutyType.Cadence {
+		case CadenceWeekly:
+			if _, err := parseWeekday(dutyType.Weekday); err != nil {
+				return fmt.Errorf("дежурство %q: %w", dutyType.Name, err)
+			}
+		case CadenceDaily, CadenceHoliday:
+			if len(dutyType.Days) == 0 {
+				dutyType.Days = []string{"monday", "tuesday", "wednesday", "thursday", "friday"}
+			}
+			for _, day := range dutyType.Days {
+				if _, err := parseWeekday(day); err != nil {
+					return fmt.Errorf("дежурство %q: %w", dutyType.Name, err)
+				}
+			}
+		default:
+			return fmt.Errorf("дежурство %q: неизвестная периодичность %q (daily, weekly, holiday)", dutyType.Name, dutyType.Cadence)
+		}
+	}
+
+	for _, dutyType := range dutyTypes {
+		for _, excluded := range dutyType.ExcludeDuties {
+			if !names[excluded] {
+				return fmt.Errorf("дежурство %q исключает неизвестное дежурство %q", dutyType.Name, excluded)
+			}
+		}
+	}
+	return nil
+}
+
+// dates возвращает дни недели, начинающейся с weekStart, в которые дежурство стоит по настройкам,
+// без учета праздников.
+func (d DutyType) dates(weekStart time.Time) []time.Time {
+	if d.Cadence == CadenceWeekly {
+		weekday, _ := parseWeekday(d.Weekday)
+		return []time.Time{weekStart.AddDate(0, 0, daysFromMonday(weekday))}
+	}
+
+	dates := make([]time.Time, 0, len(d.Days))
+	for _, day := range d.Days {
+		weekday, _ := parseWeekday(day)
+		dates = append(dates, weekStart.AddDate(0, 0, daysFromMonday(weekday)))
+	}
+	return dates
+}
+
+// dutyDates возвращает дни недели, в которые нужен дежурный, с учетом календаря: ежедневные
+// дежурства в праздники не назначаются, еженедельные переносятся на следующий рабочий день,
+// праздничные стоят только в праздники.
+func (s *Scheduler) dutyDates(dutyType DutyType, weekStart time.Time) []time.Time {
+	var dates []time.Time
+	for _, date := range dutyType.dates(weekStart) {
+		_, holiday := s.Calendar.Holiday(date)
+		switch dutyType.Cadence {
+		case CadenceWeekly:
+			dates = append(dates, s.Calendar.nextWorkingDay(date))
+		case CadenceHoliday:
+			if holiday {
+				dates = append(dates, date)
+			}
+		default:
+			if !holiday {
+				dates = append(dates, date)
+			}
+		}
+	}
+	return dates
+}
+
+// excludes проверяет, запрещено ли назначать на это дежурство человека, уже назначенного на duty.
+func (d DutyType) excludes(duty string) bool {
+	if duty == d.Name {
+		return true
+	}
+	for _, excluded := range d.ExcludeDuties {
+		if excluded == duty {
+			return true
+		}
+	}
+	return false
+}
+
+// incompatible проверяет, что одному человеку нельзя держать на одной неделе дежурства a и b.
+// Исключение действует в обе стороны: достаточно, чтобы одно из дежурств исключало другое.
+func (s *Scheduler) incompatible(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if dutyType, ok := s.dutyType(a); ok && dutyType.excludes(b) {
+		return true
+	}
+	if dutyType, ok := s.dutyType(b); ok && dutyType.excludes(a) {
+		return true
+	}
+	return false
+}
+
+// dutyType ищет вид дежурства по имени.
+func (s *Scheduler) dutyType(name string) (DutyType, bool) {
+	for _, dutyType := range s.DutyTypes {
+		if dutyType.Name == name {
+			return dutyType, true
+		}
+	}
+	return DutyType{}, false
+}
+
+// FindDutyType ищет вид дежурства по имени или названию без учета регистра.
+func (s *Scheduler) FindDutyType(query string) (DutyType, bool) {
+	for _, dutyType := range s.DutyTypes {
+		if strings.EqualFold(dutyType.Name, query) || strings.EqualFold(dutyType.Title, query) {
+			return dutyType, true
+		}
+	}
+	return DutyType{}, false
+}
+
+// DutyTitle возвращает название дежурства для вывода. Неизвестные виды выводятся по имени.
+func (s *Scheduler) DutyTitle(name string) string {
+	if dutyType, ok := s.dutyType(name); ok {
+		return dutyType.Title
+	}
+	return name
+}
+
+// inCooldown сообщает, что к дежурству на день date после дежурства last не прошел перерыв CooldownDays.
+// Перерыв отсчитывается от дня самого дежурства, одинаково для жадного подбора и оптимизатора.
+func (d DutyType) inCooldown(last, date time.Time) bool {
+	return d.CooldownDays > 0 && !last.IsZero() &&
+		dayOf(date).Sub(dayOf(last)) < time.Duration(d.CooldownDays)*24*time.Hour
+}
+
+// cooldownGroup возвращает имена дежурств, которые делят дату последнего дежурства с dutyType.
+func (s *Scheduler) cooldownGroup(dutyType DutyType) []string {
+	if dutyType.CooldownGroup == "" {
+		return []string{dutyType.Name}
+	}
+
+	var names []string
+	for _, other := range s.DutyTypes {
+		if other.CooldownGroup == dutyType.CooldownGroup {
+			names = append(names, other.Name)
+		}
+	}
+	return names
+}
+
+// lastDuty возвращает дату последнего дежурства сотрудника среди дежурств names.
+func (e Employee) lastDuty(names []string) time.Time {
+	var last time.Time
+	for _, name := range names {
+		if stats := e.Duties[name]; stats.LastDuty.After(last) {
+			last = stats.LastDuty
+		}
+	}
+	return last
+}
+
+// recordDuty учитывает дежурство в счетчиках сотрудника.
+func (e *Employee) recordDuty(dutyType DutyType, date time.Time) {
+	if e.Duties == nil {
+		e.Duties = map[string]DutyStats{}
+	}
+	stats := e.Duties[dutyType.Name]
+	stats.Count += dutyType.Weight
+	stats.LastDuty = date
+	e.Duties[dutyType.Name] = stats
+}
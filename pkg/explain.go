@@ -0,0 +1,224 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Причины, по которым кандидат не получил слот (Candidate.Reason).
+const (
+	ReasonSick     = StatusSick     // болеет в период дежурства
+	ReasonVacation = StatusVacation // в отпуске в период дежурства
+	ReasonCooldown = "cooldown"     // с прошлого дежурства не прошел перерыв CooldownDays
+	ReasonAssigned = "assigned"     // на этой неделе уже назначен на это дежурство
+	ReasonExcluded = "excluded"     // на этой неделе назначен на дежурство из ExcludeDuties (например, Express)
+	ReasonQueue    = "queue"        // подходил, но в очереди стоял ниже выбранного
+	// ReasonBlackout - день дежурства у сотрудника в Preferences.Blackouts.
+	ReasonBlackout = "blackout"
+	// ReasonMonthlyLimit - в месяце дежурства уже набран предел Preferences.MaxPerMonth.
+	ReasonMonthlyLimit = "monthly_limit"
+	// ReasonUnqualified - у сотрудника нет навыков из DutyType.RequiredSkills.
+	ReasonUnqualified = "unqualified"
+	// ReasonOtherTeam - в этот день сотрудник дежурит в другой команде.
+	ReasonOtherTeam = "other_team"
+)
+
+// Правила, по которым выбран дежурный (SlotDecision.Rule).
+const (
+	// RuleFewestDuties - у выбранного наименьшая нагрузка среди подходящих кандидатов.
+	RuleFewestDuties = "fewest_duties"
+	// RuleLongestRest - нагрузка равная, выбран тот, кто дольше всех не дежурил.
+	RuleLongestRest = "longest_rest"
+	// RuleListOrder - нагрузка равная и дата последнего дежурства одна, выбран первый по списку сотрудников.
+	RuleListOrder = "list_order"
+	// RulePreference - нагрузка равная, выбран тот, кто предпочитает дежурство или не просил его избегать.
+	RulePreference = "preference"
+	// RuleCooldownFallback - перерыв не прошел ни у кого, выбран первый доступный с наименьшей нагрузкой.
+	RuleCooldownFallback = "cooldown_fallback"
+	// RulePinned - сотрудник закреплен за слотом до формирования расписания.
+	RulePinned = "pinned"
+	// RuleOptimized - дежурный подобран оптимизатором для всей недели сразу (solver.engine = optimize).
+	RuleOptimized = "optimized"
+)
+
+// Candidate - сотрудник, рассмотренный при подборе дежурного на слот.
+type Candidate struct {
+	EmployeeId   int       `json:"employee_id"`
+	EmployeeName string    `json:"employee_name"`
+	Count        int       `json:"count"`                // счетчик этого дежурства на момент подбора
+	Load         float64   `json:"load"`                 // нагрузка, по которой выстроена очередь: счетчик или сумма по окну справедливости
+	LastDuty     time.Time `json:"last_duty"`            // последнее дежурство в группе перерыва
+	Chosen       bool      `json:"chosen,omitempty"`     // назначен на слот
+	Reason       string    `json:"reason,omitempty"`     // Reason*, пусто у выбранного
+	Detail       string    `json:"detail,omitempty"`     // пояснение к причине
+	Preference   string    `json:"preference,omitempty"` // "preferred" или "avoided" из Preferences сотрудника
+}
+
+// SlotDecision - разбор подбора дежурного на один слот: кандидаты в порядке очереди (по нагрузке,
+// затем по предпочтениям и по дате последнего дежурства) и правило, по которому выбран дежурный.
+// Уволенные сотрудники в разбор не попадают.
+type SlotDecision struct {
+	Duty         string      `json:"duty"`
+	Date         time.Time   `json:"date"`
+	EmployeeId   int         `json:"employee_id"`
+	EmployeeName string      `json:"employee_name"`
+	Rule         string      `json:"rule"`
+	Candidates   []Candidate `json:"candidates"`
+	// Overridden - пожелания сотрудников, которые не удалось учесть: выбранный просил избегать
+	// дежурства или тот, кто его предпочитает, не назначен.
+	Overridden []string `json:"overridden,omitempty"`
+}
+
+// reasonTitles - причины отказа для вывода.
+var reasonTitles = map[string]string{
+	ReasonSick:         "болеет",
+	ReasonVacation:     "в отпуске",
+	ReasonCooldown:     "не прошел перерыв",
+	ReasonAssigned:     "уже дежурит на этой неделе",
+	ReasonExcluded:     "назначен на несовместимое дежурство",
+	ReasonQueue:        "ниже в очереди",
+	ReasonBlackout:     "не дежурит в этот день недели",
+	ReasonMonthlyLimit: "набран предел дежурств в месяц",
+	ReasonUnqualified:  "не допущен к дежурству",
+	ReasonOtherTeam:    "дежурит в другой команде",
+}
+
+// ruleTitles - правила выбора для вывода.
+var ruleTitles = map[string]string{
+	RuleFewestDuties:     "наименьшая нагрузка",
+	RuleLongestRest:      "нагрузка равная, дольше всех не дежурил",
+	RuleListOrder:        "нагрузка равная, не дежурили одинаково долго, выбран первый по списку",
+	RulePreference:       "нагрузка равная, выбран по пожеланиям",
+	RuleCooldownFallback: "перерыв не прошел ни у кого, взят доступный с наименьшей нагрузкой",
+	RulePinned:           "закреплен заранее",
+	RuleOptimized:        "подобран оптимизатором по всей неделе",
+}
+
+// preferenceNames - отношение сотрудника к дежурству для Candidate.Preference.
+var preferenceNames = map[int]string{
+	preferencePreferred: "preferred",
+	preferenceAvoided:   "avoided",
+}
+
+// exclusion возвращает причину, по которой сотрудника нельзя назначить на слот без учета перерыва
+// между дежурствами, и пояснение к ней. Пустая причина - сотрудника назначить можно. Предел дежурств
+// в месяц считается по истории history до подбираемой недели и назначениям assigned.
+func (s *Scheduler) exclusion(sl slot, employee Employee, history []DutyHistory, assigned []Assignment) (string, string) {
+	// Исключаем сотрудника, который отсутствует в период дежурства.
+	if absence, ok := employee.AbsenceDuring(sl.from, sl.to); ok {
+		if absence.Start.IsZero() {
+			return absence.Kind, "бессрочно"
+		}
+		if absence.OpenEnded() {
+			return absence.Kind, "с " + absence.Start.Format("2006-01-02")
+		}
+		return absence.Kind, fmt.Sprintf("с %s по %s", absence.Start.Format("2006-01-02"), absence.End.Format("2006-01-02"))
+	}
+
+	// Исключаем сотрудника, который в этот день дежурит в другой команде.
+	if duty, ok := s.teamDuty(employee, sl.date); ok {
+		return ReasonOtherTeam, fmt.Sprintf("%s: %s", TeamTitle(duty.Team), duty.Duty)
+	}
+
+	// Исключаем сотрудника, который просил не ставить его на этот день недели.
+	if employee.blackout(sl.date) {
+		return ReasonBlackout, weekdaysRu[sl.date.Weekday()]
+	}
+
+	// Исключаем сотрудника, который на этой неделе уже назначен на это же или несовместимое дежурство.
+	for _, assignment := range assigned {
+		if assignment.EmployeeId != employee.Id || !s.incompatible(sl.dutyType.Name, assignment.Duty) {
+			continue
+		}
+		if assignment.Duty == sl.dutyType.Name {
+			return ReasonAssigned, assignment.Date.Format("2006-01-02")
+		}
+		return ReasonExcluded, fmt.Sprintf("%s %s", s.DutyTitle(assignment.Duty), assignment.Date.Format("2006-01-02"))
+	}
+
+	// Исключаем сотрудника, у которого в этом месяце уже набран предел дежурств.
+	if employee.Preferences != nil && employee.Preferences.MaxPerMonth > 0 {
+		n := monthlyDuties(employee.Id, sl.date, history, assigned, s.nextMonday())
+		if n >= employee.Preferences.MaxPerMonth {
+			return ReasonMonthlyLimit, fmt.Sprintf("%d из %d", n, employee.Preferences.MaxPerMonth)
+		}
+	}
+	return "", ""
+}
+
+// Explain возвращает разбор недели в виде текста: для каждого слота выбранного дежурного, правило
+// выбора и причины, по которым не подошли остальные кандидаты, затем стажеров недели и обмены
+// и замены дежурных, сделанные после формирования.
+func (s *Scheduler) Explain(record DutyHistory) string {
+	var b strings.Builder
+	if len(record.Decisions) == 0 {
+		fmt.Fprintf(&b, "Для недели с %s разбора нет: она сформирована до того, как разбор начал сохраняться.\n", record.Date.Format("2006-01-02"))
+	}
+
+	for i, decision := range record.Decisions {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s %s (%s): %s - %s\n", s.DutyTitle(decision.Duty), decision.Date.Format("2006-01-02"),
+			weekdaysRu[decision.Date.Weekday()], decision.EmployeeName, ruleTitles[decision.Rule])
+		candidates := decision.Candidates
+		if decision.Rule == RulePinned || decision.Rule == RuleOptimized {
+			// у закрепления и оптимизатора нет очереди кандидатов, только учтены ли пожелания
+			candidates = nil
+		}
+		for n, candidate := range candidates {
+			lastDuty := "не дежурил"
+			if !candidate.LastDuty.IsZero() {
+				lastDuty = "последнее " + candidate.LastDuty.Format("2006-01-02")
+			}
+			verdict := "назначен"
+			if !candidate.Chosen {
+				verdict = reasonTitles[candidate.Reason]
+				if candidate.Detail != "" {
+					verdict += " (" + candidate.Detail + ")"
+				}
+			}
+			load := fmt.Sprintf("дежурств: %d", candidate.Count)
+			if dutyType, ok := s.dutyType(decision.Duty); ok && dutyType.rolling() {
+				load += fmt.Sprintf(", нагрузка: %.2f", candidate.Load)
+			}
+			switch candidate.Preference {
+			case preferenceNames[preferencePreferred]:
+				verdict += ", предпочитает это дежурство"
+			case preferenceNames[preferenceAvoided]:
+				verdict += ", просит избегать этого дежурства или дня"
+			}
+			fmt.Fprintf(&b, "  %d. %s | %s, %s | %s\n", n+1, candidate.EmployeeName, load, lastDuty, verdict)
+		}
+		for _, note := range decision.Overridden {
+			fmt.Fprintf(&b, "  ! пожелание не учтено: %s\n", note)
+		}
+	}
+
+	if len(record.Shadows) > 0 {
+		b.WriteString("\nСтажеры:\n")
+		for _, shadow := range record.Shadows {
+			shifts := ""
+			if dutyType, ok := s.dutyType(shadow.Duty); ok && dutyType.ShadowShifts > 0 {
+				shifts = fmt.Sprintf(" из %d", dutyType.ShadowShifts)
+			}
+			fmt.Fprintf(&b, "  %s %s: %s, смена %d%s", s.DutyTitle(shadow.Duty), shadow.Date.Format("2006-01-02"), shadow.EmployeeName, shadow.Shift, shifts)
+			if len(shadow.Promoted) > 0 {
+				fmt.Fprintf(&b, ", после нее получены навыки: %s", strings.Join(shadow.Promoted, ", "))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if len(record.Changes) > 0 {
+		b.WriteString("\nИзменения после формирования:\n")
+		for _, change := range record.Changes {
+			fmt.Fprintf(&b, "%s, попросил(а) %s:\n", change.At.Format("2006-01-02 15:04"), change.RequestedBy)
+			for _, move := range change.Moves {
+				fmt.Fprintf(&b, "  %s %s: %s -> %s\n", s.DutyTitle(move.Duty), move.Date.Format("2006-01-02"), move.FromName, move.ToName)
+			}
+		}
+	}
+	return b.String()
+}
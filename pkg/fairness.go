@@ -0,0 +1,71 @@
+package pkg
+
+import (
+	"math"
+	"time"
+)
+
+// DefaultFairnessWindowDays - окно справедливости дежурства, у которого не задано ни окно,
+// ни период полураспада, ни сброс счетчиков.
+const DefaultFairnessWindowDays = 90
+
+// rolling сообщает, что нагрузка по дежурству считается по истории, а не по счетчику.
+func (d DutyType) rolling() bool {
+	return !d.ResetCounters
+}
+
+// assignmentLoad возвращает вклад дежурства в день date в нагрузку на неделю weekStart:
+// вес дежурства, если оно попадает в окно, или вес, уменьшенный по периоду полураспада.
+// Дежурства самой недели weekStart учитываются полностью.
+func (d DutyType) assignmentLoad(date, weekStart time.Time) float64 {
+	age := dayOf(weekStart).Sub(dayOf(date)).Hours() / 24
+	if age < 0 {
+		age = 0
+	}
+	if d.FairnessHalfLifeDays > 0 {
+		return float64(d.Weight) * math.Pow(0.5, age/float64(d.FairnessHalfLifeDays))
+	}
+	window := d.FairnessWindowDays
+	if window <= 0 {
+		window = DefaultFairnessWindowDays
+	}
+	if age > float64(window) {
+		return 0
+	}
+	return float64(d.Weight)
+}
+
+// dutyLoads возвращает нагрузку сотрудников по дежурству dutyType при подборе на неделю weekStart
+// в порядке employees. Для дежурств со сбросом счетчиков это счетчик, иначе - сумма вкладов
+// дежурств из истории до этой недели и уже сделанных на нее назначений assigned.
+func (s *Scheduler) dutyLoads(employees []Employee, dutyType DutyType, history []DutyHistory, assigned []Assignment, weekStart time.Time) []float64 {
+	loads := make([]float64, len(employees))
+	if !dutyType.rolling() {
+		for i, employee := range employees {
+			loads[i] = float64(employee.Duties[dutyType.Name].Count)
+		}
+		return loads
+	}
+
+	index := map[int]int{}
+	for i, employee := range employees {
+		index[employee.Id] = i
+	}
+	add := func(assignment Assignment) {
+		if i, ok := index[assignment.EmployeeId]; ok && assignment.Duty == dutyType.Name {
+			loads[i] += dutyType.assignmentLoad(assignment.Date, weekStart)
+		}
+	}
+	for _, record := range history {
+		if !record.Date.Before(weekStart) {
+			continue
+		}
+		for _, assignment := range record.Assignments {
+			add(assignment)
+		}
+	}
+	for _, assignment := range assigned {
+		add(assignment)
+	}
+	return loads
+}
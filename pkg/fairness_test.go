@@ -0,0 +1,111 @@
+package pkg
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAssignmentLoad(t *testing.T) {
+	week := testMonday
+	tests := []struct {
+		name     string
+		dutyType DutyType
+		age      int // дней от дежурства до недели подбора
+		expected float64
+	}{
+		{name: "окно по умолчанию, свежее дежурство", dutyType: DutyType{Weight: 2}, age: 3, expected: 2},
+		{name: "окно по умолчанию, последний день окна", dutyType: DutyType{Weight: 2}, age: DefaultFairnessWindowDays, expected: 2},
+		{name: "окно по умолчанию, дежурство вышло из окна", dutyType: DutyType{Weight: 2}, age: DefaultFairnessWindowDays + 1},
+		{name: "свое окно", dutyType: DutyType{Weight: 1, FairnessWindowDays: 30}, age: 31},
+		{name: "дежурство на неделе подбора", dutyType: DutyType{Weight: 1, FairnessWindowDays: 30}, age: -3, expected: 1},
+		{name: "полураспад", dutyType: DutyType{Weight: 2, FairnessHalfLifeDays: 14}, age: 14, expected: 1},
+		{name: "два полураспада", dutyType: DutyType{Weight: 2, FairnessHalfLifeDays: 14}, age: 28, expected: 0.5},
+		{name: "полураспад без окна", dutyType: DutyType{Weight: 2, FairnessHalfLifeDays: 14}, age: 365, expected: 2 * math.Pow(0.5, 365.0/14)},
+	}
+
+	for _, tt := range tests {
+		got := tt.dutyType.assignmentLoad(week.AddDate(0, 0, -tt.age), week)
+		if math.Abs(got-tt.expected) > 1e-9 {
+			t.Errorf("%s: вклад %g, ожидался %g", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestDutyLoads(t *testing.T) {
+	employees := testEmployees("Анна", "Борис", "Вера")
+	employees[2].Duties["support"] = DutyStats{Count: 6}
+	history := []DutyHistory{
+		{Date: testMonday.AddDate(0, 0, -98), Assignments: []Assignment{{Duty: "support", Date: testMonday.AddDate(0, 0, -98), EmployeeId: 1}}},
+		{Date: testMonday.AddDate(0, 0, -7), Assignments: []Assignment{{Duty: "support", Date: testMonday.AddDate(0, 0, -6), EmployeeId: 2}}},
+	}
+	assigned := []Assignment{{Duty: "support", Date: testMonday, EmployeeId: 1}}
+
+	tests := []struct {
+		name     string
+		dutyType DutyType
+		expected []float64
+	}{
+		// у Анны дежурство 98 дней назад вышло из окна, но считается назначение на эту неделю
+		{name: "окно по умолчанию", dutyType: DefaultDutyTypes()[2], expected: []float64{2, 2, 0}},
+		{name: "счетчик со сбросом", dutyType: resetDutyTypes("support")[2], expected: []float64{0, 0, 6}},
+	}
+	for _, tt := range tests {
+		got := NewScheduler(FixedClock(testMonday), nil).dutyLoads(employees, tt.dutyType, history, assigned, testMonday)
+		for i := range got {
+			if math.Abs(got[i]-tt.expected[i]) > 1e-9 {
+				t.Errorf("%s: нагрузка %v, ожидалась %v", tt.name, got, tt.expected)
+				break
+			}
+		}
+	}
+}
+
+func TestCountersResetOnlyOptedInDuties(t *testing.T) {
+	friday := testMonday.AddDate(0, 0, -3).Add(15 * time.Hour)
+	for _, tt := range []struct {
+		name   string
+		resets []string
+	}{
+		{name: "по умолчанию счетчики не сбрасываются"},
+		{name: "сбрасывается только Support", resets: []string{"support"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			employees := testEmployees("Анна", "Борис", "Вера", "Глеб", "Дина", "Ефим")
+			storage := &DutyHistoryStorage{}
+			total := map[string]int{}
+			resetSeen := false
+			for week := 0; week < 16; week++ {
+				scheduler := NewScheduler(FixedClock(friday.AddDate(0, 0, 7*week)), resetDutyTypes(tt.resets...))
+				_, reseted, err := scheduler.GenerateWeek(&employees, storage)
+				if err != nil {
+					t.Fatalf("неделя %d: %v", week, err)
+				}
+				resetSeen = resetSeen || reseted
+			}
+			weights := map[string]int{}
+			for _, dutyType := range DefaultDutyTypes() {
+				weights[dutyType.Name] = dutyType.Weight
+			}
+			for _, record := range storage.History {
+				for _, assignment := range record.Assignments {
+					total[assignment.Duty] += weights[assignment.Duty]
+				}
+			}
+
+			if resetSeen != (len(tt.resets) > 0) {
+				t.Errorf("сброс счетчиков: %v", resetSeen)
+			}
+			for duty, want := range total {
+				got := 0
+				for _, employee := range employees {
+					got += employee.Duties[duty].Count
+				}
+				reset := len(tt.resets) > 0 && duty == "support"
+				if reset && got >= want || !reset && got != want {
+					t.Errorf("%s: сумма счетчиков %d за всю историю %d, сброс %v", duty, got, want, reset)
+				}
+			}
+		})
+	}
+}
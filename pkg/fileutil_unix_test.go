@@ -0,0 +1,42 @@
+//go:build unix
+
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestWriteFileAtomicKeepsOriginalOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "employees.json")
+	original := []byte(`[{"id":1}]`)
+	if err := writeFileAtomic(path, original, 0644); err != nil {
+		t.Fatalf("writeFileAtomic: %v", err)
+	}
+
+	// ограничение на размер файла имитирует нехватку места посреди записи
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_FSIZE, &limit); err != nil {
+		t.Skipf("getrlimit: %v", err)
+	}
+	if err := syscall.Setrlimit(syscall.RLIMIT_FSIZE, &syscall.Rlimit{Cur: 16, Max: limit.Max}); err != nil {
+		t.Skipf("setrlimit: %v", err)
+	}
+	err := writeFileAtomic(path, make([]byte, 1024), 0644)
+	syscall.Setrlimit(syscall.RLIMIT_FSIZE, &limit)
+	if err == nil {
+		t.Fatal("запись сверх ограничения прошла без ошибки")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != string(original) {
+		t.Errorf("после неудачной записи файл содержит %q (ошибка %v), ожидалось %q", data, err, original)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("после неудачной записи в каталоге остались файлы: %v", entries)
+	}
+}
@@ -0,0 +1,248 @@
+package pkg
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Holiday - праздничный или другой нерабочий день.
+type Holiday struct {
+	Date    time.Time
+	Name    string
+	Country string // пусто, если день нерабочий для всех стран
+}
+
+// HolidayConfig - раздел holidays в config.json.
+type HolidayConfig struct {
+	Country string        `json:"country"` // код страны, праздники других стран игнорируются
+	Files   []string      `json:"files"`   // файлы .ics или .json, относительные пути считаются от каталога config.json
+	Dates   []holidayJSON `json:"dates"`   // праздники, заданные прямо в настройках
+}
+
+type holidayJSON struct {
+	Date    string `json:"date"` // YYYY-MM-DD
+	Name    string `json:"name"`
+	Country string `json:"country"`
+}
+
+func (h holidayJSON) holiday() (Holiday, error) {
+	date, err := time.Parse("2006-01-02", h.Date)
+	if err != nil {
+		return Holiday{}, fmt.Errorf("неверная дата праздника %q, ожидается YYYY-MM-DD", h.Date)
+	}
+	return Holiday{Date: date, Name: h.Name, Country: h.Country}, nil
+}
+
+// Calendar отвечает на вопрос, рабочий ли день. Пустой (nil) календарь считает
+// рабочими все дни с понедельника по пятницу.
+type Calendar struct {
+	holidays map[time.Time]Holiday
+}
+
+// NewCalendar создает календарь из списка праздников.
+func NewCalendar(holidays []Holiday) *Calendar {
+	calendar := &Calendar{holidays: map[time.Time]Holiday{}}
+	for _, holiday := range holidays {
+		calendar.holidays[dayOf(holiday.Date)] = holiday
+	}
+	return calendar
+}
+
+// Holiday возвращает праздник, приходящийся на дату.
+func (c *Calendar) Holiday(date time.Time) (Holiday, bool) {
+	if c == nil {
+		return Holiday{}, false
+	}
+	holiday, ok := c.holidays[dayOf(date)]
+	return holiday, ok
+}
+
+// IsWorkingDay проверяет, что дата - будний день и не праздник.
+func (c *Calendar) IsWorkingDay(date time.Time) bool {
+	if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+		return false
+	}
+	_, ok := c.Holiday(date)
+	return !ok
+}
+
+// nextWorkingDay возвращает date, если это рабочий день, иначе ближайший следующий рабочий день.
+func (c *Calendar) nextWorkingDay(date time.Time) time.Time {
+	// ограничиваем поиск, чтобы календарь из одних праздников не зациклил планировщик
+	for i := 0; i < 31; i++ {
+		if c.IsWorkingDay(date) {
+			return date
+		}
+		date = date.AddDate(0, 0, 1)
+	}
+	return date
+}
+
+// LoadCalendar собирает календарь по разделу holidays настроек. Относительные пути
+// к файлам считаются от каталога dir.
+func LoadCalendar(config HolidayConfig, dir string) (*Calendar, error) {
+	var holidays []Holiday
+
+	for _, file := range config.Files {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		loaded, err := LoadHolidays(file)
+		if err != nil {
+			return nil, err
+		}
+		holidays = append(holidays, loaded...)
+	}
+
+	for _, entry := range config.Dates {
+		holiday, err := entry.holiday()
+		if err != nil {
+			return nil, err
+		}
+		holidays = append(holidays, holiday)
+	}
+
+	var filtered []Holiday
+	for _, holiday := range holidays {
+		if holiday.Country == "" || config.Country == "" || strings.EqualFold(holiday.Country, config.Country) {
+			filtered = append(filtered, holiday)
+		}
+	}
+	return NewCalendar(filtered), nil
+}
+
+// LoadHolidays загружает праздники из файла. Формат определяется по расширению: .ics - календарь
+// iCalendar, иначе JSON - список {"date", "name", "country"} или объект вида {"ru": [...], "kz": [...]}.
+func LoadHolidays(filePath string) ([]Holiday, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть календарь праздников: %w", err)
+	}
+	defer file.Close()
+
+	var holidays []Holiday
+	if strings.EqualFold(filepath.Ext(filePath), ".ics") {
+		holidays, err = parseICS(file)
+	} else {
+		holidays, err = parseHolidaysJSON(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("календарь праздников %s: %w", filePath, err)
+	}
+	return holidays, nil
+}
+
+func parseHolidaysJSON(r io.Reader) ([]Holiday, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []holidayJSON
+	if err := json.Unmarshal(data, &entries); err != nil {
+		// наборы праздников по странам
+		var byCountry map[string][]holidayJSON
+		if err := json.Unmarshal(data, &byCountry); err != nil {
+			return nil, fmt.Errorf("ожидается список праздников или объект со списками по странам: %w", err)
+		}
+		for country, list := range byCountry {
+			for _, entry := range list {
+				entry.Country = country
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	holidays := make([]Holiday, 0, len(entries))
+	for _, entry := range entries {
+		holiday, err := entry.holiday()
+		if err != nil {
+			return nil, err
+		}
+		holidays = append(holidays, holiday)
+	}
+	return holidays, nil
+}
+
+var icsUnescaper = strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`)
+
+// parseICS читает события VEVENT календаря iCalendar. Каждый день события считается нерабочим,
+// DTEND, как и положено в iCalendar, в событие не входит.
+func parseICS(r io.Reader) ([]Holiday, error) {
+	// склеиваем перенесенные строки: продолжение начинается с пробела или табуляции
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var holidays []Holiday
+	var inEvent bool
+	var start, end time.Time
+	var summary string
+
+	for n, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, _, _ = strings.Cut(strings.ToUpper(name), ";")
+
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			inEvent = true
+			start, end, summary = time.Time{}, time.Time{}, ""
+		case name == "END" && value == "VEVENT":
+			inEvent = false
+			if start.IsZero() {
+				return nil, fmt.Errorf("строка %d: событие без DTSTART", n+1)
+			}
+			if !end.After(start) {
+				end = start.AddDate(0, 0, 1)
+			}
+			for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+				holidays = append(holidays, Holiday{Date: day, Name: summary})
+			}
+		case !inEvent:
+		case name == "DTSTART" || name == "DTEND":
+			date, err := parseICSDate(value)
+			if err != nil {
+				return nil, fmt.Errorf("строка %d: %w", n+1, err)
+			}
+			if name == "DTSTART" {
+				start = date
+			} else {
+				end = date
+			}
+		case name == "SUMMARY":
+			summary = icsUnescaper.Replace(value)
+		}
+	}
+	return holidays, nil
+}
+
+// parseICSDate разбирает дату DATE (20260101) или DATE-TIME (20260101T000000Z) и возвращает день.
+func parseICSDate(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("неверная дата %q", value)
+	}
+	date, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("неверная дата %q", value)
+	}
+	return date, nil
+}
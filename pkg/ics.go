@@ -0,0 +1,217 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// icsProductId - идентификатор программы в выгружаемых календарях.
+const icsProductId = "-//dev-support-schedule//RU"
+
+// ICSOptions - что попадает в календарь.
+type ICSOptions struct {
+	Name       string    // название календаря
+	EmployeeId int       // только дежурства этого сотрудника; 0 - вся команда
+	From       time.Time // только недели, начиная с этой даты; нулевая - вся история
+}
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+// WriteICS выгружает назначения из истории в формате iCalendar. Ежедневные дежурства выгружаются
+// событиями на весь день, еженедельные - в день дежурства, а если у вида дежурства задано
+// event_time - событием с этого времени. UID события зависит только от команды, дня, вида дежурства
+// и номера места, поэтому при повторном импорте календарь обновляет событие, а не дублирует его,
+// а календари разных команд с одинаковыми видами дежурств не затирают события друг друга.
+func (s *Scheduler) WriteICS(w io.Writer, storage *DutyHistoryStorage, options ICSOptions) error {
+	out := &icsWriter{w: bufio.NewWriter(w)}
+	stamp := s.now().UTC().Format("20060102T150405Z")
+	// у основной команды UID прежние, чтобы подписки не получили события заново
+	domain := "dev-support-schedule"
+	if s.Teams != nil && s.Teams.Current != "" {
+		domain = s.Teams.Current + "." + domain
+	}
+
+	out.line("BEGIN:VCALENDAR")
+	out.line("VERSION:2.0")
+	out.line("PRODID:" + icsProductId)
+	out.line("CALSCALE:GREGORIAN")
+	out.line("METHOD:PUBLISH")
+	if options.Name != "" {
+		out.line("X-WR-CALNAME:" + icsEscaper.Replace(options.Name))
+	}
+
+	for _, record := range storage.History {
+		if !options.From.IsZero() && record.Date.Before(WeekStart(options.From)) {
+			continue
+		}
+
+		status := "CONFIRMED"
+		if record.Planned() {
+			status = "TENTATIVE"
+		}
+
+		places := assignmentPlaces(record.Assignments)
+		for i, assignment := range record.Assignments {
+			place := places[i]
+
+			if options.EmployeeId != 0 && assignment.EmployeeId != options.EmployeeId {
+				continue
+			}
+
+			dutyType, ok := s.dutyType(assignment.Duty)
+			if !ok {
+				dutyType = DutyType{Name: assignment.Duty, Title: assignment.Duty}
+			}
+
+			summary := fmt.Sprintf("%s: %s", dutyType.Title, assignment.EmployeeName)
+			if options.EmployeeId != 0 {
+				summary = "Дежурство: " + dutyType.Title
+			}
+
+			out.line("BEGIN:VEVENT")
+			out.line(fmt.Sprintf("UID:%s-%s-%d@%s", assignment.Date.Format("20060102"), assignment.Duty, place, domain))
+			out.line("DTSTAMP:" + stamp)
+			start, end, allDay := dutyType.eventTime(assignment.Date)
+			if allDay {
+				out.line("DTSTART;VALUE=DATE:" + start.Format("20060102"))
+				out.line("DTEND;VALUE=DATE:" + end.Format("20060102"))
+				out.line("TRANSP:TRANSPARENT")
+			} else {
+				out.line("DTSTART:" + start.Format("20060102T150405"))
+				out.line("DTEND:" + end.Format("20060102T150405"))
+			}
+			out.line("SUMMARY:" + icsEscaper.Replace(summary))
+			out.line("DESCRIPTION:" + icsEscaper.Replace(fmt.Sprintf("%s - %s\nНеделя с %s", dutyType.Title, assignment.EmployeeName, record.Date.Format("2006-01-02"))))
+			out.line("CATEGORIES:" + icsEscaper.Replace(dutyType.Section))
+			out.line("STATUS:" + status)
+			out.line("END:VEVENT")
+		}
+	}
+
+	out.line("END:VCALENDAR")
+	if out.err != nil {
+		return out.err
+	}
+	return out.w.Flush()
+}
+
+// assignmentPlaces возвращает номер места каждого назначения среди назначений на то же дежурство
+// в тот же день. Места нумеруются по Id сотрудников, а не по порядку назначений в записи, чтобы
+// UID события не менялся при перестановке назначений.
+func assignmentPlaces(assignments []Assignment) []int {
+	order := make([]int, len(assignments))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return assignments[order[i]].EmployeeId < assignments[order[j]].EmployeeId
+	})
+
+	places := make([]int, len(assignments))
+	taken := map[string]int{}
+	for _, i := range order {
+		key := assignments[i].Duty + assignments[i].Date.Format("20060102")
+		places[i] = taken[key]
+		taken[key]++
+	}
+	return places
+}
+
+// eventTime возвращает начало и конец события в календаре. Без EventTime событие на весь день.
+func (d DutyType) eventTime(date time.Time) (start, end time.Time, allDay bool) {
+	day := dayOf(date)
+	at, err := time.Parse("15:04", d.EventTime)
+	if d.EventTime == "" || err != nil {
+		return day, day.AddDate(0, 0, 1), true
+	}
+
+	minutes := d.EventMinutes
+	if minutes <= 0 {
+		minutes = 60
+	}
+	start = day.Add(time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute)
+	return start, start.Add(time.Duration(minutes) * time.Minute), false
+}
+
+// icsWriter пишет строки календаря с CRLF и переносом длинных строк по 75 байт, как требует RFC 5545.
+type icsWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (iw *icsWriter) line(text string) {
+	if iw.err != nil {
+		return
+	}
+	// первая строка - до 75 байт, продолжения - до 74 байт после ведущего пробела
+	limit := 75
+	for len(text) > limit {
+		// не разрываем многобайтовый символ UTF-8
+		cut := limit
+		for cut > 0 && text[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		_, iw.err = iw.w.WriteString(text[:cut] + "\r\n ")
+		text = text[cut:]
+		limit = 74
+	}
+	_, iw.err = iw.w.WriteString(text + "\r\n")
+}
+
+// ICSHandler отдает календари дежурств по HTTP: /calendar.ics - вся команда,
+// /calendar/N.ics - дежурства сотрудника с Id N.
+func ICSHandler(service *Service) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "метод не поддерживается", http.StatusMethodNotAllowed)
+			return
+		}
+
+		options := ICSOptions{Name: "Дежурства"}
+		switch {
+		case r.URL.Path == "/calendar.ics":
+		case strings.HasPrefix(r.URL.Path, "/calendar/") && strings.HasSuffix(r.URL.Path, ".ics"):
+			id, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/calendar/"), ".ics"))
+			if err != nil {
+				http.NotFound(w, r)
+				return
+			}
+			options.EmployeeId = id
+		default:
+			http.NotFound(w, r)
+			return
+		}
+
+		var calendar bytes.Buffer
+		found := true
+		err := service.View(func(state *State) error {
+			if options.EmployeeId != 0 {
+				employee := FindEmployeeById(state.Employees, options.EmployeeId)
+				if employee == nil {
+					found = false
+					return nil
+				}
+				options.Name = "Дежурства: " + employee.Name
+			}
+			return service.Scheduler(service.Clock).WriteICS(&calendar, state.History, options)
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Write(calendar.Bytes())
+	})
+}
@@ -0,0 +1,109 @@
+package pkg
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// icsUIDs возвращает UID событий календаря по имени сотрудника из SUMMARY.
+func icsUIDs(t *testing.T, calendar string) map[string]string {
+	t.Helper()
+	uids := map[string]string{}
+	uid := ""
+	for _, line := range strings.Split(unfoldICS(calendar), "\r\n") {
+		switch {
+		case strings.HasPrefix(line, "UID:"):
+			uid = strings.TrimPrefix(line, "UID:")
+		case strings.HasPrefix(line, "SUMMARY:"):
+			uids[strings.TrimPrefix(line, "SUMMARY:")] = uid
+		}
+	}
+	return uids
+}
+
+func unfoldICS(calendar string) string {
+	return strings.ReplaceAll(calendar, "\r\n ", "")
+}
+
+func writeTestICS(t *testing.T, scheduler *Scheduler, storage *DutyHistoryStorage) string {
+	t.Helper()
+	var calendar bytes.Buffer
+	if err := scheduler.WriteICS(&calendar, storage, ICSOptions{Name: "Дежурства"}); err != nil {
+		t.Fatalf("WriteICS: %v", err)
+	}
+	return calendar.String()
+}
+
+func TestWriteICSStableUIDs(t *testing.T) {
+	scheduler := NewScheduler(FixedClock(testMonday), nil)
+	day := testMonday.AddDate(0, 0, 1)
+	assignments := []Assignment{
+		{Duty: "support", Date: day, EmployeeId: 3, EmployeeName: "Вера"},
+		{Duty: "support", Date: day, EmployeeId: 1, EmployeeName: "Анна"},
+		{Duty: "express", Date: day, EmployeeId: 2, EmployeeName: "Борис"},
+	}
+	record := DutyHistory{Date: testMonday, Status: HistoryConfirmed, Assignments: assignments}
+	before := icsUIDs(t, writeTestICS(t, scheduler, &DutyHistoryStorage{History: []DutyHistory{record}}))
+
+	reversed := make([]Assignment, len(assignments))
+	for i := range assignments {
+		reversed[len(assignments)-1-i] = assignments[i]
+	}
+	record.Assignments = reversed
+	after := icsUIDs(t, writeTestICS(t, scheduler, &DutyHistoryStorage{History: []DutyHistory{record}}))
+
+	if len(before) != len(assignments) {
+		t.Fatalf("событий %d, ожидалось %d: %v", len(before), len(assignments), before)
+	}
+	for summary, uid := range before {
+		if after[summary] != uid {
+			t.Errorf("%s: UID %q после перестановки назначений, был %q", summary, after[summary], uid)
+		}
+	}
+	if uid := before["Support: Анна"]; uid != "20261020-support-0@dev-support-schedule" {
+		t.Errorf("UID первого места %q", uid)
+	}
+
+	scheduler.Teams = &Teams{Current: "mobile"}
+	for summary, uid := range icsUIDs(t, writeTestICS(t, scheduler, &DutyHistoryStorage{History: []DutyHistory{record}})) {
+		if !strings.HasSuffix(uid, "@mobile.dev-support-schedule") {
+			t.Errorf("%s: UID %q без команды", summary, uid)
+		}
+	}
+}
+
+func TestWriteICSFoldsLongLines(t *testing.T) {
+	scheduler := NewScheduler(FixedClock(testMonday), nil)
+	name := strings.Repeat("Александра-Мария ", 6) + "Константинопольская"
+	storage := &DutyHistoryStorage{History: []DutyHistory{{
+		Date:        testMonday,
+		Status:      HistoryConfirmed,
+		Assignments: []Assignment{{Duty: "support", Date: testMonday, EmployeeId: 1, EmployeeName: name}},
+	}}}
+	calendar := writeTestICS(t, scheduler, storage)
+
+	if !strings.HasSuffix(calendar, "\r\n") || strings.Contains(strings.ReplaceAll(calendar, "\r\n", ""), "\n") {
+		t.Error("строки календаря должны заканчиваться CRLF")
+	}
+	folded := false
+	for _, line := range strings.Split(strings.TrimSuffix(calendar, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("строка длиннее 75 байт (%d): %q", len(line), line)
+		}
+		if strings.HasPrefix(line, " ") {
+			folded = true
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("перенос разорвал символ UTF-8: %q", line)
+		}
+	}
+	if !folded {
+		t.Error("длинные строки не перенесены")
+	}
+	if !regexp.MustCompile(`(?m)^SUMMARY:Support: ` + regexp.QuoteMeta(name) + "\r$").MatchString(unfoldICS(calendar)) {
+		t.Errorf("после склейки строк нет SUMMARY с полным именем:\n%s", unfoldICS(calendar))
+	}
+}
@@ -0,0 +1,381 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"time"
+)
+
+// Виды событий журнала (Event.Type).
+const (
+	EventEmployeeAdded   = "employee_added"       // добавлен сотрудник
+	EventEmployeeRemoved = "employee_removed"     // сотрудник удален из списка
+	EventEmployeeStatus  = "employee_status"      // изменен статус, например уволен
+	EventEmployeeAbsence = "employee_absence"     // добавлено или удалено отсутствие
+	EventEmployeeUpdated = "employee_updated"     // изменены имя, привязка к Telegram или ключ общего сотрудника
+	EventEmployeePrefs   = "employee_preferences" // изменены пожелания сотрудника к дежурствам
+	EventEmployeeSkills  = "employee_skills"      // изменены навыки сотрудника, в том числе допуск после смен стажером
+	EventCountersReset   = "counters_reset"       // счетчики дежурств сброшены
+	EventCountersChanged = "counters_changed"     // счетчики или даты последних дежурств изменены без изменения недель, например пересчетом по истории
+	EventWeekGenerated   = "week_generated"       // неделя сформирована или перегенерирована
+	EventWeekChanged     = "week_changed"         // дежурные недели поменялись или заменены
+	EventWeekConfirmed   = "week_confirmed"       // запланированная неделя утверждена
+	EventWeekPublished   = "week_published"       // неделя опубликована
+	EventWeekRemoved     = "week_removed"         // неделя удалена из истории
+	EventPinAdded        = "pin_added"            // сотрудник закреплен за слотом
+	EventPinRemoved      = "pin_removed"          // закрепление снято
+)
+
+// EventTypes - все виды событий, для проверки фильтра.
+var EventTypes = []string{
+	EventEmployeeAdded, EventEmployeeRemoved, EventEmployeeStatus, EventEmployeeAbsence, EventEmployeeUpdated,
+	EventEmployeePrefs, EventEmployeeSkills, EventCountersReset, EventCountersChanged, EventWeekGenerated, EventWeekChanged,
+	EventWeekConfirmed, EventWeekPublished, EventWeekRemoved, EventPinAdded, EventPinRemoved,
+}
+
+// Actor - кто меняет данные и зачем. Попадает в каждое событие журнала.
+type Actor struct {
+	Name   string // os:alice, api:ci, telegram:123456 (@alice), daemon
+	Reason string
+}
+
+// OSActor возвращает пользователя операционной системы, от имени которого запущена программа.
+func OSActor(reason string) Actor {
+	name := os.Getenv("USER")
+	if current, err := user.Current(); err == nil {
+		name = current.Username
+	}
+	if name == "" {
+		name = "unknown"
+	}
+	return Actor{Name: "os:" + name, Reason: reason}
+}
+
+// Event - запись журнала изменений. Журнал только дополняется: события не меняются и не удаляются.
+type Event struct {
+	At        time.Time       `json:"at"`
+	Type      string          `json:"type"`
+	Actor     string          `json:"actor"`
+	Reason    string          `json:"reason,omitempty"`
+	Week      *time.Time      `json:"week,omitempty"`      // неделя для событий недель и закреплений
+	Employees []int           `json:"employees,omitempty"` // Id затронутых сотрудников
+	Before    json.RawMessage `json:"before,omitempty"`    // значение до изменения
+	After     json.RawMessage `json:"after,omitempty"`     // значение после изменения
+}
+
+// EventFilter - отбор событий журнала. Нулевые поля не ограничивают отбор.
+type EventFilter struct {
+	EmployeeId int
+	Type       string
+	From, To   time.Time // даты включительно
+}
+
+// Match проверяет, подходит ли событие под отбор.
+func (f EventFilter) Match(event Event) bool {
+	if f.Type != "" && event.Type != f.Type {
+		return false
+	}
+	if !f.From.IsZero() && dayOf(event.At).Before(dayOf(f.From)) {
+		return false
+	}
+	if !f.To.IsZero() && dayOf(event.At).After(dayOf(f.To)) {
+		return false
+	}
+	if f.EmployeeId != 0 {
+		for _, id := range event.Employees {
+			if id == f.EmployeeId {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// CloneState возвращает независимую копию данных, чтобы потом сравнить ее с измененными.
+func CloneState(state *State) (*State, error) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	clone := &State{}
+	if err := json.Unmarshal(data, clone); err != nil {
+		return nil, err
+	}
+	if clone.Employees == nil {
+		clone.Employees = &[]Employee{}
+	}
+	if clone.History == nil {
+		clone.History = &DutyHistoryStorage{}
+	}
+	return clone, nil
+}
+
+// journal собирает события одного изменения.
+type journal struct {
+	actor  Actor
+	at     time.Time
+	events []Event
+}
+
+func (j *journal) add(eventType string, week *time.Time, employees []int, before, after interface{}) {
+	event := Event{At: j.at, Type: eventType, Actor: j.actor.Name, Reason: j.actor.Reason, Week: week, Employees: employees}
+	if before != nil {
+		event.Before, _ = json.Marshal(before)
+	}
+	if after != nil {
+		event.After, _ = json.Marshal(after)
+	}
+	j.events = append(j.events, event)
+}
+
+// DiffEvents сравнивает данные до и после изменения и возвращает события журнала от имени actor
+// со временем по clock. События строятся по самим данным, поэтому в журнал попадает любое изменение,
+// чем бы оно ни было сделано.
+func DiffEvents(before, after *State, actor Actor, clock Clock) []Event {
+	j := &journal{actor: actor, at: clock.Now()}
+	j.diffEmployees(*before.Employees, *after.Employees)
+	j.diffHistory(before, after)
+	j.diffStats(*before.Employees, *after.Employees)
+	return j.events
+}
+
+// employeeInfo - поля сотрудника, которые правят вручную.
+type employeeInfo struct {
+	Name           string `json:"name"`
+	TelegramUserId int64  `json:"telegram_user_id,omitempty"`
+	Member         string `json:"member,omitempty"`
+}
+
+func (j *journal) diffEmployees(before, after []Employee) {
+	old := map[int]Employee{}
+	for _, employee := range before {
+		old[employee.Id] = employee
+	}
+
+	for _, employee := range after {
+		id := []int{employee.Id}
+		previous, ok := old[employee.Id]
+		if !ok {
+			j.add(EventEmployeeAdded, nil, id, nil, employeeInfo{Name: employee.Name, TelegramUserId: employee.TelegramUserId, Member: employee.Member})
+			continue
+		}
+		delete(old, employee.Id)
+
+		if previous.Status != employee.Status {
+			j.add(EventEmployeeStatus, nil, id, previous.Status, employee.Status)
+		}
+		if !sameJSON(previous.Absences, employee.Absences) {
+			j.add(EventEmployeeAbsence, nil, id, previous.Absences, employee.Absences)
+		}
+		if !sameJSON(previous.Skills, employee.Skills) {
+			j.add(EventEmployeeSkills, nil, id, previous.Skills, employee.Skills)
+		}
+		if !sameJSON(previous.Preferences, employee.Preferences) {
+			j.add(EventEmployeePrefs, nil, id, preferencesValue(previous.Preferences), preferencesValue(employee.Preferences))
+		}
+		previousInfo := employeeInfo{Name: previous.Name, TelegramUserId: previous.TelegramUserId, Member: previous.Member}
+		info := employeeInfo{Name: employee.Name, TelegramUserId: employee.TelegramUserId, Member: employee.Member}
+		if previousInfo != info {
+			j.add(EventEmployeeUpdated, nil, id, previousInfo, info)
+		}
+	}
+
+	removed := make([]int, 0, len(old))
+	for id := range old {
+		removed = append(removed, id)
+	}
+	sort.Ints(removed)
+	for _, id := range removed {
+		j.add(EventEmployeeRemoved, nil, []int{id}, employeeInfo{Name: old[id].Name, TelegramUserId: old[id].TelegramUserId, Member: old[id].Member}, nil)
+	}
+}
+
+// preferencesValue возвращает пожелания для события; отсутствие пожеланий - nil без типа, чтобы поле осталось пустым.
+func preferencesValue(preferences *Preferences) interface{} {
+	if preferences == nil {
+		return nil
+	}
+	return preferences
+}
+
+// countersSnapshot - счетчики сотрудников и дата сброса для события counters_reset.
+type countersSnapshot struct {
+	LastResetDate time.Time              `json:"last_reset_date"`
+	Counters      map[string]map[int]int `json:"counters"` // дежурство -> Id сотрудника -> счетчик
+}
+
+func newCountersSnapshot(state *State) countersSnapshot {
+	snapshot := countersSnapshot{LastResetDate: state.History.LastResetDate, Counters: map[string]map[int]int{}}
+	for _, employee := range *state.Employees {
+		for duty, stats := range employee.Duties {
+			if snapshot.Counters[duty] == nil {
+				snapshot.Counters[duty] = map[int]int{}
+			}
+			snapshot.Counters[duty][employee.Id] = stats.Count
+		}
+	}
+	return snapshot
+}
+
+// weekSnapshot - неделя истории в событиях недель.
+type weekSnapshot struct {
+	Status      string        `json:"status,omitempty"`
+	Assignments []Assignment  `json:"assignments"`
+	Shadows     []ShadowShift `json:"shadows,omitempty"`
+}
+
+func (j *journal) diffHistory(before, after *State) {
+	// первая дата сброса ставится при первом формировании и сбросом не является
+	if !before.History.LastResetDate.IsZero() && !before.History.LastResetDate.Equal(after.History.LastResetDate) {
+		var ids []int
+		for _, employee := range *after.Employees {
+			ids = append(ids, employee.Id)
+		}
+		j.add(EventCountersReset, nil, ids, newCountersSnapshot(before), newCountersSnapshot(after))
+	}
+
+	// даты сравниваются по моменту времени: у загруженных и только что созданных может отличаться пояс
+	old := map[int64]DutyHistory{}
+	for _, record := range before.History.History {
+		old[record.Date.Unix()] = record
+	}
+	kept := map[int64]bool{}
+	for _, record := range after.History.History {
+		week := record.Date
+		kept[week.Unix()] = true
+		previous, ok := old[week.Unix()]
+		snapshot := weekSnapshot{Status: record.Status, Assignments: record.Assignments, Shadows: record.Shadows}
+
+		switch {
+		case !ok:
+			j.add(EventWeekGenerated, &week, assignmentEmployees(record.Assignments), nil, snapshot)
+		case !sameJSON(previous.Assignments, record.Assignments) || !sameJSON(previous.Shadows, record.Shadows):
+			eventType := EventWeekGenerated
+			var after interface{} = snapshot
+			ids := assignmentEmployees(previous.Assignments, record.Assignments)
+			if len(record.Changes) > len(previous.Changes) {
+				// обмен или замена записывается в журнал недели, его и сохраняем
+				changes := record.Changes[len(previous.Changes):]
+				eventType, after, ids = EventWeekChanged, changes, movedEmployees(changes)
+			}
+			j.add(eventType, &week, ids, weekSnapshot{Status: previous.Status, Assignments: previous.Assignments, Shadows: previous.Shadows}, after)
+		case previous.Status != record.Status:
+			j.add(EventWeekConfirmed, &week, nil, previous.Status, record.Status)
+		}
+
+		if record.Publication != nil && !sameJSON(previous.Publication, record.Publication) {
+			j.add(EventWeekPublished, &week, nil, previous.Publication, record.Publication)
+		}
+	}
+	for _, record := range before.History.History {
+		if !kept[record.Date.Unix()] {
+			week := record.Date
+			j.add(EventWeekRemoved, &week, assignmentEmployees(record.Assignments), weekSnapshot{Status: record.Status, Assignments: record.Assignments, Shadows: record.Shadows}, nil)
+		}
+	}
+
+	j.diffPins(before.History.Pins, after.History.Pins)
+}
+
+func (j *journal) diffPins(before, after []Pin) {
+	// закрепления сравниваются как мультимножества
+	key := func(pin Pin) string {
+		return fmt.Sprintf("%d/%s/%d/%d/%t", pin.Week.Unix(), pin.Duty, pin.Date.Unix(), pin.EmployeeId, pin.Force)
+	}
+	count := map[string]int{}
+	for _, pin := range before {
+		count[key(pin)]++
+	}
+	for _, pin := range after {
+		if count[key(pin)] > 0 {
+			count[key(pin)]--
+			continue
+		}
+		week := pin.Week
+		j.add(EventPinAdded, &week, []int{pin.EmployeeId}, nil, pin)
+	}
+	for _, pin := range before {
+		if count[key(pin)] > 0 {
+			count[key(pin)]--
+			week := pin.Week
+			j.add(EventPinRemoved, &week, []int{pin.EmployeeId}, pin, nil)
+		}
+	}
+}
+
+// diffStats записывает изменение счетчиков и дат последних дежурств, если его не объясняют
+// уже записанные события сброса счетчиков или изменения недель.
+func (j *journal) diffStats(before, after []Employee) {
+	for _, event := range j.events {
+		switch event.Type {
+		case EventCountersReset, EventWeekGenerated, EventWeekChanged, EventWeekRemoved:
+			return
+		}
+	}
+
+	old := map[int]Employee{}
+	for _, employee := range before {
+		old[employee.Id] = employee
+	}
+	var ids []int
+	previous, current := map[int]map[string]DutyStats{}, map[int]map[string]DutyStats{}
+	for _, employee := range after {
+		prev, ok := old[employee.Id]
+		if !ok || sameJSON(prev.Duties, employee.Duties) {
+			continue
+		}
+		ids = append(ids, employee.Id)
+		previous[employee.Id], current[employee.Id] = prev.Duties, employee.Duties
+	}
+	if len(ids) > 0 {
+		j.add(EventCountersChanged, nil, ids, previous, current)
+	}
+}
+
+// sameJSON сравнивает значения по их JSON: так одинаковые даты в разных поясах считаются равными.
+func sameJSON(a, b interface{}) bool {
+	first, _ := json.Marshal(a)
+	second, _ := json.Marshal(b)
+	return string(first) == string(second)
+}
+
+// assignmentEmployees возвращает Id сотрудников из назначений без повторов.
+func assignmentEmployees(lists ...[]Assignment) []int {
+	seen := map[int]bool{}
+	var ids []int
+	for _, assignments := range lists {
+		for _, assignment := range assignments {
+			if !seen[assignment.EmployeeId] {
+				seen[assignment.EmployeeId] = true
+				ids = append(ids, assignment.EmployeeId)
+			}
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// movedEmployees возвращает Id сотрудников, отдавших или принявших дежурства, без повторов.
+func movedEmployees(changes []WeekChange) []int {
+	var moved []Assignment
+	for _, change := range changes {
+		for _, move := range change.Moves {
+			moved = append(moved, Assignment{EmployeeId: move.FromId}, Assignment{EmployeeId: move.ToId})
+		}
+	}
+	return assignmentEmployees(moved)
+}
+
+// ValidEventType проверяет вид события для фильтра.
+func ValidEventType(eventType string) error {
+	for _, known := range EventTypes {
+		if known == eventType {
+			return nil
+		}
+	}
+	return fmt.Errorf("неизвестный вид события %q", eventType)
+}
@@ -0,0 +1,195 @@
+package pkg
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiffEvents(t *testing.T) {
+	scheduler := NewScheduler(FixedClock(testMonday.AddDate(0, 0, 4)), nil)
+	tests := []struct {
+		name      string
+		prepare   func(state *State) // до изменения
+		change    func(state *State)
+		types     []string
+		employees []int // Id затронутых сотрудников первого события
+	}{
+		{name: "без изменений", change: func(state *State) {}},
+		{
+			name:      "новый сотрудник",
+			change:    func(state *State) { scheduler.AddNewEmployee(state.Employees, "Жанна") },
+			types:     []string{EventEmployeeAdded},
+			employees: []int{7},
+		},
+		{
+			name:      "увольнение",
+			change:    func(state *State) { (*state.Employees)[1].Status = StatusFired },
+			types:     []string{EventEmployeeStatus},
+			employees: []int{2},
+		},
+		{
+			name: "отсутствие",
+			change: func(state *State) {
+				AddEmployeeAbsence(state.Employees, 3, Absence{Kind: StatusSick, Start: testMonday.AddDate(0, 0, 7)})
+			},
+			types:     []string{EventEmployeeAbsence},
+			employees: []int{3},
+		},
+		{
+			name: "имя и пожелания",
+			change: func(state *State) {
+				(*state.Employees)[0].Name = "Анна П."
+				(*state.Employees)[0].Preferences = &Preferences{MaxPerMonth: 2}
+			},
+			types: []string{EventEmployeePrefs, EventEmployeeUpdated},
+		},
+		{
+			name:   "навыки",
+			change: func(state *State) { (*state.Employees)[3].Skills = nil },
+			types:  []string{EventEmployeeSkills},
+		},
+		{
+			name: "новая неделя без отдельного события счетчиков",
+			change: func(state *State) {
+				if _, _, err := scheduler.GenerateWeek(state.Employees, state.History); err != nil {
+					t.Fatal(err)
+				}
+			},
+			types: []string{EventWeekGenerated},
+		},
+		{
+			name: "замена дежурного",
+			change: func(state *State) {
+				record := &state.History.History[0]
+				from := record.Assignments[0]
+				record.Assignments[0].EmployeeId, record.Assignments[0].EmployeeName = 6, "Ефим"
+				record.Changes = append(record.Changes, WeekChange{At: testMonday, Kind: ChangeReplace, RequestedBy: "test",
+					Moves: []AssignmentMove{{Duty: from.Duty, Date: from.Date, FromId: from.EmployeeId, ToId: 6}}})
+			},
+			types: []string{EventWeekChanged},
+		},
+		{
+			name:    "утверждение",
+			prepare: func(state *State) { state.History.History[0].Status = HistoryPlanned },
+			change:  func(state *State) { state.History.History[0].Status = HistoryConfirmed },
+			types:   []string{EventWeekConfirmed},
+		},
+		{
+			name: "публикация",
+			change: func(state *State) {
+				state.History.History[0].Publication = &Publication{PublishedAt: testMonday, Target: "telegram:100"}
+			},
+			types: []string{EventWeekPublished},
+		},
+		{
+			name: "удаление недели",
+			change: func(state *State) {
+				if err := scheduler.DiscardWeek(state.Employees, state.History, testMonday); err != nil {
+					t.Fatal(err)
+				}
+			},
+			types: []string{EventWeekRemoved},
+		},
+		{
+			name: "закрепления",
+			change: func(state *State) {
+				state.History.Pins = []Pin{{Week: testMonday.AddDate(0, 0, 7), Duty: "express", Date: testMonday.AddDate(0, 0, 10), EmployeeId: 3}}
+			},
+			types:     []string{EventPinAdded, EventPinRemoved},
+			employees: []int{3},
+		},
+		{
+			name: "сброс счетчиков",
+			change: func(state *State) {
+				NewScheduler(scheduler.Clock, resetDutyTypes("support")).ForceResetDutyCounters(state.Employees, state.History)
+			},
+			types: []string{EventCountersReset},
+		},
+		{
+			name: "пересчет счетчиков",
+			change: func(state *State) {
+				stats := (*state.Employees)[4].Duties["support"]
+				stats.Count++
+				(*state.Employees)[4].Duties["support"] = stats
+			},
+			types:     []string{EventCountersChanged},
+			employees: []int{5},
+		},
+	}
+
+	actor := Actor{Name: "test", Reason: "проверка"}
+	at := testMonday.Add(10 * time.Hour)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := testState(t)
+			before.History.LastResetDate = testMonday.AddDate(0, 0, -30)
+			if tt.prepare != nil {
+				tt.prepare(before)
+			}
+			after, err := CloneState(before)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.change(after)
+
+			events := DiffEvents(before, after, actor, FixedClock(at))
+			var types []string
+			for _, event := range events {
+				types = append(types, event.Type)
+				if !event.At.Equal(at) || event.Actor != actor.Name || event.Reason != actor.Reason {
+					t.Errorf("%s: время %s, автор %q, причина %q", event.Type, event.At, event.Actor, event.Reason)
+				}
+			}
+			if !reflect.DeepEqual(types, tt.types) {
+				t.Fatalf("события %v, ожидались %v", types, tt.types)
+			}
+			if tt.employees != nil && !reflect.DeepEqual(events[0].Employees, tt.employees) {
+				t.Errorf("сотрудники события %v, ожидались %v", events[0].Employees, tt.employees)
+			}
+		})
+	}
+}
+
+func TestLoadEventsFilter(t *testing.T) {
+	repo := NewJSONRepository(t.TempDir())
+	events := []Event{
+		{At: testMonday, Type: EventEmployeeAdded, Actor: "test", Employees: []int{1}},
+		{At: testMonday.AddDate(0, 0, 1).Add(23 * time.Hour), Type: EventEmployeeStatus, Actor: "test", Employees: []int{2}},
+		{At: testMonday.AddDate(0, 0, 2), Type: EventWeekGenerated, Actor: "test", Employees: []int{1, 2}},
+	}
+	state := &State{Employees: &[]Employee{}, History: &DutyHistoryStorage{}}
+	if err := repo.Save(state, events[:1]); err != nil {
+		t.Fatal(err)
+	}
+	// журнал только дополняется
+	if err := repo.Save(state, events[1:]); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		filter   EventFilter
+		expected []int // индексы событий
+	}{
+		{name: "все", expected: []int{0, 1, 2}},
+		{name: "по виду", filter: EventFilter{Type: EventWeekGenerated}, expected: []int{2}},
+		{name: "по сотруднику", filter: EventFilter{EmployeeId: 2}, expected: []int{1, 2}},
+		{name: "даты включительно", filter: EventFilter{From: testMonday.AddDate(0, 0, 1), To: testMonday.AddDate(0, 0, 1)}, expected: []int{1}},
+		{name: "с даты", filter: EventFilter{From: testMonday.AddDate(0, 0, 2)}, expected: []int{2}},
+		{name: "ничего", filter: EventFilter{Type: EventPinAdded}},
+	}
+	for _, tt := range tests {
+		got, err := repo.LoadEvents(tt.filter)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var want []Event
+		for _, i := range tt.expected {
+			want = append(want, events[i])
+		}
+		if !sameJSON(got, want) {
+			t.Errorf("%s: %d событий, ожидалось %d", tt.name, len(got), len(want))
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package pkg
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Поддержка файлов в формате, где у сотрудника были отдельные поля под Support, Express и Instances,
+// а в истории хранились копии сотрудников вместо назначений.
+
+// legacyEmployeeFields - поля сотрудника из старого формата.
+type legacyEmployeeFields struct {
+	SupportLastDuty    time.Time `json:"support_last_duty"`
+	ReleaseLastDuty    time.Time `json:"release_last_duty"`
+	SupportDutyCount   int       `json:"support_duty_count"`
+	ExpressDutyCount   int       `json:"express_duty_count"`
+	InstancesDutyCount int       `json:"instances_duty_count"`
+}
+
+func (l legacyEmployeeFields) empty() bool {
+	return l == legacyEmployeeFields{}
+}
+
+// duties переносит старые поля в счетчики по видам дежурств по умолчанию.
+func (l legacyEmployeeFields) duties() map[string]DutyStats {
+	return map[string]DutyStats{
+		"support":   {Count: l.SupportDutyCount, LastDuty: l.SupportLastDuty},
+		"express":   {Count: l.ExpressDutyCount, LastDuty: l.ReleaseLastDuty},
+		"instances": {Count: l.InstancesDutyCount, LastDuty: l.ReleaseLastDuty},
+	}
+}
+
+// assignment определяет назначение по копии сотрудника из старой истории:
+// GetSchedule выставлял в ней единичный счетчик того дежурства, на которое назначен сотрудник.
+func (l legacyEmployeeFields) assignment() (string, time.Time) {
+	switch {
+	case l.ExpressDutyCount > 0:
+		return "express", l.ReleaseLastDuty
+	case l.InstancesDutyCount > 0:
+		return "instances", l.ReleaseLastDuty
+	default:
+		return "support", l.SupportLastDuty
+	}
+}
+
+func (e *Employee) UnmarshalJSON(data []byte) error {
+	type plain Employee
+	var decoded struct {
+		plain
+		legacyEmployeeFields
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	*e = Employee(decoded.plain)
+	if e.Duties == nil && !decoded.legacyEmployeeFields.empty() {
+		e.Duties = decoded.legacyEmployeeFields.duties()
+	}
+	return nil
+}
+
+func (h *DutyHistory) UnmarshalJSON(data []byte) error {
+	type plain DutyHistory
+	var decoded struct {
+		plain
+		Employees []struct {
+			Id   int    `json:"id"`
+			Name string `json:"name"`
+			legacyEmployeeFields
+		}
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	*h = DutyHistory(decoded.plain)
+	if h.Assignments == nil {
+		for _, employee := range decoded.Employees {
+			duty, date := employee.assignment()
+			h.Assignments = append(h.Assignments, Assignment{Duty: duty, Date: date, EmployeeId: employee.Id, EmployeeName: employee.Name})
+		}
+	}
+	return nil
+}
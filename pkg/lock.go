@@ -0,0 +1,62 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// lockFileName - файл в каталоге данных, на который берется блокировка.
+const lockFileName = ".lock"
+
+// ErrLocked возвращается, если каталог данных уже заблокирован другим экземпляром программы.
+var ErrLocked = errors.New("каталог данных заблокирован другим экземпляром программы")
+
+// DataLock - рекомендательная блокировка каталога данных. Ее нужно держать на протяжении
+// всего цикла загрузка→изменение→сохранение, чтобы параллельные запуски не затирали друг друга.
+type DataLock struct {
+	file *os.File
+	path string
+}
+
+// LockDataDir берет блокировку каталога dir, создавая его при необходимости.
+// Если блокировку держит другой процесс, возвращается ошибка, оборачивающая ErrLocked.
+func LockDataDir(dir string) (*DataLock, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("не удалось создать каталог данных: %w", err)
+	}
+
+	path := filepath.Join(dir, lockFileName)
+	file, err := lockFile(path)
+	if errors.Is(err, ErrLocked) {
+		holder, _ := os.ReadFile(path)
+		if info := strings.TrimSpace(string(holder)); info != "" {
+			return nil, fmt.Errorf("%w (%s): %s", ErrLocked, info, dir)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrLocked, dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось заблокировать каталог данных: %w", err)
+	}
+
+	// записываем, кто держит блокировку, чтобы показать это другим экземплярам
+	host, _ := os.Hostname()
+	file.Truncate(0)
+	file.WriteAt([]byte(fmt.Sprintf("pid %d на %s с %s\n", os.Getpid(), host, time.Now().Format("2006-01-02 15:04:05"))), 0)
+
+	return &DataLock{file: file, path: path}, nil
+}
+
+// Unlock снимает блокировку.
+func (l *DataLock) Unlock() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	l.file.Truncate(0)
+	err := unlockFile(l.file, l.path)
+	l.file = nil
+	return err
+}
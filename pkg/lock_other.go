@@ -0,0 +1,23 @@
+//go:build !unix
+
+package pkg
+
+import (
+	"errors"
+	"os"
+)
+
+// lockFile создает файл блокировки эксклюзивно. Если процесс упадет, не сняв блокировку,
+// файл нужно удалить вручную.
+func lockFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return nil, ErrLocked
+	}
+	return file, err
+}
+
+func unlockFile(file *os.File, path string) error {
+	file.Close()
+	return os.Remove(path)
+}
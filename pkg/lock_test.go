@@ -0,0 +1,29 @@
+package pkg
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLockDataDir(t *testing.T) {
+	dir := t.TempDir()
+	lock, err := LockDataDir(dir)
+	if err != nil {
+		t.Fatalf("LockDataDir: %v", err)
+	}
+
+	if second, err := LockDataDir(dir); !errors.Is(err, ErrLocked) {
+		second.Unlock()
+		t.Fatalf("повторная блокировка: ошибка %v, ожидалась ErrLocked", err)
+	}
+
+	if err := lock.Unlock(); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	// после снятия блокировку можно взять снова
+	again, err := LockDataDir(dir)
+	if err != nil {
+		t.Fatalf("блокировка после Unlock: %v", err)
+	}
+	again.Unlock()
+}
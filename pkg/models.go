@@ -9,23 +9,80 @@ const (
 	StatusFired     = "fired"     // уволен
 )
 
+// Absence описывает период отсутствия сотрудника (включительно по обе даты).
+// Нулевая дата окончания означает бессрочное отсутствие, которое закрывается при возврате статуса available.
+type Absence struct {
+	Kind  string    `json:"kind"` // StatusSick или StatusVacation
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// DutyStats - счетчик и дата последнего дежурства сотрудника для одного вида дежурства.
+type DutyStats struct {
+	Count    int       `json:"count"`
+	LastDuty time.Time `json:"last_duty"`
+}
+
 type Employee struct {
-	Id                 int       `json:"id"`
-	Name               string    `json:"name"`
-	SupportLastDuty    time.Time `json:"support_last_duty"`
-	ReleaseLastDuty    time.Time `json:"release_last_duty"`
-	Status             string    `json:"status"` // может принимать значения StatusAvailable, StatusSick, StatusVacation, StatusFired
-	SupportDutyCount   int       `json:"support_duty_count"`
-	ExpressDutyCount   int       `json:"express_duty_count"`
-	InstancesDutyCount int       `json:"instances_duty_count"`
+	Id       int                  `json:"id"`
+	Name     string               `json:"name"`
+	Status   string               `json:"status"` // StatusAvailable или StatusFired; StatusSick и StatusVacation остались от старого формата и считаются бессрочным отсутствием
+	Absences []Absence            `json:"absences,omitempty"`
+	Duties   map[string]DutyStats `json:"duties"` // ключ - DutyType.Name
+	// TelegramUserId - id пользователя Telegram, чтобы сотрудник мог сам менять свой статус через бота.
+	TelegramUserId int64 `json:"telegram_user_id,omitempty"`
+	// Member - ключ человека, общий для его записей в составах разных команд (например, логин).
+	// По нему проверяется, что сотрудник не дежурит в двух командах в один день.
+	Member string `json:"member,omitempty"`
+	// Preferences - пожелания сотрудника: дни, когда он не дежурит, предпочтения и предел дежурств в месяц.
+	Preferences *Preferences `json:"preferences,omitempty"`
+	// Skills - навыки сотрудника, которые требуют дежурства (DutyType.RequiredSkills).
+	Skills []string `json:"skills,omitempty"`
+	// ShadowShifts - сколько смен стажером сотрудник отстоял по дежурствам, к которым еще не допущен;
+	// ключ - DutyType.Name. После допуска запись удаляется.
+	ShadowShifts map[string]int `json:"shadow_shifts,omitempty"`
 }
 
+// Assignment - назначение сотрудника на дежурство в конкретный день.
+type Assignment struct {
+	Duty         string    `json:"duty"` // DutyType.Name
+	Date         time.Time `json:"date"`
+	EmployeeId   int       `json:"employee_id"`
+	EmployeeName string    `json:"employee_name"`
+}
+
+// Состояние недели в истории.
+const (
+	HistoryPlanned   = "planned"   // неделя спланирована заранее и может быть перегенерирована
+	HistoryConfirmed = "confirmed" // расписание утверждено
+)
+
 type DutyHistory struct {
-	Date      time.Time `json:"date"`
-	Employees []Employee
+	Date        time.Time    `json:"date"`             // понедельник недели
+	Status      string       `json:"status,omitempty"` // HistoryPlanned или HistoryConfirmed, пустой статус у старых записей означает confirmed
+	Assignments []Assignment `json:"assignments"`
+	Publication *Publication `json:"publication,omitempty"` // nil, пока расписание не опубликовано
+	// Decisions - разбор подбора дежурных на каждый слот; пусто у недель, сформированных до его появления.
+	Decisions []SlotDecision `json:"decisions,omitempty"`
+	// Changes - журнал обменов и замен дежурных после формирования расписания.
+	Changes []WeekChange `json:"changes,omitempty"`
+	// Shadows - стажеры, поставленные рядом с дежурными недели.
+	Shadows []ShadowShift `json:"shadows,omitempty"`
+}
+
+// Publication - отметка о том, что расписание недели опубликовано.
+type Publication struct {
+	PublishedAt time.Time `json:"published_at"`
+	Target      string    `json:"target"`
+}
+
+// Planned сообщает, что неделя только спланирована и еще не утверждена.
+func (h DutyHistory) Planned() bool {
+	return h.Status == HistoryPlanned
 }
 
 type DutyHistoryStorage struct {
 	History       []DutyHistory
 	LastResetDate time.Time
+	Pins          []Pin `json:",omitempty"` // закрепления сотрудников за слотами будущих недель
 }
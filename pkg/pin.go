@@ -0,0 +1,180 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Pin закрепляет сотрудника за слотом дежурства на неделе до формирования расписания: например,
+// релиз должен вести автор большой миграции или кто-то сам вызвался на саппорт в среду.
+// Закрепления хранятся вместе с историей и учитываются при каждом формировании недели.
+type Pin struct {
+	Week       time.Time `json:"week"` // понедельник недели
+	Duty       string    `json:"duty"` // DutyType.Name
+	Date       time.Time `json:"date"` // день дежурства с учетом переноса из-за праздника
+	EmployeeId int       `json:"employee_id"`
+	// Force - закрепить, несмотря на отсутствие сотрудника в период дежурства.
+	Force bool `json:"force,omitempty"`
+}
+
+// PinsFor возвращает закрепления на неделю week.
+func (storage *DutyHistoryStorage) PinsFor(week time.Time) []Pin {
+	var pins []Pin
+	for _, pin := range storage.Pins {
+		if pin.Week.Equal(week) {
+			pins = append(pins, pin)
+		}
+	}
+	return pins
+}
+
+// findEmployeeIndex возвращает индекс сотрудника с идентификатором id.
+func findEmployeeIndex(employees []Employee, id int) (int, bool) {
+	for i := range employees {
+		if employees[i].Id == id {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// pinSlot находит слот недели weekStart, за которым закрепляется сотрудник. У еженедельного
+// дежурства слот на неделе один, поэтому день не учитывается: так закрепление переживает перенос
+// дежурства из-за праздника. Для остальных дежурств день обязателен.
+func (s *Scheduler) pinSlot(duty string, date, weekStart time.Time) (slot, error) {
+	dutyType, ok := s.dutyType(duty)
+	if !ok {
+		return slot{}, fmt.Errorf("неизвестное дежурство %q", duty)
+	}
+
+	dates := s.dutyDates(dutyType, weekStart)
+	if dutyType.Cadence == CadenceWeekly {
+		if len(dates) == 0 {
+			return slot{}, fmt.Errorf("на неделе с %s нет дежурства %s", weekStart.Format("2006-01-02"), dutyType.Title)
+		}
+		date = dates[0]
+	} else if date.IsZero() {
+		return slot{}, fmt.Errorf("для дежурства %s укажите день", dutyType.Title)
+	}
+	for _, d := range dates {
+		if d.Equal(date) {
+			return s.newSlot(dutyType, d, weekStart), nil
+		}
+	}
+	return slot{}, fmt.Errorf("дежурства %s %s нет в расписании недели с %s", dutyType.Title, date.Format("2006-01-02"), weekStart.Format("2006-01-02"))
+}
+
+// pinnedAssignments проверяет закрепления недели weekStart и превращает их в назначения.
+// Закрепление уволенного сотрудника, лишнее для числа мест в слоте, а без Force - пересекающееся
+// с отсутствием сотрудника или с его закреплением на несовместимое дежурство считается ошибкой.
+func (s *Scheduler) pinnedAssignments(employees []Employee, pins []Pin, weekStart time.Time) ([]Assignment, error) {
+	var assignments []Assignment
+	for _, pin := range pins {
+		sl, err := s.pinSlot(pin.Duty, pin.Date, weekStart)
+		if err != nil {
+			return nil, err
+		}
+		i, ok := findEmployeeIndex(employees, pin.EmployeeId)
+		if !ok {
+			return nil, fmt.Errorf("закрепление на %s %s: сотрудник с Id: %d не найден", sl.dutyType.Title, sl.date.Format("2006-01-02"), pin.EmployeeId)
+		}
+		employee := employees[i]
+		if employee.Status == StatusFired {
+			return nil, fmt.Errorf("закрепление на %s %s: сотрудник %s уволен", sl.dutyType.Title, sl.date.Format("2006-01-02"), employee.Name)
+		}
+		if absence, ok := employee.AbsenceDuring(sl.from, sl.to); ok && !pin.Force {
+			return nil, fmt.Errorf("закрепление на %s %s: сотрудник %s отсутствует (%s); закрепите принудительно, если это не мешает дежурству",
+				sl.dutyType.Title, sl.date.Format("2006-01-02"), employee.Name, absence.Kind)
+		}
+		if missing := employee.missingSkills(sl.dutyType); len(missing) > 0 && !pin.Force {
+			return nil, fmt.Errorf("закрепление на %s %s: сотрудник %s не допущен к дежурству (нет навыков: %s); закрепите принудительно, если сотрудник справится",
+				sl.dutyType.Title, sl.date.Format("2006-01-02"), employee.Name, strings.Join(missing, ", "))
+		}
+		if duty, ok := s.teamDuty(employee, sl.date); ok && !pin.Force {
+			return nil, fmt.Errorf("закрепление на %s %s: сотрудник %s в этот день дежурит в команде %s (%s); закрепите принудительно, если это не помешает",
+				sl.dutyType.Title, sl.date.Format("2006-01-02"), employee.Name, TeamTitle(duty.Team), duty.Duty)
+		}
+		if employee.blackout(sl.date) && !pin.Force {
+			return nil, fmt.Errorf("закрепление на %s %s: сотрудник %s не дежурит по дням %s; закрепите принудительно, если сотрудник согласен",
+				sl.dutyType.Title, sl.date.Format("2006-01-02"), employee.Name, weekdaysRu[sl.date.Weekday()])
+		}
+
+		for _, assignment := range assignments {
+			if assignment.EmployeeId == employee.Id && assignment.Duty != pin.Duty && s.incompatible(assignment.Duty, pin.Duty) && !pin.Force {
+				return nil, fmt.Errorf("закрепление на %s %s: сотрудник %s уже закреплен на %s %s, эти дежурства несовместимы; закрепите принудительно, если это не помешает",
+					sl.dutyType.Title, sl.date.Format("2006-01-02"), employee.Name, s.DutyTitle(assignment.Duty), assignment.Date.Format("2006-01-02"))
+			}
+		}
+
+		taken := 0
+		for _, assignment := range assignments {
+			if assignment.Duty == pin.Duty && assignment.Date.Equal(sl.date) {
+				if assignment.EmployeeId == employee.Id {
+					return nil, fmt.Errorf("сотрудник %s закреплен на %s %s дважды", employee.Name, sl.dutyType.Title, sl.date.Format("2006-01-02"))
+				}
+				taken++
+			}
+		}
+		if taken >= sl.dutyType.Slots {
+			return nil, fmt.Errorf("на %s %s закреплено больше сотрудников, чем мест (%d)", sl.dutyType.Title, sl.date.Format("2006-01-02"), sl.dutyType.Slots)
+		}
+
+		assignments = append(assignments, Assignment{
+			Duty:         pin.Duty,
+			Date:         sl.date,
+			EmployeeId:   employee.Id,
+			EmployeeName: employee.Name,
+		})
+	}
+	return assignments, nil
+}
+
+// pinnedDecision - разбор слота, занятого закреплением.
+func pinnedDecision(assignment Assignment) SlotDecision {
+	return SlotDecision{
+		Duty:         assignment.Duty,
+		Date:         assignment.Date,
+		EmployeeId:   assignment.EmployeeId,
+		EmployeeName: assignment.EmployeeName,
+		Rule:         RulePinned,
+		Candidates:   []Candidate{{EmployeeId: assignment.EmployeeId, EmployeeName: assignment.EmployeeName, Chosen: true}},
+	}
+}
+
+// AddPin закрепляет сотрудника за слотом недели, следующей за текущей датой планировщика.
+// Для еженедельного дежурства pin.Date не нужна. Закрепление проверяется вместе
+// с уже существующими закреплениями недели и возвращается с заполненными Week и Date.
+func (s *Scheduler) AddPin(employees *[]Employee, storage *DutyHistoryStorage, pin Pin) (Pin, error) {
+	week := s.nextMonday()
+	sl, err := s.pinSlot(pin.Duty, pin.Date, week)
+	if err != nil {
+		return Pin{}, err
+	}
+	pin.Week, pin.Date = week, sl.date
+
+	if err := s.loadTeamDuties(); err != nil {
+		return Pin{}, err
+	}
+	if _, err := s.pinnedAssignments(*employees, append(storage.PinsFor(week), pin), week); err != nil {
+		return Pin{}, err
+	}
+	storage.Pins = append(storage.Pins, pin)
+	return pin, nil
+}
+
+// RemovePin снимает закрепление с номером index (по порядку PinsFor) на неделе week.
+func RemovePin(storage *DutyHistoryStorage, week time.Time, index int) error {
+	n := 0
+	for i, pin := range storage.Pins {
+		if !pin.Week.Equal(week) {
+			continue
+		}
+		if n == index {
+			storage.Pins = append(storage.Pins[:i], storage.Pins[i+1:]...)
+			return nil
+		}
+		n++
+	}
+	return fmt.Errorf("закрепление №%d на неделе с %s не найдено", index, week.Format("2006-01-02"))
+}
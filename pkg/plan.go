@@ -0,0 +1,227 @@
+package pkg
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// offsetClock сдвигает время другого Clock на заданное число дней.
+type offsetClock struct {
+	base Clock
+	days int
+}
+
+func (c offsetClock) Now() time.Time {
+	return c.base.Now().AddDate(0, 0, c.days)
+}
+
+// WeekPlan - расписание одной недели горизонта планирования.
+type WeekPlan struct {
+	Week     time.Time
+	Schedule WeekSchedule
+	Skipped  bool // неделя уже утверждена и не перегенерировалась
+}
+
+// NextWeek возвращает понедельник недели, на которую GetSchedule сформирует расписание.
+func (s *Scheduler) NextWeek() time.Time {
+	return s.nextMonday()
+}
+
+// forWeek возвращает планировщик, для которого "следующей" будет неделя со сдвигом weeks от текущей.
+func (s *Scheduler) forWeek(weeks int) *Scheduler {
+	shifted := *s
+	shifted.Clock = offsetClock{base: s.Clock, days: 7 * weeks}
+	return &shifted
+}
+
+// FindWeek возвращает запись истории за неделю, начинающуюся с week.
+func (storage *DutyHistoryStorage) FindWeek(week time.Time) (*DutyHistory, bool) {
+	for i := range storage.History {
+		if storage.History[i].Date.Equal(week) {
+			return &storage.History[i], true
+		}
+	}
+	return nil, false
+}
+
+// PlanWeeks формирует расписание на n недель подряд, начиная со следующей. Назначения каждой недели
+// сразу учитываются в счетчиках и перерывах при подборе следующей, а сами недели записываются
+// в историю как запланированные, закрепления учитываются. Ранее запланированные недели горизонта перегенерируются,
+// а запланированные за его пределами удаляются; утвержденные и уже опубликованные остаются как есть.
+func (s *Scheduler) PlanWeeks(employees *[]Employee, storage *DutyHistoryStorage, n int) ([]WeekPlan, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("число недель должно быть положительным")
+	}
+
+	start := s.nextMonday()
+
+	// сначала откатываем все старые планы начиная со следующей недели, в том числе за пределами
+	// нового горизонта, чтобы перерывы считались без них, а сокращенный горизонт не оставлял хвост
+	var planned []time.Time
+	for _, record := range storage.History {
+		if !record.Date.Before(start) && record.Planned() && record.Publication == nil {
+			planned = append(planned, record.Date)
+		}
+	}
+	sort.Slice(planned, func(i, j int) bool { return planned[i].After(planned[j]) })
+	for _, week := range planned {
+		if err := s.DiscardWeek(employees, storage, week); err != nil {
+			return nil, err
+		}
+	}
+
+	plans := make([]WeekPlan, 0, n)
+	for k := 0; k < n; k++ {
+		weekScheduler := s.forWeek(k)
+		week := weekScheduler.nextMonday()
+
+		if record, ok := storage.FindWeek(week); ok {
+			plans = append(plans, WeekPlan{Week: week, Schedule: weekScheduler.HistorySchedule(*record), Skipped: true})
+			continue
+		}
+
+		schedule, err := weekScheduler.GetSchedule(employees, storage)
+		if err != nil {
+			return nil, fmt.Errorf("неделя с %s: %w", week.Format("2006-01-02"), err)
+		}
+		weekScheduler.AddScheduleToHistory(schedule, storage, HistoryPlanned)
+		plans = append(plans, WeekPlan{Week: week, Schedule: schedule})
+	}
+
+	return plans, nil
+}
+
+// GenerateWeek формирует и утверждает расписание на следующую неделю: откатывает прошлый вариант
+// этой недели, если он был, подбирает дежурных, при необходимости сбрасывает счетчики и записывает
+// неделю в историю. Закрепления недели из storage.Pins учитываются. Отметка о публикации прошлого
+// варианта сохраняется, только если дежурные и стажеры не изменились: тогда неделю не объявят дважды,
+// а измененное расписание опубликуется заново.
+// Второе значение сообщает, что счетчики были сброшены.
+func (s *Scheduler) GenerateWeek(employees *[]Employee, storage *DutyHistoryStorage) (WeekSchedule, bool, error) {
+	week := s.nextMonday()
+
+	var previous *DutyHistory
+	if record, ok := storage.FindWeek(week); ok {
+		copied := *record
+		previous = &copied
+		if err := s.DiscardWeek(employees, storage, week); err != nil {
+			return WeekSchedule{}, false, err
+		}
+	}
+
+	// Сброс счетчиков дежурств с ResetCounters (если прошло более 90 дней с момента последнего сброса).
+	// Сбрасываем до подбора, чтобы дежурства формируемой недели вошли в новый период, как их посчитает RecomputeStats.
+	reseted := s.ResetDutyCounters(employees, storage)
+
+	schedule, err := s.GetSchedule(employees, storage)
+	if err != nil {
+		return WeekSchedule{}, false, err
+	}
+
+	s.AddScheduleToHistory(schedule, storage, HistoryConfirmed)
+	if record, ok := storage.FindWeek(week); ok && previous != nil && previous.Publication != nil &&
+		sameJSON(previous.Assignments, record.Assignments) && sameJSON(previous.Shadows, record.Shadows) {
+		record.Publication = previous.Publication
+	}
+	return schedule, reseted, nil
+}
+
+// ConfirmWeek утверждает запланированную неделю и, как GenerateWeek, при необходимости сбрасывает
+// счетчики. Второе значение сообщает, что счетчики были сброшены.
+func (s *Scheduler) ConfirmWeek(employees *[]Employee, storage *DutyHistoryStorage, week time.Time) (bool, error) {
+	record, ok := storage.FindWeek(week)
+	if !ok {
+		return false, fmt.Errorf("неделя с %s не найдена в истории", week.Format("2006-01-02"))
+	}
+	record.Status = HistoryConfirmed
+	return s.ResetDutyCounters(employees, storage), nil
+}
+
+// DiscardWeek удаляет неделю из истории и откатывает счетчики ее дежурных. Дата последнего дежурства
+// восстанавливается по оставшейся истории.
+func (s *Scheduler) DiscardWeek(employees *[]Employee, storage *DutyHistoryStorage, week time.Time) error {
+	index := -1
+	for i := range storage.History {
+		if storage.History[i].Date.Equal(week) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("неделя с %s не найдена в истории", week.Format("2006-01-02"))
+	}
+
+	discarded := storage.History[index]
+	storage.History = append(storage.History[:index], storage.History[index+1:]...)
+	s.discardShadows(employees, discarded.Shadows)
+
+	for _, assignment := range discarded.Assignments {
+		employee := FindEmployeeById(employees, assignment.EmployeeId)
+		if employee == nil {
+			continue
+		}
+
+		weight := 1
+		if dutyType, ok := s.dutyType(assignment.Duty); ok {
+			weight = dutyType.Weight
+		}
+
+		stats := employee.Duties[assignment.Duty]
+		stats.Count -= weight
+		if stats.Count < 0 {
+			stats.Count = 0
+		}
+		if stats.LastDuty.Equal(assignment.Date) {
+			stats.LastDuty = storage.lastAssignmentDate(assignment.EmployeeId, assignment.Duty)
+		}
+		if employee.Duties == nil {
+			employee.Duties = map[string]DutyStats{}
+		}
+		employee.Duties[assignment.Duty] = stats
+	}
+	return nil
+}
+
+// OnDuty возвращает назначения на дежурство dutyType, действующие в день day: для еженедельных
+// дежурств - назначение на неделю, в которую попадает день, для остальных - назначение на сам день.
+func (s *Scheduler) OnDuty(storage *DutyHistoryStorage, dutyType DutyType, day time.Time) []Assignment {
+	record, ok := storage.FindWeek(WeekStart(day))
+	if !ok {
+		return nil
+	}
+
+	var result []Assignment
+	for _, assignment := range record.Assignments {
+		if assignment.Duty != dutyType.Name {
+			continue
+		}
+		if dutyType.Cadence == CadenceWeekly || dayOf(assignment.Date).Equal(dayOf(day)) {
+			result = append(result, assignment)
+		}
+	}
+	return result
+}
+
+// lastAssignmentDate возвращает дату последнего дежурства сотрудника данного вида по истории.
+func (storage *DutyHistoryStorage) lastAssignmentDate(employeeId int, duty string) time.Time {
+	var last time.Time
+	for _, record := range storage.History {
+		for _, assignment := range record.Assignments {
+			if assignment.EmployeeId == employeeId && assignment.Duty == duty && assignment.Date.After(last) {
+				last = assignment.Date
+			}
+		}
+	}
+	return last
+}
+
+// FindEmployeeById возвращает сотрудника по Id или nil, если такого нет.
+func FindEmployeeById(employees *[]Employee, id int) *Employee {
+	for i := range *employees {
+		if (*employees)[i].Id == id {
+			return &(*employees)[i]
+		}
+	}
+	return nil
+}
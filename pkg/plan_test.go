@@ -0,0 +1,45 @@
+package pkg
+
+import "testing"
+
+func TestPlanWeeksShrinkingHorizon(t *testing.T) {
+	scheduler := NewScheduler(FixedClock(testMonday.AddDate(0, 0, -3)), nil)
+
+	employees := testEmployees("Анна", "Борис", "Вера", "Глеб", "Дина", "Ефим")
+	storage := &DutyHistoryStorage{}
+	if _, err := scheduler.PlanWeeks(&employees, storage, 4); err != nil {
+		t.Fatalf("план на 4 недели: %v", err)
+	}
+	plans, err := scheduler.PlanWeeks(&employees, storage, 2)
+	if err != nil {
+		t.Fatalf("план на 2 недели: %v", err)
+	}
+	if len(plans) != 2 {
+		t.Fatalf("недель в плане %d, ожидалось 2", len(plans))
+	}
+
+	// недели за новым горизонтом удалены, счетчики - как при плане сразу на 2 недели
+	if len(storage.History) != 2 {
+		t.Errorf("недель в истории %d, ожидалось 2", len(storage.History))
+	}
+	for _, record := range storage.History {
+		if record.Date.After(testMonday.AddDate(0, 0, 7)) {
+			t.Errorf("в истории осталась неделя с %s за горизонтом", record.Date.Format("2006-01-02"))
+		}
+	}
+
+	fresh := testEmployees("Анна", "Борис", "Вера", "Глеб", "Дина", "Ефим")
+	if _, err := scheduler.PlanWeeks(&fresh, &DutyHistoryStorage{}, 2); err != nil {
+		t.Fatalf("план на 2 недели с нуля: %v", err)
+	}
+	// дату последнего дежурства до истории DiscardWeek не восстанавливает, поэтому она
+	// сравнивается только у тех, кто дежурит в оставшихся неделях
+	for i := range fresh {
+		for duty, want := range fresh[i].Duties {
+			got := employees[i].Duties[duty]
+			if got.Count != want.Count || want.Count > 0 && !got.LastDuty.Equal(want.LastDuty) {
+				t.Errorf("%s, %s: счетчики %+v, ожидались %+v", employees[i].Name, duty, got, want)
+			}
+		}
+	}
+}
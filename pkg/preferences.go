@@ -0,0 +1,173 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Preferences - пожелания сотрудника к дежурствам. Дни Blackouts и предел MaxPerMonth - жесткие
+// ограничения: подбор их не нарушает. Preferred, Avoided и AvoidedDays - мягкие: при равной нагрузке
+// выбирается тот, кто дежурство предпочитает, а не тот, кто его или этот день недели избегает.
+type Preferences struct {
+	Blackouts   []string `json:"blackouts,omitempty"`     // дни недели (monday...sunday), в которые сотрудник не дежурит
+	Preferred   []string `json:"preferred,omitempty"`     // дежурства (DutyType.Name), которые сотрудник предпочитает
+	Avoided     []string `json:"avoided,omitempty"`       // дежурства, которых сотрудник просит избегать
+	AvoidedDays []string `json:"avoided_days,omitempty"`  // дни недели, в которые сотрудник просит по возможности не ставить его
+	MaxPerMonth int      `json:"max_per_month,omitempty"` // сколько дежурств за календарный месяц, 0 - без ограничения
+}
+
+// Empty сообщает, что пожеланий нет.
+func (p Preferences) Empty() bool {
+	return len(p.Blackouts) == 0 && len(p.Preferred) == 0 && len(p.Avoided) == 0 && len(p.AvoidedDays) == 0 && p.MaxPerMonth == 0
+}
+
+// Уровни пожелания сотрудника к дежурству (Employee.preference).
+const (
+	preferenceAvoided   = -1
+	preferenceNeutral   = 0
+	preferencePreferred = 1
+)
+
+// preference возвращает отношение сотрудника к дежурству duty в день date. Нежелательный день
+// недели перевешивает предпочтение дежурства.
+func (e Employee) preference(duty string, date time.Time) int {
+	if e.Preferences == nil {
+		return preferenceNeutral
+	}
+	if weekdayIn(e.Preferences.AvoidedDays, date) {
+		return preferenceAvoided
+	}
+	for _, name := range e.Preferences.Preferred {
+		if name == duty {
+			return preferencePreferred
+		}
+	}
+	for _, name := range e.Preferences.Avoided {
+		if name == duty {
+			return preferenceAvoided
+		}
+	}
+	return preferenceNeutral
+}
+
+// blackout сообщает, что сотрудник не дежурит в день date.
+func (e Employee) blackout(date time.Time) bool {
+	return e.Preferences != nil && weekdayIn(e.Preferences.Blackouts, date)
+}
+
+// weekdayIn сообщает, что день недели даты date есть среди дней days (monday...sunday).
+func weekdayIn(days []string, date time.Time) bool {
+	for _, day := range days {
+		if weekday, _ := parseWeekday(day); weekday == date.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// monthlyDuties возвращает, сколько дежурств у сотрудника в календарном месяце дня date
+// по истории до недели weekStart и назначениям assigned.
+func monthlyDuties(employeeId int, date time.Time, history []DutyHistory, assigned []Assignment, weekStart time.Time) int {
+	sameMonth := func(assignment Assignment) bool {
+		return assignment.EmployeeId == employeeId && assignment.Date.Year() == date.Year() && assignment.Date.Month() == date.Month()
+	}
+	n := 0
+	for _, record := range history {
+		if !record.Date.Before(weekStart) {
+			continue
+		}
+		for _, assignment := range record.Assignments {
+			if sameMonth(assignment) {
+				n++
+			}
+		}
+	}
+	for _, assignment := range assigned {
+		if sameMonth(assignment) {
+			n++
+		}
+	}
+	return n
+}
+
+// SetEmployeePreferences проверяет пожелания и записывает их сотруднику с Id id.
+// Пустые пожелания удаляются.
+func (s *Scheduler) SetEmployeePreferences(employees *[]Employee, id int, preferences Preferences) error {
+	employee := FindEmployeeById(employees, id)
+	if employee == nil {
+		return fmt.Errorf("сотрудник с Id: %d не найден", id)
+	}
+
+	for _, days := range [][]string{preferences.Blackouts, preferences.AvoidedDays} {
+		for i, day := range days {
+			if _, err := parseWeekday(day); err != nil {
+				return err
+			}
+			days[i] = strings.ToLower(day)
+		}
+	}
+	for _, duty := range append(append([]string(nil), preferences.Preferred...), preferences.Avoided...) {
+		if _, ok := s.dutyType(duty); !ok {
+			return fmt.Errorf("неизвестное дежурство %q", duty)
+		}
+	}
+	for _, preferred := range preferences.Preferred {
+		for _, avoided := range preferences.Avoided {
+			if preferred == avoided {
+				return fmt.Errorf("дежурство %s одновременно предпочитается и избегается", s.DutyTitle(preferred))
+			}
+		}
+	}
+	if preferences.MaxPerMonth < 0 {
+		return fmt.Errorf("предел дежурств в месяц не может быть отрицательным")
+	}
+
+	if preferences.Empty() {
+		employee.Preferences = nil
+		return nil
+	}
+	employee.Preferences = &preferences
+	return nil
+}
+
+// FormatPreferences возвращает пожелания сотрудника в виде строки для вывода.
+func (s *Scheduler) FormatPreferences(preferences *Preferences) string {
+	if preferences == nil || preferences.Empty() {
+		return "нет пожеланий"
+	}
+	titles := func(duties []string) string {
+		names := make([]string, len(duties))
+		for i, duty := range duties {
+			names[i] = s.DutyTitle(duty)
+		}
+		return strings.Join(names, ", ")
+	}
+
+	weekdays := func(days []string) string {
+		names := make([]string, len(days))
+		for i, day := range days {
+			weekday, _ := parseWeekday(day)
+			names[i] = weekdaysRu[weekday]
+		}
+		return strings.Join(names, ", ")
+	}
+
+	var parts []string
+	if len(preferences.Blackouts) > 0 {
+		parts = append(parts, "не дежурит: "+weekdays(preferences.Blackouts))
+	}
+	if len(preferences.Preferred) > 0 {
+		parts = append(parts, "предпочитает: "+titles(preferences.Preferred))
+	}
+	if len(preferences.Avoided) > 0 {
+		parts = append(parts, "избегает: "+titles(preferences.Avoided))
+	}
+	if len(preferences.AvoidedDays) > 0 {
+		parts = append(parts, "по возможности не дежурит: "+weekdays(preferences.AvoidedDays))
+	}
+	if preferences.MaxPerMonth > 0 {
+		parts = append(parts, fmt.Sprintf("не больше %d дежурств в месяц", preferences.MaxPerMonth))
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,184 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ErrAlreadyPublished возвращается при попытке повторно опубликовать неделю.
+var ErrAlreadyPublished = errors.New("расписание на эту неделю уже опубликовано")
+
+// Publisher отправляет готовое сообщение с расписанием.
+type Publisher interface {
+	Publish(ctx context.Context, message string) error
+	// Target - куда публикуется сообщение, для записи в историю. Не должен содержать секретов.
+	Target() string
+}
+
+// WebhookURLEnv - переменная окружения с адресом webhook, чтобы не хранить его в config.json.
+const WebhookURLEnv = "SCHEDULE_WEBHOOK_URL"
+
+// PublishConfig - раздел publish в config.json.
+type PublishConfig struct {
+	WebhookURL     string `json:"webhook_url"`     // incoming webhook Slack или Mattermost, переопределяется SCHEDULE_WEBHOOK_URL
+	Template       string `json:"template"`        // шаблон сообщения для публикации, по умолчанию общий template
+	Channel        string `json:"channel"`         // канал, если webhook позволяет его переопределить
+	Username       string `json:"username"`        // имя отправителя, если webhook позволяет его переопределить
+	TimeoutSeconds int    `json:"timeout_seconds"` // таймаут одного запроса, по умолчанию 10
+	Retries        int    `json:"retries"`         // число повторов при сетевых ошибках и ответах 429/5xx, по умолчанию 3
+}
+
+// WebhookPublisher публикует сообщение в incoming webhook Slack или Mattermost.
+// Оба принимают JSON вида {"text": "..."}.
+type WebhookPublisher struct {
+	URL      string
+	Channel  string
+	Username string
+	Client   *http.Client
+	Retries  int
+	Backoff  time.Duration // пауза перед первым повтором, дальше удваивается
+}
+
+// NewWebhookPublisher создает публикатор по настройкам. Если адрес webhook не задан ни в настройках,
+// ни в переменной окружения, возвращается nil.
+func NewWebhookPublisher(config PublishConfig) *WebhookPublisher {
+	webhookURL := config.WebhookURL
+	if env := os.Getenv(WebhookURLEnv); env != "" {
+		webhookURL = env
+	}
+	if webhookURL == "" {
+		return nil
+	}
+
+	timeout := 10 * time.Second
+	if config.TimeoutSeconds > 0 {
+		timeout = time.Duration(config.TimeoutSeconds) * time.Second
+	}
+	retries := 3
+	if config.Retries > 0 {
+		retries = config.Retries
+	}
+
+	return &WebhookPublisher{
+		URL:      webhookURL,
+		Channel:  config.Channel,
+		Username: config.Username,
+		Client:   &http.Client{Timeout: timeout},
+		Retries:  retries,
+		Backoff:  time.Second,
+	}
+}
+
+// Target возвращает адрес webhook без пути, в котором у Slack и Mattermost зашит секрет.
+func (p *WebhookPublisher) Target() string {
+	u, err := url.Parse(p.URL)
+	if err != nil {
+		return "webhook"
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// Payload возвращает тело запроса, которое будет отправлено в webhook.
+func (p *WebhookPublisher) Payload(message string) ([]byte, error) {
+	payload := struct {
+		Text     string `json:"text"`
+		Channel  string `json:"channel,omitempty"`
+		Username string `json:"username,omitempty"`
+	}{Text: message, Channel: p.Channel, Username: p.Username}
+	return json.MarshalIndent(payload, "", "  ")
+}
+
+// Publish отправляет сообщение. Сетевые ошибки и ответы 429 и 5xx повторяются до Retries раз
+// с растущей паузой, остальные ошибки возвращаются сразу.
+func (p *WebhookPublisher) Publish(ctx context.Context, message string) error {
+	body, err := p.Payload(message)
+	if err != nil {
+		return err
+	}
+
+	wait := p.Backoff
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := p.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if retryAfter < 0 || attempt >= p.Retries {
+			return fmt.Errorf("не удалось опубликовать расписание: %w", err)
+		}
+
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("не удалось опубликовать расписание: %w", ctx.Err())
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// post выполняет один запрос. Отрицательный retryAfter означает, что повторять запрос бессмысленно.
+func (p *WebhookPublisher) post(ctx context.Context, body []byte) (retryAfter time.Duration, err error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return -1, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		if ctx.Err() != nil {
+			return -1, err
+		}
+		return 0, err
+	}
+	defer response.Body.Close()
+	text, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return 0, nil
+	}
+
+	err = fmt.Errorf("webhook ответил %s: %s", response.Status, bytes.TrimSpace(text))
+	if response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= 500 {
+		if seconds, convErr := strconv.Atoi(response.Header.Get("Retry-After")); convErr == nil {
+			return time.Duration(seconds) * time.Second, err
+		}
+		return 0, err
+	}
+	return -1, err
+}
+
+// PublishWeek публикует сообщение с расписанием недели и отмечает публикацию в истории временем
+// clock. Уже опубликованная неделя повторно отправляется только при force. Передавайте реальные часы,
+// а не часы планировщика, который может смотреть на другую неделю.
+func PublishWeek(ctx context.Context, publisher Publisher, storage *DutyHistoryStorage, week time.Time, message string, force bool, clock Clock) error {
+	record, ok := storage.FindWeek(week)
+	if !ok {
+		return fmt.Errorf("неделя с %s не найдена в истории", week.Format("2006-01-02"))
+	}
+	if record.Publication != nil && !force {
+		return fmt.Errorf("%w: %s в %s", ErrAlreadyPublished, record.Publication.Target, record.Publication.PublishedAt.Format("2006-01-02 15:04"))
+	}
+
+	if err := publisher.Publish(ctx, message); err != nil {
+		return err
+	}
+
+	record.Publication = &Publication{PublishedAt: clock.Now(), Target: publisher.Target()}
+	return nil
+}
@@ -0,0 +1,107 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// webhookServer отвечает на запросы по очереди кодами statuses, последний код повторяется.
+// headers - заголовки к ответу с тем же номером. Возвращает сервер и счетчик запросов.
+func webhookServer(t *testing.T, statuses []int, headers map[int]http.Header) (*httptest.Server, *int32) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&requests, 1)) - 1
+		for key, values := range headers[n] {
+			w.Header()[key] = values
+		}
+		if n >= len(statuses) {
+			n = len(statuses) - 1
+		}
+		w.WriteHeader(statuses[n])
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestWebhookPublisherRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		retries  int
+		fail     bool
+		requests int32
+	}{
+		{name: "успешный ответ", statuses: []int{http.StatusOK}, retries: 3, requests: 1},
+		{name: "повтор после 5xx", statuses: []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK}, retries: 3, requests: 3},
+		{name: "повтор после 429", statuses: []int{http.StatusTooManyRequests, http.StatusOK}, retries: 3, requests: 2},
+		{name: "повторы исчерпаны", statuses: []int{http.StatusInternalServerError}, retries: 2, fail: true, requests: 3},
+		{name: "4xx не повторяется", statuses: []int{http.StatusBadRequest, http.StatusOK}, retries: 3, fail: true, requests: 1},
+		{name: "404 не повторяется", statuses: []int{http.StatusNotFound}, retries: 3, fail: true, requests: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := webhookServer(t, tt.statuses, nil)
+			publisher := &WebhookPublisher{URL: server.URL, Client: server.Client(), Retries: tt.retries, Backoff: time.Millisecond}
+
+			err := publisher.Publish(context.Background(), "расписание")
+			if (err != nil) != tt.fail {
+				t.Fatalf("Publish: ошибка %v, ожидалась ошибка: %v", err, tt.fail)
+			}
+			if got := atomic.LoadInt32(requests); got != tt.requests {
+				t.Errorf("запросов %d, ожидалось %d", got, tt.requests)
+			}
+		})
+	}
+}
+
+func TestWebhookPublisherRetryAfter(t *testing.T) {
+	headers := map[int]http.Header{0: {"Retry-After": []string{"1"}}}
+	server, requests := webhookServer(t, []int{http.StatusTooManyRequests, http.StatusOK}, headers)
+	publisher := &WebhookPublisher{URL: server.URL, Client: server.Client(), Retries: 3, Backoff: time.Millisecond}
+
+	start := time.Now()
+	if err := publisher.Publish(context.Background(), "расписание"); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("повтор через %s, Retry-After требует не раньше чем через 1s", elapsed)
+	}
+	if got := atomic.LoadInt32(requests); got != 2 {
+		t.Errorf("запросов %d, ожидалось 2", got)
+	}
+}
+
+func TestPublishWeekOnce(t *testing.T) {
+	server, requests := webhookServer(t, []int{http.StatusOK}, nil)
+	publisher := &WebhookPublisher{URL: server.URL, Client: server.Client(), Retries: 3, Backoff: time.Millisecond}
+	storage := &DutyHistoryStorage{History: []DutyHistory{{Date: testMonday}}}
+
+	if err := PublishWeek(context.Background(), publisher, storage, testMonday, "расписание", false, FixedClock(testMonday)); err != nil {
+		t.Fatalf("первая публикация: %v", err)
+	}
+	record, _ := storage.FindWeek(testMonday)
+	if record.Publication == nil || record.Publication.Target != publisher.Target() {
+		t.Fatalf("публикация не отмечена в истории: %+v", record.Publication)
+	}
+
+	err := PublishWeek(context.Background(), publisher, storage, testMonday, "расписание", false, FixedClock(testMonday))
+	if !errors.Is(err, ErrAlreadyPublished) {
+		t.Errorf("повторная публикация: ошибка %v, ожидалась ErrAlreadyPublished", err)
+	}
+	if got := atomic.LoadInt32(requests); got != 1 {
+		t.Errorf("запросов %d, ожидался 1", got)
+	}
+
+	if err := PublishWeek(context.Background(), publisher, storage, testMonday, "расписание", true, FixedClock(testMonday)); err != nil {
+		t.Fatalf("публикация с force: %v", err)
+	}
+	if got := atomic.LoadInt32(requests); got != 2 {
+		t.Errorf("запросов после force %d, ожидалось 2", got)
+	}
+}
@@ -0,0 +1,116 @@
+package pkg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// StatsChange - расхождение счетчика или даты последнего дежурства сотрудника с историей.
+type StatsChange struct {
+	EmployeeId   int       `json:"employee_id"`
+	EmployeeName string    `json:"employee_name"`
+	Duty         string    `json:"duty"`
+	Before       DutyStats `json:"before"` // сохраненное значение
+	After        DutyStats `json:"after"`  // значение по истории
+}
+
+// RecomputeStats пересчитывает счетчики и даты последних дежурств сотрудников по истории и записывает
+// их в employees. Счетчик - сумма весов назначений с датами в окне [from, to], дата последнего
+// дежурства - последнее назначение не позже to: сброс счетчиков ее не обнуляет. Нулевые from и to
+// окно не ограничивают. from действует только на дежурства со сбросом счетчиков (ResetCounters):
+// счетчики остальных не сбрасываются и считаются по всей истории. Если в истории у сотрудника нет ни одного такого дежурства, дата остается
+// прежней: новичкам она проставляется при добавлении. Возвращаются все расхождения с прежними значениями.
+func (s *Scheduler) RecomputeStats(employees *[]Employee, storage *DutyHistoryStorage, from, to time.Time) []StatsChange {
+	type key struct {
+		id   int
+		duty string
+	}
+	recomputed := map[key]DutyStats{}
+	seen := map[key]bool{}
+	for _, record := range storage.History {
+		for _, assignment := range record.Assignments {
+			date := dayOf(assignment.Date)
+			k := key{assignment.EmployeeId, assignment.Duty}
+			seen[k] = true
+			if !to.IsZero() && date.After(dayOf(to)) {
+				continue
+			}
+			stats := recomputed[k]
+			if assignment.Date.After(stats.LastDuty) {
+				stats.LastDuty = assignment.Date
+			}
+			dutyType, ok := s.dutyType(assignment.Duty)
+			if !ok {
+				dutyType = DutyType{Weight: 1}
+			}
+			if from.IsZero() || !dutyType.ResetCounters || !date.Before(dayOf(from)) {
+				stats.Count += dutyType.Weight
+			}
+			recomputed[k] = stats
+		}
+	}
+
+	var changes []StatsChange
+	for i := range *employees {
+		employee := &(*employees)[i]
+		for _, duty := range s.statsDuties(employee) {
+			k := key{employee.Id, duty}
+			before := employee.Duties[duty]
+			after := recomputed[k]
+			if !seen[k] {
+				after.LastDuty = before.LastDuty
+			}
+			if before.Count == after.Count && before.LastDuty.Equal(after.LastDuty) {
+				continue
+			}
+			changes = append(changes, StatsChange{
+				EmployeeId:   employee.Id,
+				EmployeeName: employee.Name,
+				Duty:         duty,
+				Before:       before,
+				After:        after,
+			})
+			if employee.Duties == nil {
+				employee.Duties = map[string]DutyStats{}
+			}
+			employee.Duties[duty] = after
+		}
+	}
+	return changes
+}
+
+// statsDuties возвращает дежурства, счетчики которых пересчитываются у сотрудника: все из настроек
+// в порядке объявления и затем по алфавиту те, что остались у сотрудника от прежних настроек.
+func (s *Scheduler) statsDuties(employee *Employee) []string {
+	var duties []string
+	for _, dutyType := range s.DutyTypes {
+		duties = append(duties, dutyType.Name)
+	}
+	var legacy []string
+	for duty := range employee.Duties {
+		if _, ok := s.dutyType(duty); !ok {
+			legacy = append(legacy, duty)
+		}
+	}
+	sort.Strings(legacy)
+	return append(duties, legacy...)
+}
+
+// FormatStatsChanges возвращает расхождения с историей в виде текста, по строке на дежурство сотрудника.
+func (s *Scheduler) FormatStatsChanges(changes []StatsChange) string {
+	var b strings.Builder
+	for _, change := range changes {
+		fmt.Fprintf(&b, "%d. %s | %s | дежурств: %d -> %d | последнее: %s -> %s\n", change.EmployeeId, change.EmployeeName,
+			s.DutyTitle(change.Duty), change.Before.Count, change.After.Count, formatLastDuty(change.Before.LastDuty), formatLastDuty(change.After.LastDuty))
+	}
+	return b.String()
+}
+
+func formatLastDuty(date time.Time) string {
+	if date.IsZero() {
+		return "нет"
+	}
+	return date.Format("2006-01-02")
+}
@@ -0,0 +1,55 @@
+package pkg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecomputeStatsMatchesGeneration(t *testing.T) {
+	employees := testEmployees("Анна", "Борис", "Вера", "Глеб", "Дина", "Ефим")
+	// новичок без истории: дата последнего дежурства остается той, что проставлена при добавлении
+	NewScheduler(FixedClock(testMonday), nil).AddNewEmployee(&employees, "Жанна")
+	storage := &DutyHistoryStorage{}
+
+	friday := testMonday.AddDate(0, 0, -3).Add(15 * time.Hour)
+	for week := 0; week < 16; week++ {
+		// счетчики Support сбрасываются, релизов - считаются по всей истории
+		scheduler := NewScheduler(FixedClock(friday.AddDate(0, 0, 7*week)), resetDutyTypes("support"))
+		if _, _, err := scheduler.GenerateWeek(&employees, storage); err != nil {
+			t.Fatalf("неделя %d: %v", week, err)
+		}
+		if week == 3 {
+			// перегенерация уже сформированной недели откатывает ее счетчики
+			if _, _, err := scheduler.GenerateWeek(&employees, storage); err != nil {
+				t.Fatalf("перегенерация: %v", err)
+			}
+		}
+		if week == 5 {
+			record := storage.History[len(storage.History)-1]
+			ref := SlotRef{Duty: "support", Date: record.Date}
+			for _, assignment := range record.Assignments {
+				if assignment.Duty == "support" && assignment.Date.Equal(record.Date) {
+					ref.EmployeeId = assignment.EmployeeId
+				}
+			}
+			replacement := 7
+			if ref.EmployeeId == 7 {
+				replacement = 1
+			}
+			if _, err := scheduler.ReplaceAssignment(&employees, storage, record.Date, ref, replacement, "test"); err != nil {
+				t.Fatalf("замена: %v", err)
+			}
+		}
+
+		check, err := CloneState(&State{Employees: &employees, History: storage})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changes := scheduler.RecomputeStats(check.Employees, check.History, storage.LastResetDate, time.Time{}); len(changes) > 0 {
+			t.Fatalf("неделя %d: пересчет расходится со счетчиками:\n%s", week, scheduler.FormatStatsChanges(changes))
+		}
+	}
+	if !storage.LastResetDate.After(friday) {
+		t.Error("за 16 недель счетчики ни разу не сбросились, сброс не проверен")
+	}
+}
@@ -0,0 +1,130 @@
+package pkg
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// WeekSchedule - расписание на неделю, из которого шаблон строит сообщение.
+type WeekSchedule struct {
+	Start       time.Time // понедельник
+	End         time.Time // пятница
+	Assignments []Assignment
+	Sections    []ScheduleSection
+	Decisions   []SlotDecision // разбор подбора дежурных, заполняется только в GetSchedule
+	Shadows     []ShadowShift  // стажеры рядом с дежурными
+}
+
+// ScheduleSection - блок сообщения (DutyType.Section) со строками в порядке вывода.
+type ScheduleSection struct {
+	Title string         `json:"title"`
+	Lines []ScheduleLine `json:"lines"`
+}
+
+// ScheduleLine - одна строка блока: назначение или отметка о праздничном дне без дежурства.
+type ScheduleLine struct {
+	EmployeeName string    `json:"employee_name,omitempty"` // пусто для DayOff
+	Duty         string    `json:"duty"`
+	DutyTitle    string    `json:"duty_title"`
+	Cadence      string    `json:"cadence"`
+	Date         time.Time `json:"date"`
+	Weekday      string    `json:"weekday"`           // день недели по-русски
+	DayOff       bool      `json:"day_off,omitempty"` // праздник, дежурство не назначалось
+	Holiday      string    `json:"holiday,omitempty"` // название праздника для DayOff
+	Moved        bool      `json:"moved,omitempty"`   // еженедельное дежурство перенесено из-за праздника
+	Shadow       string    `json:"shadow,omitempty"`  // стажер рядом с дежурным
+}
+
+// DefaultTemplate - встроенный шаблон, которым сообщение формировалось изначально.
+const DefaultTemplate = "markdown"
+
+//go:embed templates/*.tmpl
+var builtinTemplates embed.FS
+
+// BuiltinTemplates возвращает имена встроенных шаблонов.
+func BuiltinTemplates() []string {
+	entries, _ := builtinTemplates.ReadDir("templates")
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".tmpl"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Функции, доступные в шаблонах сообщений, помимо встроенных функций text/template (html, js, ...).
+var templateFuncs = template.FuncMap{
+	"slack":    escapeSlack,
+	"telegram": escapeTelegram,
+}
+
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// escapeSlack экранирует управляющие символы Slack mrkdwn.
+func escapeSlack(s string) string {
+	return slackEscaper.Replace(s)
+}
+
+// escapeTelegram экранирует символы, зарезервированные в Telegram MarkdownV2.
+func escapeTelegram(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune("\\_*[]()~`>#+-=|{}.!", r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Renderer превращает WeekSchedule в текст сообщения по шаблону text/template.
+type Renderer struct {
+	tmpl *template.Template
+}
+
+// NewRenderer возвращает рендерер по имени встроенного шаблона (markdown, slack, telegram, plain, html)
+// или по пути к файлу шаблона. Относительный путь считается от каталога dir.
+func NewRenderer(name, dir string) (*Renderer, error) {
+	if name == "" {
+		name = DefaultTemplate
+	}
+
+	var text []byte
+	var err error
+	if !strings.ContainsAny(name, `/\.`) {
+		text, _ = builtinTemplates.ReadFile("templates/" + name + ".tmpl")
+	}
+	if text == nil {
+		path := name
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		text, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("шаблон %q не найден среди встроенных (%s) и не читается как файл: %w",
+				name, strings.Join(BuiltinTemplates(), ", "), err)
+		}
+	}
+
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка в шаблоне %q: %w", name, err)
+	}
+	return &Renderer{tmpl: tmpl}, nil
+}
+
+// Render формирует текст сообщения. Переводы строк в конце шаблона отбрасываются.
+func (r *Renderer) Render(schedule WeekSchedule) (string, error) {
+	var buf bytes.Buffer
+	if err := r.tmpl.Execute(&buf, schedule); err != nil {
+		return "", fmt.Errorf("не удалось сформировать сообщение: %w", err)
+	}
+	return strings.TrimRight(buf.String(), "\n"), nil
+}
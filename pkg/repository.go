@@ -0,0 +1,89 @@
+package pkg
+
+import "path/filepath"
+
+// EmployeeRepository загружает и сохраняет список сотрудников.
+type EmployeeRepository interface {
+	LoadEmployees() (*[]Employee, error)
+	SaveEmployees(employees *[]Employee) error
+}
+
+// HistoryRepository загружает и сохраняет историю дежурств.
+type HistoryRepository interface {
+	LoadDutyHistory() (*DutyHistoryStorage, error)
+	SaveDutyHistory(storage *DutyHistoryStorage) error
+}
+
+// EventRepository дописывает и читает журнал изменений.
+type EventRepository interface {
+	AppendEvents(events []Event) error
+	LoadEvents(filter EventFilter) ([]Event, error)
+}
+
+// Repository объединяет хранилища сотрудников, истории и журнала одного бэкенда.
+type Repository interface {
+	EmployeeRepository
+	HistoryRepository
+	EventRepository
+	// Save сохраняет сотрудников и историю вместе с событиями журнала об их изменении так,
+	// чтобы сохраненное изменение не осталось без записи в журнале.
+	Save(state *State, events []Event) error
+	Close() error
+}
+
+// JSONRepository хранит сотрудников и историю в JSON-файлах, целиком переписывая их при сохранении.
+// Журнал изменений только дописывается в events.jsonl, по событию на строку.
+type JSONRepository struct {
+	EmployeesPath string
+	HistoryPath   string
+	EventsPath    string
+}
+
+// NewJSONRepository создает JSON-хранилище с файлами employees.json, history.json и events.jsonl в каталоге dir.
+func NewJSONRepository(dir string) *JSONRepository {
+	return &JSONRepository{
+		EmployeesPath: filepath.Join(dir, "employees.json"),
+		HistoryPath:   filepath.Join(dir, "history.json"),
+		EventsPath:    filepath.Join(dir, "events.jsonl"),
+	}
+}
+
+func (r *JSONRepository) LoadEmployees() (*[]Employee, error) {
+	return LoadEmployees(r.EmployeesPath)
+}
+
+func (r *JSONRepository) SaveEmployees(employees *[]Employee) error {
+	return SaveEmployees(r.EmployeesPath, employees)
+}
+
+func (r *JSONRepository) LoadDutyHistory() (*DutyHistoryStorage, error) {
+	return LoadDutyHistory(r.HistoryPath)
+}
+
+func (r *JSONRepository) SaveDutyHistory(storage *DutyHistoryStorage) error {
+	return SaveDutyHistory(r.HistoryPath, storage)
+}
+
+func (r *JSONRepository) AppendEvents(events []Event) error {
+	return AppendEvents(r.EventsPath, events)
+}
+
+// Save дописывает события в журнал до сохранения файлов: если сохранить их не удастся,
+// в журнале останется лишнее событие, но не будет изменений без записи.
+func (r *JSONRepository) Save(state *State, events []Event) error {
+	if err := r.AppendEvents(events); err != nil {
+		return err
+	}
+	if err := r.SaveEmployees(state.Employees); err != nil {
+		return err
+	}
+	return r.SaveDutyHistory(state.History)
+}
+
+func (r *JSONRepository) LoadEvents(filter EventFilter) ([]Event, error) {
+	return LoadEvents(r.EventsPath, filter)
+}
+
+func (r *JSONRepository) Close() error {
+	return nil
+}
@@ -1,160 +1,181 @@
 package pkg
 
 import (
-	"errors"
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 )
 
-// findEmployeeForInstances находит подходящего сотрудника для дежурства типа "Instances release".
-func findEmployeeForInstances(employees *[]Employee, expressEmployee *Employee) (Employee, error) {
-	// Сортировка списка сотрудников сначала по числу дежурств, затем по дате последнего дежурства.
-	sort.Slice(*employees, func(i, j int) bool {
-		if (*employees)[i].InstancesDutyCount == (*employees)[j].InstancesDutyCount {
-			return (*employees)[i].ReleaseLastDuty.Before((*employees)[j].ReleaseLastDuty)
-		}
-		return (*employees)[i].InstancesDutyCount < (*employees)[j].InstancesDutyCount
-	})
+// Scheduler формирует расписание относительно даты, которую возвращает Clock.
+type Scheduler struct {
+	Clock     Clock
+	DutyTypes []DutyType // порядок важен: дежурства подбираются в этом порядке
+	Calendar  *Calendar  // праздники; nil - рабочие все дни с понедельника по пятницу
+	Solver    SolverConfig
+	Teams     *Teams // другие команды каталога данных; nil - конфликты с ними не проверяются
 
-	for _, employee := range *employees {
-		// Исключаем сотрудника, который болеет, в отпуске или уволен.
-		if employee.Status == StatusVacation || employee.Status == StatusSick || employee.Status == StatusFired {
-			continue
-		}
-
-		// Исключаем сотрудника, который был назначен на "Express Release".
-		if employee.Id == expressEmployee.Id {
-			continue
-		}
+	teamDuties []TeamDuty // дежурства общих сотрудников в других командах, см. loadTeamDuties
+}
 
-		// Проверяем, что прошло не менее 2 недель с момента последнего дежурства сотрудника.
-		if time.Since(employee.ReleaseLastDuty).Hours() >= 14*24 {
-			employee.ReleaseLastDuty = nextMonday().AddDate(0, 0, 3)
-			employee.InstancesDutyCount++
-			return employee, nil
-		}
+// NewScheduler создает планировщик. Если clock не передан, используется системное время,
+// если не переданы виды дежурств - DefaultDutyTypes.
+func NewScheduler(clock Clock, dutyTypes []DutyType) *Scheduler {
+	if clock == nil {
+		clock = SystemClock{}
 	}
+	if len(dutyTypes) == 0 {
+		dutyTypes = DefaultDutyTypes()
+	}
+	return &Scheduler{Clock: clock, DutyTypes: dutyTypes}
+}
 
-	// Если дошли до конца списка и никого не подобрали тогда берем первого, с наименьшим количеством дежурств в Instances release
-	// Сортировка списка сотрудников по возрастанию числа дежурств.
-	sort.Slice(*employees, func(i, j int) bool {
-		return (*employees)[i].InstancesDutyCount < (*employees)[j].InstancesDutyCount
-	})
-
-	for _, employee := range *employees {
-		// Исключаем сотрудника, который болеет, в отпуске или уволен.
-		if employee.Status == StatusVacation || employee.Status == StatusSick || employee.Status == StatusFired {
-			continue
-		}
+func (s *Scheduler) now() time.Time {
+	return s.Clock.Now()
+}
 
-		// Исключаем сотрудника, который был назначен на "Express Release".
-		if employee.Id == expressEmployee.Id {
-			continue
-		}
+// slot - один день (или неделя) дежурства, на который нужно подобрать сотрудника.
+type slot struct {
+	dutyType DutyType
+	date     time.Time
+	// период, в течение которого сотрудник должен быть доступен
+	from, to time.Time
+}
 
-		employee.ReleaseLastDuty = nextMonday().AddDate(0, 0, 3)
-		employee.InstancesDutyCount++
-		return employee, nil
+func (s *Scheduler) newSlot(dutyType DutyType, date, weekStart time.Time) slot {
+	if dutyType.Cadence == CadenceWeekly {
+		// на релиз не назначаем тех, кто отсутствует хотя бы один рабочий день недели
+		return slot{dutyType: dutyType, date: date, from: weekStart, to: weekStart.AddDate(0, 0, 4)}
 	}
-
-	return Employee{}, errors.New("не найдено подходящего сотрудника для дежурства 'Instances release'")
+	return slot{dutyType: dutyType, date: date, from: date, to: date}
 }
 
-// findEmployeeForSupport находит подходящего сотрудника для дежурства типа "Support".
-func findEmployeeForSupport(employees *[]Employee, dayInWeek int) (Employee, error) {
-	// Сортировка списка сотрудников сначала по числу дежурств, затем по дате последнего дежурства.
-	sort.Slice(*employees, func(i, j int) bool {
-		if (*employees)[i].SupportDutyCount == (*employees)[j].SupportDutyCount {
-			return (*employees)[i].SupportLastDuty.Before((*employees)[j].SupportLastDuty)
+// findEmployee подбирает сотрудника на слот и возвращает его индекс в employees и разбор подбора.
+// Сначала ищется сотрудник с наименьшей нагрузкой, у которого прошел перерыв с прошлого дежурства,
+// если такого нет - берется первый доступный с наименьшей нагрузкой. Нагрузка - сумма дежурств
+// по окну справедливости в истории history или, у дежурств со сбросом счетчиков, сам счетчик (см. dutyLoads).
+// При равной нагрузке вперед идут те, кто предпочитает дежурство, и назад - те, кто просит избегать
+// его или этот день недели.
+func (s *Scheduler) findEmployee(employees []Employee, sl slot, history []DutyHistory, assigned []Assignment) (int, SlotDecision, error) {
+	name := sl.dutyType.Name
+	group := s.cooldownGroup(sl.dutyType)
+	loads := s.dutyLoads(employees, sl.dutyType, history, assigned, s.nextMonday())
+
+	order := make([]int, 0, len(employees))
+	for i := range employees {
+		// уволенные не рассматриваются и в разбор не попадают
+		if employees[i].Status != StatusFired {
+			order = append(order, i)
 		}
-		return (*employees)[i].SupportDutyCount < (*employees)[j].SupportDutyCount
-	})
+	}
 
-	for i, employee := range *employees {
-		// Исключаем сотрудника, который болеет, в отпуске или уволен.
-		if employee.Status == StatusVacation || employee.Status == StatusSick || employee.Status == StatusFired {
-			continue
+	// Сортировка списка сотрудников сначала по нагрузке, затем по пожеланиям и по дате последнего дежурства.
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if loads[a] != loads[b] {
+			return loads[a] < loads[b]
 		}
-
-		// Проверяем, что прошло не менее 7 дней с момента последнего дежурства сотрудника.
-		if time.Since(employee.SupportLastDuty).Hours() >= 7*24 {
-			employee.SupportLastDuty = nextMonday().AddDate(0, 0, dayInWeek)
-			employee.SupportDutyCount = employee.SupportDutyCount + 2
-			// и удаляем его из списка сотрудников, чтобы он не попал в дежурство на следующий день
-			*employees = append((*employees)[:i], (*employees)[i+1:]...)
-			return employee, nil
+		if pa, pb := employees[a].preference(name, sl.date), employees[b].preference(name, sl.date); pa != pb {
+			return pa > pb
 		}
-	}
-
-	// Если дошли до конца списка и никого не подобрали тогда берем первого, с наименьшим количеством дежурств в Support
-	// Сортировка списка сотрудников по возрастанию числа дежурств.
-	sort.Slice(*employees, func(i, j int) bool {
-		return (*employees)[i].SupportDutyCount < (*employees)[j].SupportDutyCount
+		return employees[a].lastDuty(group).Before(employees[b].lastDuty(group))
 	})
 
-	for i, employee := range *employees {
-		// Исключаем сотрудника, который болеет, в отпуске или уволен.
-		if employee.Status == StatusVacation || employee.Status == StatusSick || employee.Status == StatusFired {
-			continue
+	decision := SlotDecision{Duty: name, Date: sl.date}
+	chosen := -1 // позиция выбранного в decision.Candidates
+	for _, i := range order {
+		employee := employees[i]
+		candidate := Candidate{
+			EmployeeId:   employee.Id,
+			EmployeeName: employee.Name,
+			Count:        employee.Duties[name].Count,
+			Load:         loads[i],
+			LastDuty:     employee.lastDuty(group),
+			Preference:   preferenceNames[employee.preference(name, sl.date)],
 		}
-
-		employee.SupportLastDuty = nextMonday().AddDate(0, 0, dayInWeek)
-		employee.SupportDutyCount = employee.SupportDutyCount + 2
-		// и удаляем его из списка сотрудников, чтобы он не попал в дежурство на следующий день
-		*employees = append((*employees)[:i], (*employees)[i+1:]...)
-		return employee, nil
+		if missing := employee.missingSkills(sl.dutyType); len(missing) > 0 {
+			candidate.Reason, candidate.Detail = ReasonUnqualified, "нет навыков: "+strings.Join(missing, ", ")
+		} else {
+			candidate.Reason, candidate.Detail = s.exclusion(sl, employee, history, assigned)
+		}
+		switch {
+		case candidate.Reason != "":
+		case sl.dutyType.inCooldown(candidate.LastDuty, sl.date):
+			// с прошлого дежурства до дня слота прошло недостаточно времени
+			candidate.Reason = ReasonCooldown
+			candidate.Detail = fmt.Sprintf("нужно %d дн. с прошлого дежурства", sl.dutyType.CooldownDays)
+		case chosen >= 0:
+			candidate.Reason = ReasonQueue
+		default:
+			chosen = len(decision.Candidates)
+			decision.Rule = RuleFewestDuties
+		}
+		decision.Candidates = append(decision.Candidates, candidate)
 	}
 
-	return Employee{}, errors.New("не найдено подходящего сотрудника для дежурства 'Support'")
-}
-
-// findEmployeeForExpress находит подходящего сотрудника для дежурства типа "Express Release".
-func findEmployeeForExpress(employees *[]Employee) (Employee, error) {
-	// Сортировка списка сотрудников сначала по числу дежурств, затем по дате последнего дежурства.
-	sort.Slice(*employees, func(i, j int) bool {
-		if (*employees)[i].ExpressDutyCount == (*employees)[j].ExpressDutyCount {
-			return (*employees)[i].ReleaseLastDuty.Before((*employees)[j].ReleaseLastDuty)
+	if chosen >= 0 {
+		// следующий в очереди подходящий кандидат показывает, что решило выбор
+		winner := decision.Candidates[chosen]
+		for _, candidate := range decision.Candidates[chosen+1:] {
+			if candidate.Reason != ReasonQueue {
+				continue
+			}
+			if candidate.Load == winner.Load {
+				switch {
+				case candidate.Preference != winner.Preference:
+					decision.Rule = RulePreference
+				case candidate.LastDuty.Equal(winner.LastDuty):
+					decision.Rule = RuleListOrder
+				default:
+					decision.Rule = RuleLongestRest
+				}
+			}
+			break
 		}
-		return (*employees)[i].ExpressDutyCount < (*employees)[j].ExpressDutyCount
-	})
-
-	for _, employee := range *employees {
-		// Исключаем сотрудника, который болеет, в отпуске или уволен.
-		if employee.Status == StatusVacation || employee.Status == StatusSick || employee.Status == StatusFired {
-			continue
+	} else {
+		// Если дошли до конца списка и никого не подобрали, тогда берем первого доступного
+		// с наименьшей нагрузкой: список уже отсортирован по ней.
+		for k, candidate := range decision.Candidates {
+			if candidate.Reason == ReasonCooldown {
+				chosen = k
+				decision.Rule = RuleCooldownFallback
+				break
+			}
 		}
+	}
 
-		// Проверяем, что прошло не менее 2 недель с момента последнего дежурства сотрудника.
-		if time.Since(employee.ReleaseLastDuty).Hours() >= 14*24 {
-			employee.ReleaseLastDuty = nextMonday().AddDate(0, 0, 3)
-			employee.ExpressDutyCount = employee.ExpressDutyCount + 2
-			return employee, nil
-		}
+	if chosen < 0 {
+		return -1, decision, fmt.Errorf("не найдено подходящего сотрудника для дежурства '%s'", sl.dutyType.Title)
 	}
 
-	// Если дошли до конца списка и никого не подобрали тогда берем первого, с наименьшим количеством дежурств в Express release
-	// Сортировка списка сотрудников по возрастанию числа дежурств.
-	sort.Slice(*employees, func(i, j int) bool {
-		return (*employees)[i].ExpressDutyCount < (*employees)[j].ExpressDutyCount
-	})
+	winner := &decision.Candidates[chosen]
+	winner.Chosen, winner.Reason, winner.Detail = true, "", ""
+	decision.EmployeeId, decision.EmployeeName = winner.EmployeeId, winner.EmployeeName
+	decision.Overridden = overriddenPreferences(decision.Candidates)
+	return order[chosen], decision, nil
+}
 
-	for _, employee := range *employees {
-		// Исключаем сотрудника, который болеет, в отпуске или уволен.
-		if employee.Status == StatusVacation || employee.Status == StatusSick || employee.Status == StatusFired {
-			continue
+// overriddenPreferences возвращает пожелания кандидатов, которые подбор не учел: выбранный просил
+// избегать дежурства или предпочитавший его кандидат не назначен.
+func overriddenPreferences(candidates []Candidate) []string {
+	var notes []string
+	for _, candidate := range candidates {
+		switch {
+		case candidate.Chosen && candidate.Preference == preferenceNames[preferenceAvoided]:
+			notes = append(notes, fmt.Sprintf("%s просит избегать этого дежурства или дня, но назначен(а)", candidate.EmployeeName))
+		case !candidate.Chosen && candidate.Preference == preferenceNames[preferencePreferred]:
+			reason := reasonTitles[candidate.Reason]
+			if candidate.Reason == "" {
+				reason = "оптимизатор выбрал другого дежурного"
+			}
+			notes = append(notes, fmt.Sprintf("%s предпочитает это дежурство, но не назначен(а): %s", candidate.EmployeeName, reason))
 		}
-
-		employee.ReleaseLastDuty = nextMonday().AddDate(0, 0, 3)
-		employee.ExpressDutyCount++
-		return employee, nil
 	}
-
-	return Employee{}, errors.New("не найдено подходящего сотрудника для дежурства 'Express Release'")
+	return notes
 }
 
-func nextMonday() time.Time {
-	today := time.Now().Weekday()
+func (s *Scheduler) nextMonday() time.Time {
+	today := s.now().Weekday()
 	var daysToAdd int
 
 	switch today {
@@ -174,103 +195,196 @@ func nextMonday() time.Time {
 		daysToAdd = 1
 	}
 
-	nextMonday := time.Now().AddDate(0, 0, daysToAdd)
-	return nextMonday.Truncate(24 * time.Hour)
+	return startOfDay(s.now()).AddDate(0, 0, daysToAdd)
 }
 
-// GetSchedule формирует и возвращает расписание на предстоящую неделю.
-func GetSchedule(employees *[]Employee) (string, *[]Employee, error) {
-	// узнать какой сегодня день недели и прибавить столько дней, чтобы получить понедельник
-
-	startDate := nextMonday()             // начало следующей недели
-	endDate := startDate.AddDate(0, 0, 4) // пятница следующей недели
-
-	fmt.Println("startDate: ", startDate, " | endDate: ", endDate)
-
-	var schedule []Employee
+// GetSchedule формирует расписание на неделю, следующую за текущей датой планировщика.
+// Чтобы получить расписание на конкретную неделю, создайте планировщик с FixedClock на ее понедельник.
+// Закрепленные на неделю сотрудники (storage.Pins) занимают свои слоты, остальные слоты подбираются
+// вокруг них; нагрузка по дежурствам с окном справедливости считается по storage.History.
+// Счетчики и даты последних дежурств назначенных сотрудников, в том числе закрепленных, обновляются
+// в employees, разбор подбора каждого слота возвращается в WeekSchedule.Decisions.
+// С Solver.Engine = SolverOptimize свободные слоты заполняет оптимизатор (см. optimizeWeek).
+// Общий с другими командами сотрудник не назначается в день, когда дежурит там (см. Teams).
+// Дежурными назначаются только сотрудники с навыками дежурства (DutyType.RequiredSkills), рядом с ними
+// ставятся стажеры (см. assignShadows).
+// Текст сообщения из расписания получается через Renderer.
+func (s *Scheduler) GetSchedule(employees *[]Employee, storage *DutyHistoryStorage) (WeekSchedule, error) {
+	startDate := s.nextMonday() // начало следующей недели
+	if err := s.loadTeamDuties(); err != nil {
+		return WeekSchedule{}, err
+	}
 
-	expressEmployee, err := findEmployeeForExpress(employees)
+	pinned, err := s.pinnedAssignments(*employees, storage.PinsFor(startDate), startDate)
 	if err != nil {
-		return "", nil, err
+		return WeekSchedule{}, err
+	}
+	// закрепления учитываются заранее, чтобы при подборе на другие дни и дежурства
+	// закрепленный сотрудник уже считался назначенным
+	s.recordPinned(employees, pinned)
+	var schedule WeekSchedule
+	if s.Solver.Engine == SolverOptimize {
+		schedule, err = s.optimizeWeek(employees, storage, pinned, startDate)
+	} else {
+		schedule, err = s.greedyWeek(employees, storage, pinned, startDate)
 	}
-	fmt.Println("---expressEmployee---")
-	fmt.Println(*employees)
-	fmt.Println(expressEmployee)
-
-	instancesEmployee, err := findEmployeeForInstances(employees, &expressEmployee)
 	if err != nil {
-		return "", nil, err
+		return WeekSchedule{}, err
 	}
-	fmt.Println("---instancesEmployee---")
-	fmt.Println(*employees)
-	fmt.Println(instancesEmployee)
+	schedule.addShadows(s.assignShadows(employees, storage.History, schedule.Assignments, startDate))
+	return schedule, nil
+}
 
-	// Обновляем статусы и счетчики для выбранных дежурных
-	err = updateEmployeeInList(employees, &expressEmployee)
-	if err != nil {
-		return "", nil, err
+// greedyWeek подбирает дежурных на свободные слоты недели weekStart по очереди, каждому - подходящего
+// сотрудника с наименьшей нагрузкой (см. findEmployee).
+func (s *Scheduler) greedyWeek(employees *[]Employee, storage *DutyHistoryStorage, pinned []Assignment, startDate time.Time) (WeekSchedule, error) {
+	taken := append([]Assignment(nil), pinned...)
+
+	var assignments []Assignment
+	var decisions []SlotDecision
+	for _, dutyType := range s.DutyTypes {
+		for _, date := range s.dutyDates(dutyType, startDate) {
+			free := dutyType.Slots
+			for _, assignment := range pinned {
+				if assignment.Duty == dutyType.Name && assignment.Date.Equal(date) {
+					assignments = append(assignments, assignment)
+					decisions = append(decisions, pinnedDecision(assignment))
+					free--
+				}
+			}
+
+			sl := s.newSlot(dutyType, date, startDate)
+			for n := 0; n < free; n++ {
+				i, decision, err := s.findEmployee(*employees, sl, storage.History, taken)
+				if err != nil {
+					return WeekSchedule{}, err
+				}
+				decisions = append(decisions, decision)
+
+				employee := &(*employees)[i]
+				employee.recordDuty(dutyType, date)
+				assignment := Assignment{
+					Duty:         dutyType.Name,
+					Date:         date,
+					EmployeeId:   employee.Id,
+					EmployeeName: employee.Name,
+				}
+				taken = append(taken, assignment)
+				assignments = append(assignments, assignment)
+			}
+		}
 	}
-	expressEmployee.SupportDutyCount = 0
-	expressEmployee.ExpressDutyCount = 1
-	expressEmployee.InstancesDutyCount = 0
-	schedule = append(schedule, expressEmployee)
 
-	err = updateEmployeeInList(employees, &instancesEmployee)
-	if err != nil {
-		return "", nil, err
+	schedule := s.WeekSchedule(startDate, assignments)
+	schedule.Decisions = decisions
+	return schedule, nil
+}
+
+// recordPinned учитывает закрепленные назначения в счетчиках сотрудников.
+func (s *Scheduler) recordPinned(employees *[]Employee, pinned []Assignment) {
+	for _, assignment := range pinned {
+		i, _ := findEmployeeIndex(*employees, assignment.EmployeeId)
+		dutyType, _ := s.dutyType(assignment.Duty)
+		(*employees)[i].recordDuty(dutyType, assignment.Date)
+	}
+}
+
+// WeekSchedule собирает расписание недели weekStart из готовых назначений, например из истории.
+func (s *Scheduler) WeekSchedule(weekStart time.Time, assignments []Assignment) WeekSchedule {
+	return WeekSchedule{
+		Start:       weekStart,
+		End:         weekStart.AddDate(0, 0, 4),
+		Assignments: assignments,
+		Sections:    s.sections(weekStart, assignments),
 	}
-	instancesEmployee.SupportDutyCount = 0
-	instancesEmployee.ExpressDutyCount = 0
-	instancesEmployee.InstancesDutyCount = 1
-	schedule = append(schedule, instancesEmployee)
-
-	supportSchedule := ""
-	weekdays := [5]string{"понедельник", "вторник", "среда", "четверг", "пятница"}
-
-	// создаем копию списка сотрудников, чтобы не менять исходный
-	employeesCopy := make([]Employee, len(*employees))
-	copy(employeesCopy, *employees)
-
-	for dayInWeek, day := range weekdays {
-		supportEmployee, err := findEmployeeForSupport(&employeesCopy, dayInWeek)
-		if err != nil {
-			return "", nil, err
+}
+
+// sections группирует назначения по блокам DutyType.Section в порядке объявления дежурств.
+// Праздничные дни, в которые дежурство не назначалось или было перенесено, отмечаются в блоке.
+func (s *Scheduler) sections(weekStart time.Time, assignments []Assignment) []ScheduleSection {
+	var sections []ScheduleSection
+	index := map[string]int{}
+
+	for _, dutyType := range s.DutyTypes {
+		i, ok := index[dutyType.Section]
+		if !ok {
+			i = len(sections)
+			index[dutyType.Section] = i
+			sections = append(sections, ScheduleSection{Title: dutyType.Section})
+		}
+		section := &sections[i]
+
+		newLine := func(assignment Assignment) ScheduleLine {
+			return ScheduleLine{
+				EmployeeName: assignment.EmployeeName,
+				Duty:         dutyType.Name,
+				DutyTitle:    dutyType.Title,
+				Cadence:      dutyType.Cadence,
+				Date:         assignment.Date,
+				Weekday:      weekdaysRu[assignment.Date.Weekday()],
+			}
 		}
-		fmt.Println("---supportEmployee---")
-		fmt.Println(*employees)
-		fmt.Println(supportEmployee)
 
-		err = updateEmployeeInList(employees, &supportEmployee)
-		if err != nil {
-			return "", nil, err
+		switch dutyType.Cadence {
+		case CadenceDaily:
+			for _, date := range dutyType.dates(weekStart) {
+				if holiday, ok := s.Calendar.Holiday(date); ok {
+					section.Lines = append(section.Lines, ScheduleLine{
+						Duty:      dutyType.Name,
+						DutyTitle: dutyType.Title,
+						Cadence:   dutyType.Cadence,
+						Date:      date,
+						Weekday:   weekdaysRu[date.Weekday()],
+						DayOff:    true,
+						Holiday:   holiday.Name,
+					})
+					continue
+				}
+				for _, assignment := range assignments {
+					if assignment.Duty == dutyType.Name && assignment.Date.Equal(date) {
+						section.Lines = append(section.Lines, newLine(assignment))
+					}
+				}
+			}
+		case CadenceWeekly:
+			planned := dutyType.dates(weekStart)[0]
+			for _, assignment := range assignments {
+				if assignment.Duty == dutyType.Name {
+					line := newLine(assignment)
+					line.Moved = !assignment.Date.Equal(planned)
+					section.Lines = append(section.Lines, line)
+				}
+			}
+		default:
+			for _, assignment := range assignments {
+				if assignment.Duty == dutyType.Name {
+					section.Lines = append(section.Lines, newLine(assignment))
+				}
+			}
 		}
-		supportEmployee.SupportDutyCount = 1
-		supportEmployee.ExpressDutyCount = 0
-		supportEmployee.InstancesDutyCount = 0
-		schedule = append(schedule, supportEmployee)
-		supportSchedule += fmt.Sprintf("%s - %s\n", supportEmployee.Name, day)
 	}
 
-	result := fmt.Sprintf(
-		"Всем привет! 👾\n**Расписание для саппорт и релиз инженеров с %s по %s**\n**Релизы**\n%s - Express Release\n%s - Instances release\n\n**Саппорт**\n%s\n\nЛюбезно сгенерировано автоматически 🤖\nP.S. Если заметите аномалии, дайте знать - алгоритм требует донастройки 😉",
-		startDate.Format("2 January"), endDate.Format("2 January"),
-		expressEmployee.Name, instancesEmployee.Name, supportSchedule)
-
-	return result, &schedule, nil
+	return sections
 }
 
-// AllEmployees возвращает отформатированную строку со списком всех сотрудников, с их статусами и счетчиками дежурств.
-func AllEmployees(employees *[]Employee) string {
+// AllEmployees возвращает отформатированную строку со списком всех сотрудников, с их статусами на сегодня и счетчиками дежурств.
+func (s *Scheduler) AllEmployees(employees *[]Employee) string {
 	result := ""
+	today := s.now()
 
 	for _, employee := range *employees {
 		if employee.Status == StatusFired {
 			continue
 		}
 
-		result += fmt.Sprintf(
-			"%d. %s – %s | Support: %d | Instances release: %d | Express Release: %d\n",
-			employee.Id, employee.Name, employee.Status, employee.SupportDutyCount, employee.InstancesDutyCount, employee.ExpressDutyCount)
+		result += fmt.Sprintf("%d. %s – %s", employee.Id, employee.Name, employee.StatusOn(today))
+		for _, dutyType := range s.DutyTypes {
+			result += fmt.Sprintf(" | %s: %d", dutyType.Title, employee.Duties[dutyType.Name].Count)
+		}
+		if employee.Member != "" {
+			result += fmt.Sprintf(" | общий: %s", employee.Member)
+		}
+		result += "\n"
 	}
 
 	return result
@@ -0,0 +1,238 @@
+package pkg
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// testMonday - понедельник недели, на которую формируется расписание в тестах.
+var testMonday = time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+
+// testEmployees создает сотрудников с пустыми счетчиками: чем раньше имя в списке, тем давнее
+// их последнее дежурство, поэтому при равной нагрузке очередь идет по порядку имен.
+func testEmployees(names ...string) []Employee {
+	employees := make([]Employee, len(names))
+	for i, name := range names {
+		employees[i] = Employee{Id: i + 1, Name: name, Status: StatusAvailable, Duties: map[string]DutyStats{}}
+		for _, dutyType := range DefaultDutyTypes() {
+			employees[i].Duties[dutyType.Name] = DutyStats{LastDuty: testMonday.AddDate(0, 0, -60+i)}
+		}
+	}
+	return employees
+}
+
+// resetDutyTypes возвращает дежурства по умолчанию, у которых перечисленные счетчики сбрасываются
+// раз в 90 дней, а не считаются по окну справедливости.
+func resetDutyTypes(names ...string) []DutyType {
+	dutyTypes := DefaultDutyTypes()
+	for i := range dutyTypes {
+		for _, name := range names {
+			if dutyTypes[i].Name == name {
+				dutyTypes[i].ResetCounters = true
+			}
+		}
+	}
+	return dutyTypes
+}
+
+// assignmentsByDate возвращает назначения в виде "дежурство дата" -> имя сотрудника.
+func assignmentsByDate(assignments []Assignment) map[string]string {
+	result := map[string]string{}
+	for _, assignment := range assignments {
+		result[fmt.Sprintf("%s %s", assignment.Duty, assignment.Date.Format("2006-01-02"))] = assignment.EmployeeName
+	}
+	return result
+}
+
+func TestGetSchedule(t *testing.T) {
+	tests := []struct {
+		name     string
+		prepare  func(employees []Employee, storage *DutyHistoryStorage, scheduler *Scheduler)
+		expected map[string]string
+		rules    map[string]string // "дежурство дата" -> правило выбора, только проверяемые слоты
+	}{
+		{
+			name: "очередь по давности последнего дежурства",
+			expected: map[string]string{
+				"express 2026-10-22":   "Анна",
+				"instances 2026-10-22": "Борис",
+				"support 2026-10-19":   "Анна",
+				"support 2026-10-20":   "Борис",
+				"support 2026-10-21":   "Вера",
+				"support 2026-10-22":   "Глеб",
+				"support 2026-10-23":   "Дина",
+			},
+			rules: map[string]string{"support 2026-10-21": RuleLongestRest},
+		},
+		{
+			name: "отсутствующий сотрудник пропускается",
+			prepare: func(employees []Employee, storage *DutyHistoryStorage, scheduler *Scheduler) {
+				employees[0].Absences = []Absence{{Kind: StatusVacation, Start: testMonday.AddDate(0, 0, 2), End: testMonday.AddDate(0, 0, 2)}}
+			},
+			expected: map[string]string{
+				// на релиз не ставится тот, кто отсутствует хотя бы день недели
+				"express 2026-10-22":   "Борис",
+				"instances 2026-10-22": "Вера",
+				"support 2026-10-19":   "Анна",
+				"support 2026-10-20":   "Борис",
+				"support 2026-10-21":   "Вера",
+				"support 2026-10-22":   "Глеб",
+				"support 2026-10-23":   "Дина",
+			},
+		},
+		{
+			name: "релиз переносится с праздника на следующий рабочий день",
+			prepare: func(employees []Employee, storage *DutyHistoryStorage, scheduler *Scheduler) {
+				scheduler.Calendar = NewCalendar([]Holiday{{Date: testMonday.AddDate(0, 0, 3), Name: "Праздник"}})
+			},
+			expected: map[string]string{
+				"express 2026-10-23":   "Анна",
+				"instances 2026-10-23": "Борис",
+				"support 2026-10-19":   "Анна",
+				"support 2026-10-20":   "Борис",
+				"support 2026-10-21":   "Вера",
+				"support 2026-10-23":   "Глеб",
+			},
+		},
+		{
+			name: "без прошедшего перерыва берется сотрудник с наименьшей нагрузкой",
+			prepare: func(employees []Employee, storage *DutyHistoryStorage, scheduler *Scheduler) {
+				for i := range employees {
+					stats := employees[i].Duties["express"]
+					stats.LastDuty = testMonday.AddDate(0, 0, -7+i)
+					employees[i].Duties["express"] = stats
+				}
+				// Express месяц назад входит в окно справедливости
+				monthAgo := testMonday.AddDate(0, 0, -28)
+				storage.History = []DutyHistory{{Date: monthAgo, Status: HistoryConfirmed, Assignments: []Assignment{
+					{Duty: "express", Date: monthAgo.AddDate(0, 0, 3), EmployeeId: 1, EmployeeName: "Анна"},
+				}}}
+			},
+			expected: map[string]string{
+				"express 2026-10-22":   "Борис",
+				"instances 2026-10-22": "Анна",
+				"support 2026-10-19":   "Анна",
+				"support 2026-10-20":   "Борис",
+				"support 2026-10-21":   "Вера",
+				"support 2026-10-22":   "Глеб",
+				"support 2026-10-23":   "Дина",
+			},
+			rules: map[string]string{"express 2026-10-22": RuleCooldownFallback},
+		},
+		{
+			name: "у дежурства со сбросом нагрузка - счетчик",
+			prepare: func(employees []Employee, storage *DutyHistoryStorage, scheduler *Scheduler) {
+				scheduler.DutyTypes = resetDutyTypes("express", "instances")
+				for i := range employees {
+					employees[i].Duties["express"] = DutyStats{LastDuty: testMonday.AddDate(0, 0, -7+i)}
+				}
+				employees[0].Duties["express"] = DutyStats{Count: 2, LastDuty: testMonday.AddDate(0, 0, -7)}
+			},
+			expected: map[string]string{
+				"express 2026-10-22":   "Борис",
+				"instances 2026-10-22": "Анна",
+				"support 2026-10-19":   "Анна",
+				"support 2026-10-20":   "Борис",
+				"support 2026-10-21":   "Вера",
+				"support 2026-10-22":   "Глеб",
+				"support 2026-10-23":   "Дина",
+			},
+			rules: map[string]string{"express 2026-10-22": RuleCooldownFallback},
+		},
+		{
+			name: "закрепленный на Instances не назначается на Express",
+			prepare: func(employees []Employee, storage *DutyHistoryStorage, scheduler *Scheduler) {
+				// перерыв не прошел ни у кого, а наименьшая нагрузка по Express - у закрепленного
+				for i := 1; i < len(employees); i++ {
+					employees[i].Duties["express"] = DutyStats{Count: 2, LastDuty: testMonday.AddDate(0, 0, -3)}
+				}
+				storage.Pins = []Pin{{Week: testMonday, Duty: "instances", Date: testMonday.AddDate(0, 0, 3), EmployeeId: 1}}
+			},
+			expected: map[string]string{
+				"express 2026-10-22":   "Борис",
+				"instances 2026-10-22": "Анна",
+				"support 2026-10-19":   "Анна",
+				"support 2026-10-20":   "Борис",
+				"support 2026-10-21":   "Вера",
+				"support 2026-10-22":   "Глеб",
+				"support 2026-10-23":   "Дина",
+			},
+			rules: map[string]string{"express 2026-10-22": RuleCooldownFallback, "instances 2026-10-22": RulePinned},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			employees := testEmployees("Анна", "Борис", "Вера", "Глеб", "Дина", "Ефим")
+			storage := &DutyHistoryStorage{}
+			scheduler := NewScheduler(FixedClock(testMonday), nil)
+			if tt.prepare != nil {
+				tt.prepare(employees, storage, scheduler)
+			}
+
+			schedule, err := scheduler.GetSchedule(&employees, storage)
+			if err != nil {
+				t.Fatalf("GetSchedule: %v", err)
+			}
+
+			got := assignmentsByDate(schedule.Assignments)
+			if len(got) != len(tt.expected) {
+				t.Errorf("назначений %d, ожидалось %d: %v", len(got), len(tt.expected), got)
+			}
+			for slot, name := range tt.expected {
+				if got[slot] != name {
+					t.Errorf("%s: назначен %q, ожидался %q", slot, got[slot], name)
+				}
+			}
+			for _, decision := range schedule.Decisions {
+				slot := fmt.Sprintf("%s %s", decision.Duty, decision.Date.Format("2006-01-02"))
+				if rule, ok := tt.rules[slot]; ok && decision.Rule != rule {
+					t.Errorf("%s: правило %q, ожидалось %q", slot, decision.Rule, rule)
+				}
+			}
+		})
+	}
+}
+
+func TestNextMonday(t *testing.T) {
+	tests := []struct {
+		now      time.Time
+		expected time.Time
+	}{
+		{now: testMonday, expected: testMonday},
+		{now: testMonday.Add(15 * time.Hour), expected: testMonday},
+		{now: testMonday.AddDate(0, 0, 1), expected: testMonday.AddDate(0, 0, 7)},
+		{now: testMonday.AddDate(0, 0, 6).Add(23 * time.Hour), expected: testMonday.AddDate(0, 0, 7)},
+		// 01:00 понедельника по Москве - еще воскресенье по UTC, но неделя уже новая
+		{now: time.Date(2026, 10, 19, 1, 0, 0, 0, time.FixedZone("MSK", 3*60*60)), expected: testMonday},
+		// 23:00 воскресенья в Нью-Йорке - уже понедельник по UTC
+		{now: time.Date(2026, 10, 18, 23, 0, 0, 0, time.FixedZone("EDT", -4*60*60)), expected: testMonday},
+	}
+
+	for _, tt := range tests {
+		if got := NewScheduler(FixedClock(tt.now), nil).nextMonday(); !got.Equal(tt.expected) {
+			t.Errorf("%s: следующий понедельник %s, ожидался %s", tt.now, got, tt.expected)
+		}
+	}
+}
+
+func TestWeekStart(t *testing.T) {
+	msk := time.FixedZone("MSK", 3*60*60)
+	tests := []struct {
+		date     time.Time
+		expected time.Time
+	}{
+		{date: testMonday, expected: testMonday},
+		{date: testMonday.AddDate(0, 0, 6), expected: testMonday},
+		{date: time.Date(2026, 10, 19, 1, 0, 0, 0, msk), expected: testMonday},
+		{date: time.Date(2026, 10, 25, 23, 30, 0, 0, msk), expected: testMonday},
+		{date: time.Date(2026, 10, 26, 0, 30, 0, 0, msk), expected: testMonday.AddDate(0, 0, 7)},
+	}
+
+	for _, tt := range tests {
+		if got := WeekStart(tt.date); !got.Equal(tt.expected) {
+			t.Errorf("%s: начало недели %s, ожидалось %s", tt.date, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package pkg
+
+import (
+	"fmt"
+	"sync"
+)
+
+// State - данные, загруженные для одной операции сервиса.
+type State struct {
+	Employees *[]Employee
+	History   *DutyHistoryStorage
+}
+
+// Service выполняет операции над данными в долгоживущих режимах (бот, сервер): каждая операция
+// заново загружает сотрудников и историю, а изменяющая - еще и блокирует каталог данных
+// на время загрузки и сохранения. Поэтому параллельно с ними можно запускать команды CLI.
+type Service struct {
+	Repo    Repository
+	Config  *Config
+	DataDir string // каталог, который блокируется на время изменений; пусто - без блокировки
+	Teams   *Teams // команды установки для проверки общих сотрудников; nil - команда одна
+	Clock   Clock  // время событий журнала
+
+	mu sync.Mutex // упорядочивает изменения внутри процесса
+}
+
+// NewService создает сервис поверх открытого хранилища.
+func NewService(repo Repository, config *Config, dataDir string) *Service {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	return &Service{Repo: repo, Config: config, DataDir: dataDir, Clock: SystemClock{}}
+}
+
+// Scheduler создает планировщик по настройкам сервиса.
+func (s *Service) Scheduler(clock Clock) *Scheduler {
+	scheduler := s.Config.NewScheduler(clock)
+	scheduler.Teams = s.Teams
+	return scheduler
+}
+
+func (s *Service) load() (*State, error) {
+	employees, err := s.Repo.LoadEmployees()
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.Repo.LoadDutyHistory()
+	if err != nil {
+		return nil, fmt.Errorf("при попытке загрузить историю дежурств произошла ошибка: %w", err)
+	}
+	return &State{Employees: employees, History: history}, nil
+}
+
+// View загружает данные и передает их fn. Изменения, сделанные fn, не сохраняются.
+func (s *Service) View(fn func(state *State) error) error {
+	state, err := s.load()
+	if err != nil {
+		return err
+	}
+	return fn(state)
+}
+
+// Update загружает данные под блокировкой, передает их fn и, если fn не вернула ошибку,
+// сохраняет сотрудников и историю вместе с записью сделанных изменений в журнал от имени actor.
+func (s *Service) Update(actor Actor, fn func(state *State) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.DataDir != "" {
+		lock, err := LockDataDir(s.DataDir)
+		if err != nil {
+			return err
+		}
+		defer lock.Unlock()
+	}
+
+	state, err := s.load()
+	if err != nil {
+		return err
+	}
+	before, err := CloneState(state)
+	if err != nil {
+		return err
+	}
+	if err := fn(state); err != nil {
+		return err
+	}
+
+	return s.Repo.Save(state, DiffEvents(before, state, actor, s.Clock))
+}
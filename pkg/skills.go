@@ -0,0 +1,237 @@
+package pkg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ShadowShift - смена стажера рядом с дежурным: сотрудник без навыков дежурства учится на нем,
+// но дежурным не считается и в счетчики не попадает.
+type ShadowShift struct {
+	Duty         string    `json:"duty"` // DutyType.Name
+	Date         time.Time `json:"date"`
+	EmployeeId   int       `json:"employee_id"`
+	EmployeeName string    `json:"employee_name"`
+	Shift        int       `json:"shift"`              // номер смены стажера на этом дежурстве
+	Promoted     []string  `json:"promoted,omitempty"` // навыки, полученные после этой смены
+}
+
+// missingSkills возвращает навыки дежурства, которых нет у сотрудника.
+func (e Employee) missingSkills(dutyType DutyType) []string {
+	var missing []string
+	for _, required := range dutyType.RequiredSkills {
+		if !e.hasSkill(required) {
+			missing = append(missing, required)
+		}
+	}
+	return missing
+}
+
+func (e Employee) hasSkill(skill string) bool {
+	for _, own := range e.Skills {
+		if own == skill {
+			return true
+		}
+	}
+	return false
+}
+
+// assignShadows ставит стажеров рядом с дежурными недели weekStart: на каждый день дежурства
+// с ShadowShifts одного сотрудника без его навыков, который свободен в этот день. Первыми идут
+// те, кто отстоял больше смен, чтобы быстрее получить допуск. Смены записываются в счетчики
+// стажеров, набравший ShadowShifts смен получает навыки дежурства.
+func (s *Scheduler) assignShadows(employees *[]Employee, history []DutyHistory, assignments []Assignment, weekStart time.Time) []ShadowShift {
+	var shadows []ShadowShift
+	for _, dutyType := range s.DutyTypes {
+		if dutyType.ShadowShifts == 0 {
+			continue
+		}
+		for _, date := range s.dutyDates(dutyType, weekStart) {
+			staffed := false
+			for _, assignment := range assignments {
+				if assignment.Duty == dutyType.Name && assignment.Date.Equal(date) {
+					staffed = true
+				}
+			}
+			if !staffed {
+				continue
+			}
+
+			i, ok := s.findShadow(*employees, s.newSlot(dutyType, date, weekStart), history, assignments, shadows)
+			if !ok {
+				continue
+			}
+			employee := &(*employees)[i]
+			shadows = append(shadows, employee.recordShadow(dutyType, date))
+		}
+	}
+	return shadows
+}
+
+// findShadow подбирает стажера на слот и возвращает его индекс в employees.
+func (s *Scheduler) findShadow(employees []Employee, sl slot, history []DutyHistory, assigned []Assignment, shadows []ShadowShift) (int, bool) {
+	name := sl.dutyType.Name
+	var order []int
+	for i, employee := range employees {
+		if employee.Status == StatusFired || len(employee.missingSkills(sl.dutyType)) == 0 {
+			continue
+		}
+		if reason, _ := s.exclusion(sl, employee, history, assigned); reason != "" {
+			continue
+		}
+		busy := false
+		for _, assignment := range assigned {
+			busy = busy || (assignment.EmployeeId == employee.Id && assignment.Date.Equal(sl.date))
+		}
+		for _, shadow := range shadows {
+			busy = busy || (shadow.EmployeeId == employee.Id && shadow.Date.Equal(sl.date))
+		}
+		if !busy {
+			order = append(order, i)
+		}
+	}
+	if len(order) == 0 {
+		return -1, false
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return employees[order[i]].ShadowShifts[name] > employees[order[j]].ShadowShifts[name]
+	})
+	return order[0], true
+}
+
+// recordShadow засчитывает сотруднику смену стажером и, если смен набралось достаточно, дает ему навыки дежурства.
+func (e *Employee) recordShadow(dutyType DutyType, date time.Time) ShadowShift {
+	if e.ShadowShifts == nil {
+		e.ShadowShifts = map[string]int{}
+	}
+	e.ShadowShifts[dutyType.Name]++
+	shadow := ShadowShift{
+		Duty:         dutyType.Name,
+		Date:         date,
+		EmployeeId:   e.Id,
+		EmployeeName: e.Name,
+		Shift:        e.ShadowShifts[dutyType.Name],
+	}
+	if shadow.Shift >= dutyType.ShadowShifts {
+		shadow.Promoted = e.missingSkills(dutyType)
+		e.Skills = append(e.Skills, shadow.Promoted...)
+		delete(e.ShadowShifts, dutyType.Name)
+		if len(e.ShadowShifts) == 0 {
+			e.ShadowShifts = nil
+		}
+	}
+	return shadow
+}
+
+// discardShadows откатывает смены стажеров удаленной недели: уменьшает их счетчики и забирает
+// навыки, полученные после этих смен.
+func (s *Scheduler) discardShadows(employees *[]Employee, shadows []ShadowShift) {
+	for n := len(shadows) - 1; n >= 0; n-- {
+		shadow := shadows[n]
+		employee := FindEmployeeById(employees, shadow.EmployeeId)
+		if employee == nil {
+			continue
+		}
+		if len(shadow.Promoted) > 0 {
+			skills := employee.Skills[:0]
+			for _, skill := range employee.Skills {
+				if !containsString(shadow.Promoted, skill) {
+					skills = append(skills, skill)
+				}
+			}
+			employee.Skills = skills
+			if len(skills) == 0 {
+				employee.Skills = nil
+			}
+		}
+
+		if shadow.Shift <= 1 {
+			delete(employee.ShadowShifts, shadow.Duty)
+		} else {
+			if employee.ShadowShifts == nil {
+				employee.ShadowShifts = map[string]int{}
+			}
+			employee.ShadowShifts[shadow.Duty] = shadow.Shift - 1
+		}
+		if len(employee.ShadowShifts) == 0 {
+			employee.ShadowShifts = nil
+		}
+	}
+}
+
+// SetEmployeeSkills записывает навыки сотрудника с Id id. Счетчики смен стажером по дежурствам,
+// к которым сотрудник теперь допущен, удаляются.
+func (s *Scheduler) SetEmployeeSkills(employees *[]Employee, id int, skills []string) error {
+	employee := FindEmployeeById(employees, id)
+	if employee == nil {
+		return fmt.Errorf("сотрудник с Id: %d не найден", id)
+	}
+
+	var unique []string
+	for _, skill := range skills {
+		skill = strings.TrimSpace(skill)
+		if skill == "" {
+			return fmt.Errorf("название навыка не может быть пустым")
+		}
+		if !containsString(unique, skill) {
+			unique = append(unique, skill)
+		}
+	}
+	employee.Skills = unique
+
+	for duty := range employee.ShadowShifts {
+		if dutyType, ok := s.dutyType(duty); !ok || len(employee.missingSkills(dutyType)) == 0 {
+			delete(employee.ShadowShifts, duty)
+		}
+	}
+	if len(employee.ShadowShifts) == 0 {
+		employee.ShadowShifts = nil
+	}
+	return nil
+}
+
+// FormatSkills возвращает навыки сотрудника и его смены стажером в виде строки для вывода.
+func (s *Scheduler) FormatSkills(employee Employee) string {
+	parts := []string{"навыков нет"}
+	if len(employee.Skills) > 0 {
+		parts[0] = "навыки: " + strings.Join(employee.Skills, ", ")
+	}
+	for _, dutyType := range s.DutyTypes {
+		missing := employee.missingSkills(dutyType)
+		if len(missing) == 0 {
+			continue
+		}
+		part := fmt.Sprintf("%s: не допущен (нет %s)", dutyType.Title, strings.Join(missing, ", "))
+		if dutyType.ShadowShifts > 0 {
+			part += fmt.Sprintf(", смен стажером %d из %d", employee.ShadowShifts[dutyType.Name], dutyType.ShadowShifts)
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// HistorySchedule собирает расписание недели из истории вместе со стажерами.
+func (s *Scheduler) HistorySchedule(record DutyHistory) WeekSchedule {
+	schedule := s.WeekSchedule(record.Date, record.Assignments)
+	schedule.addShadows(record.Shadows)
+	return schedule
+}
+
+// addShadows добавляет стажеров в расписание и в строки дежурств, рядом с которыми они стоят.
+func (schedule *WeekSchedule) addShadows(shadows []ShadowShift) {
+	schedule.Shadows = shadows
+	for _, shadow := range shadows {
+		for i := range schedule.Sections {
+			lines := schedule.Sections[i].Lines
+			for j := range lines {
+				if !lines[j].DayOff && lines[j].Duty == shadow.Duty && lines[j].Date.Equal(shadow.Date) && lines[j].Shadow == "" {
+					lines[j].Shadow = shadow.EmployeeName
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,607 @@
+package pkg
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Движки подбора дежурных (SolverConfig.Engine).
+const (
+	SolverGreedy   = "greedy"   // слоты по очереди: каждому - подходящий сотрудник с наименьшей нагрузкой
+	SolverOptimize = "optimize" // неделя целиком: локальный поиск с жесткими ограничениями и штрафами
+)
+
+// SolverConfig - раздел solver в config.json: каким движком подбирать дежурных и настройки оптимизатора.
+type SolverConfig struct {
+	Engine string `json:"engine"` // SolverGreedy (по умолчанию) или SolverOptimize
+	// MaxDutiesPerWeek - сколько дежурств сотрудник может получить за неделю; 0 - без ограничения.
+	MaxDutiesPerWeek int            `json:"max_duties_per_week"`
+	Iterations       int            `json:"iterations"` // сколько раз встряхивать найденное решение
+	Weights          PenaltyWeights `json:"weights"`
+}
+
+// PenaltyWeights - веса мягких штрафов оптимизатора.
+type PenaltyWeights struct {
+	Fairness   float64 `json:"fairness"`     // за сумму квадратов отклонений нагрузки от средней
+	BackToBack float64 `json:"back_to_back"` // за дежурство одной группы перерыва две недели подряд
+	Preference float64 `json:"preference"`   // за нарушенное пожелание
+}
+
+// DefaultSolverConfig возвращает настройки по умолчанию: жадный подбор, для оптимизатора - веса штрафов,
+// при которых дежурство две недели подряд и нарушенное пожелание хуже небольшой неравномерности.
+func DefaultSolverConfig() SolverConfig {
+	return SolverConfig{
+		Engine:     SolverGreedy,
+		Iterations: 50,
+		Weights:    PenaltyWeights{Fairness: 1, BackToBack: 10, Preference: 20},
+	}
+}
+
+// Validate проверяет раздел solver.
+func (c SolverConfig) Validate() error {
+	switch c.Engine {
+	case "", SolverGreedy, SolverOptimize:
+	default:
+		return fmt.Errorf("solver.engine: неизвестный движок %q (greedy, optimize)", c.Engine)
+	}
+	if c.MaxDutiesPerWeek < 0 || c.Iterations < 0 {
+		return fmt.Errorf("solver: max_duties_per_week и iterations не могут быть отрицательными")
+	}
+	if c.Weights.Fairness < 0 || c.Weights.BackToBack < 0 || c.Weights.Preference < 0 {
+		return fmt.Errorf("solver.weights: веса штрафов не могут быть отрицательными")
+	}
+	return nil
+}
+
+// WeekScore - оценка расписания недели: нарушения жестких ограничений, назначения в обход перерыва
+// и мягкие штрафы.
+type WeekScore struct {
+	Hard       int      `json:"hard"`                 // число нарушений жестких ограничений
+	Violations []string `json:"violations,omitempty"` // описания нарушений
+	// Cooldown - назначений, у которых не прошел перерыв. Как и в жадном подборе, перерыв уступает,
+	// только если иначе слот не заполнить, поэтому такие назначения не нарушение, но дороже мягких штрафов.
+	Cooldown      int      `json:"cooldown"`
+	CooldownBreak []string `json:"cooldown_break,omitempty"` // описания назначений в обход перерыва
+	Fairness      float64  `json:"fairness"`                 // сумма квадратов отклонений нагрузки от средней по дежурствам
+	BackToBack    int      `json:"back_to_back"`             // дежурств одной группы перерыва две недели подряд
+	Preference    int      `json:"preference"`               // нарушенных пожеланий
+	Penalty       float64  `json:"penalty"`                  // взвешенная сумма мягких штрафов
+}
+
+const (
+	// hardPenalty - цена одного нарушения жесткого ограничения: больше всех остальных штрафов.
+	hardPenalty = 1e9
+	// cooldownPenalty - цена назначения в обход перерыва: больше любых мягких штрафов.
+	cooldownPenalty = 1e6
+)
+
+func (score WeekScore) cost() float64 {
+	return float64(score.Hard)*hardPenalty + float64(score.Cooldown)*cooldownPenalty + score.Penalty
+}
+
+// placed - назначение недели в задаче оптимизатора.
+type placed struct {
+	employee int // индекс в weekProblem.employees
+	duty     int // индекс в Scheduler.DutyTypes
+	sl       slot
+	fixed    bool // закрепление, оптимизатор его не меняет
+}
+
+// weekProblem - задача подбора дежурных на неделю целиком.
+type weekProblem struct {
+	s         *Scheduler
+	employees []Employee
+	weekStart time.Time
+	slots     []slot   // свободные слоты; решение - индекс сотрудника на каждый
+	fixed     []placed // закрепления
+	active    []int    // индексы неуволенных сотрудников: кандидаты на каждый слот
+	base      [][]float64
+	// prior - последнее дежурство сотрудника в группе перерыва до этой недели
+	prior []map[string]time.Time
+	// previous - группы перерыва, в которых сотрудник дежурил на прошлой неделе
+	previous []map[string]bool
+	// monthly - дежурства сотрудника до этой недели по месяцам (monthKey), для Preferences.MaxPerMonth
+	monthly []map[int]int
+}
+
+// monthKey возвращает номер календарного месяца дня date.
+func monthKey(date time.Time) int {
+	return date.Year()*12 + int(date.Month()) - 1
+}
+
+// group возвращает ключ группы перерыва дежурства.
+func (d DutyType) group() string {
+	if d.CooldownGroup != "" {
+		return d.CooldownGroup
+	}
+	return d.Name
+}
+
+// newWeekProblem собирает задачу на неделю weekStart. Закрепления pinned уже должны быть учтены
+// в счетчиках employees, как это делает GetSchedule.
+func (s *Scheduler) newWeekProblem(employees []Employee, storage *DutyHistoryStorage, pinned []Assignment, weekStart time.Time) *weekProblem {
+	p := &weekProblem{s: s, employees: employees, weekStart: weekStart}
+	dutyIndex := map[string]int{}
+	for d, dutyType := range s.DutyTypes {
+		dutyIndex[dutyType.Name] = d
+		p.base = append(p.base, s.dutyLoads(employees, dutyType, storage.History, pinned, weekStart))
+	}
+
+	for _, dutyType := range s.DutyTypes {
+		for _, date := range s.dutyDates(dutyType, weekStart) {
+			sl := s.newSlot(dutyType, date, weekStart)
+			free := dutyType.Slots
+			for _, assignment := range pinned {
+				if assignment.Duty == dutyType.Name && assignment.Date.Equal(date) {
+					i, _ := findEmployeeIndex(employees, assignment.EmployeeId)
+					p.fixed = append(p.fixed, placed{employee: i, duty: dutyIndex[dutyType.Name], sl: sl, fixed: true})
+					free--
+				}
+			}
+			for n := 0; n < free; n++ {
+				p.slots = append(p.slots, sl)
+			}
+		}
+	}
+
+	previousWeek, hasPrevious := storage.FindWeek(weekStart.AddDate(0, 0, -7))
+	for i, employee := range employees {
+		if employee.Status != StatusFired {
+			p.active = append(p.active, i)
+		}
+
+		prior := map[string]time.Time{}
+		for _, dutyType := range s.DutyTypes {
+			// даты из счетчиков нужны для новичков, у которых истории нет; даты этой недели
+			// туда уже попали из закреплений, их оптимизатор учитывает сам
+			if last := employee.Duties[dutyType.Name].LastDuty; last.Before(weekStart) && last.After(prior[dutyType.group()]) {
+				prior[dutyType.group()] = last
+			}
+		}
+		for _, record := range storage.History {
+			if !record.Date.Before(weekStart) {
+				continue
+			}
+			for _, assignment := range record.Assignments {
+				d, ok := dutyIndex[assignment.Duty]
+				if ok && assignment.EmployeeId == employee.Id && assignment.Date.After(prior[s.DutyTypes[d].group()]) {
+					prior[s.DutyTypes[d].group()] = assignment.Date
+				}
+			}
+		}
+		p.prior = append(p.prior, prior)
+
+		previous := map[string]bool{}
+		if hasPrevious {
+			for _, assignment := range previousWeek.Assignments {
+				if d, ok := dutyIndex[assignment.Duty]; ok && assignment.EmployeeId == employee.Id {
+					previous[s.DutyTypes[d].group()] = true
+				}
+			}
+		}
+		p.previous = append(p.previous, previous)
+
+		monthly := map[int]int{}
+		if employee.Preferences != nil && employee.Preferences.MaxPerMonth > 0 {
+			for _, record := range storage.History {
+				if !record.Date.Before(weekStart) {
+					continue
+				}
+				for _, assignment := range record.Assignments {
+					if assignment.EmployeeId == employee.Id {
+						monthly[monthKey(assignment.Date)]++
+					}
+				}
+			}
+		}
+		p.monthly = append(p.monthly, monthly)
+	}
+	return p
+}
+
+// evaluate оценивает решение: solution[k] - индекс сотрудника на слот k, -1 - слот еще не заполнен.
+// С verbose в оценку попадают описания нарушений.
+func (p *weekProblem) evaluate(solution []int, verbose bool) WeekScore {
+	var score WeekScore
+	violate := func(format string, args ...interface{}) {
+		score.Hard++
+		if verbose {
+			score.Violations = append(score.Violations, fmt.Sprintf(format, args...))
+		}
+	}
+
+	all := append([]placed(nil), p.fixed...)
+	for k, i := range solution {
+		if i < 0 {
+			continue
+		}
+		sl := p.slots[k]
+		d := 0
+		for n, dutyType := range p.s.DutyTypes {
+			if dutyType.Name == sl.dutyType.Name {
+				d = n
+			}
+		}
+		all = append(all, placed{employee: i, duty: d, sl: sl})
+	}
+
+	perEmployee := make([]int, len(p.employees))
+	type employeeMonth struct{ employee, month int }
+	perMonth := map[employeeMonth]int{}
+	for x, a := range all {
+		employee := p.employees[a.employee]
+		dutyType := p.s.DutyTypes[a.duty]
+		perEmployee[a.employee]++
+		if employee.Preferences != nil && employee.Preferences.MaxPerMonth > 0 {
+			month := employeeMonth{a.employee, monthKey(a.sl.date)}
+			perMonth[month]++
+			if !a.fixed && p.monthly[a.employee][month.month]+perMonth[month] > employee.Preferences.MaxPerMonth {
+				violate("у %s больше %d дежурств в месяце %s", employee.Name, employee.Preferences.MaxPerMonth, a.sl.date.Format("2006-01"))
+			}
+		}
+
+		if !a.fixed {
+			if employee.Status == StatusFired {
+				violate("%s уволен", employee.Name)
+			}
+			if absence, ok := employee.AbsenceDuring(a.sl.from, a.sl.to); ok {
+				violate("%s отсутствует (%s) на %s %s", employee.Name, absence.Kind, dutyType.Title, a.sl.date.Format("2006-01-02"))
+			}
+			if missing := employee.missingSkills(dutyType); len(missing) > 0 {
+				violate("%s не допущен к %s (нет навыков: %s)", employee.Name, dutyType.Title, strings.Join(missing, ", "))
+			}
+			if duty, ok := p.s.teamDuty(employee, a.sl.date); ok {
+				violate("%s дежурит %s в команде %s (%s)", employee.Name, a.sl.date.Format("2006-01-02"), TeamTitle(duty.Team), duty.Duty)
+			}
+			if employee.blackout(a.sl.date) {
+				violate("%s не дежурит по дням %s (%s %s)", employee.Name, weekdaysRu[a.sl.date.Weekday()], dutyType.Title, a.sl.date.Format("2006-01-02"))
+			}
+		}
+
+		// перерыв с прошлого дежурства группы: до этой недели или раньше на этой неделе
+		last := p.prior[a.employee][dutyType.group()]
+		lastFixed := true
+		for y, b := range all {
+			if y != x && b.employee == a.employee && p.s.DutyTypes[b.duty].group() == dutyType.group() &&
+				b.sl.date.Before(a.sl.date) && b.sl.date.After(last) {
+				last, lastFixed = b.sl.date, b.fixed
+			}
+		}
+		if !(a.fixed && lastFixed) && dutyType.inCooldown(last, a.sl.date) {
+			score.Cooldown++
+			if verbose {
+				score.CooldownBreak = append(score.CooldownBreak, fmt.Sprintf("у %s не прошел перерыв перед %s %s (прошлое дежурство %s)",
+					employee.Name, dutyType.Title, a.sl.date.Format("2006-01-02"), last.Format("2006-01-02")))
+			}
+		}
+
+		for y := x + 1; y < len(all); y++ {
+			b := all[y]
+			if b.employee != a.employee || (a.fixed && b.fixed) {
+				continue
+			}
+			other := p.s.DutyTypes[b.duty]
+			if b.sl.date.Equal(a.sl.date) {
+				violate("%s дважды назначен на %s", employee.Name, a.sl.date.Format("2006-01-02"))
+			} else if dutyType.excludes(other.Name) || other.excludes(dutyType.Name) {
+				violate("%s назначен на %s и %s на одной неделе", employee.Name, dutyType.Title, other.Title)
+			}
+		}
+
+		if p.previous[a.employee][dutyType.group()] {
+			score.BackToBack++
+		}
+		if !a.fixed && employee.preference(dutyType.Name, a.sl.date) == preferenceAvoided {
+			score.Preference++
+		}
+	}
+
+	if max := p.s.Solver.MaxDutiesPerWeek; max > 0 {
+		for i, n := range perEmployee {
+			if n > max {
+				violate("у %s %d дежурств на неделе, можно не больше %d", p.employees[i].Name, n, max)
+			}
+		}
+	}
+
+	// неравномерность: сумма квадратов отклонений нагрузки от средней по каждому дежурству
+	for d, dutyType := range p.s.DutyTypes {
+		loads := make([]float64, len(p.employees))
+		copy(loads, p.base[d])
+		for k, i := range solution {
+			if i >= 0 && p.slots[k].dutyType.Name == dutyType.Name {
+				loads[i] += float64(dutyType.Weight)
+			}
+		}
+		if len(p.active) == 0 {
+			continue
+		}
+		mean := 0.0
+		for _, i := range p.active {
+			mean += loads[i]
+		}
+		mean /= float64(len(p.active))
+		for _, i := range p.active {
+			score.Fairness += (loads[i] - mean) * (loads[i] - mean)
+		}
+	}
+
+	weights := p.s.Solver.Weights
+	score.Penalty = weights.Fairness*score.Fairness + weights.BackToBack*float64(score.BackToBack) + weights.Preference*float64(score.Preference)
+	return score
+}
+
+// solve подбирает сотрудников на свободные слоты: жадно строит начальное решение, улучшает его
+// спуском по заменам и обменам дежурных, затем Iterations раз встряхивает лучшее решение и спускается
+// снова. Встряски детерминированы, поэтому на одних данных результат всегда одинаковый.
+func (p *weekProblem) solve() ([]int, WeekScore) {
+	solution := make([]int, len(p.slots))
+	for k := range solution {
+		solution[k] = -1
+	}
+	if len(p.active) == 0 {
+		return solution, p.evaluate(solution, true)
+	}
+	for k := range p.slots {
+		best, bestCost := -1, 0.0
+		for _, i := range p.active {
+			solution[k] = i
+			if cost := p.evaluate(solution, false).cost(); best < 0 || cost < bestCost {
+				best, bestCost = i, cost
+			}
+		}
+		solution[k] = best
+	}
+
+	best := p.descend(solution)
+	bestCost := p.evaluate(best, false).cost()
+	random := rand.New(rand.NewSource(1))
+	for n := 0; n < p.s.Solver.Iterations && bestCost > 0 && len(p.slots) > 0; n++ {
+		candidate := append([]int(nil), best...)
+		for m := 0; m < 2; m++ {
+			candidate[random.Intn(len(candidate))] = p.active[random.Intn(len(p.active))]
+		}
+		candidate = p.descend(candidate)
+		if cost := p.evaluate(candidate, false).cost(); cost < bestCost {
+			best, bestCost = candidate, cost
+		}
+	}
+	return best, p.evaluate(best, true)
+}
+
+// descend улучшает решение, пока лучшая замена сотрудника на слоте или обмен двух слотов снижают штраф.
+func (p *weekProblem) descend(solution []int) []int {
+	current := append([]int(nil), solution...)
+	currentCost := p.evaluate(current, false).cost()
+	for {
+		bestCost := currentCost
+		var apply func()
+		for k := range current {
+			for _, i := range p.active {
+				if i == current[k] {
+					continue
+				}
+				previous := current[k]
+				current[k] = i
+				if cost := p.evaluate(current, false).cost(); cost < bestCost-1e-9 {
+					k, i := k, i
+					bestCost, apply = cost, func() { current[k] = i }
+				}
+				current[k] = previous
+			}
+			for l := k + 1; l < len(current); l++ {
+				if current[k] == current[l] {
+					continue
+				}
+				current[k], current[l] = current[l], current[k]
+				if cost := p.evaluate(current, false).cost(); cost < bestCost-1e-9 {
+					k, l := k, l
+					bestCost, apply = cost, func() { current[k], current[l] = current[l], current[k] }
+				}
+				current[k], current[l] = current[l], current[k]
+			}
+		}
+		if apply == nil {
+			return current
+		}
+		apply()
+		currentCost = bestCost
+	}
+}
+
+// optimizeWeek подбирает дежурных на неделю weekStart оптимизатором и собирает расписание
+// в том же порядке, что и жадный подбор. Закрепления pinned уже учтены в счетчиках employees.
+func (s *Scheduler) optimizeWeek(employees *[]Employee, storage *DutyHistoryStorage, pinned []Assignment, weekStart time.Time) (WeekSchedule, error) {
+	p := s.newWeekProblem(*employees, storage, pinned, weekStart)
+	solution, score := p.solve()
+	if score.Hard > 0 {
+		return WeekSchedule{}, fmt.Errorf("оптимизатор не нашел расписание без нарушения жестких ограничений: %s", strings.Join(score.Violations, "; "))
+	}
+
+	var assignments []Assignment
+	var decisions []SlotDecision
+	k := 0
+	for d, dutyType := range s.DutyTypes {
+		for _, date := range s.dutyDates(dutyType, weekStart) {
+			for _, assignment := range pinned {
+				if assignment.Duty == dutyType.Name && assignment.Date.Equal(date) {
+					assignments = append(assignments, assignment)
+					decisions = append(decisions, pinnedDecision(assignment))
+				}
+			}
+			for ; k < len(p.slots) && p.slots[k].dutyType.Name == dutyType.Name && p.slots[k].date.Equal(date); k++ {
+				employee := &(*employees)[solution[k]]
+				decisions = append(decisions, SlotDecision{
+					Duty:         dutyType.Name,
+					Date:         date,
+					EmployeeId:   employee.Id,
+					EmployeeName: employee.Name,
+					Rule:         RuleOptimized,
+					Candidates: []Candidate{{
+						EmployeeId:   employee.Id,
+						EmployeeName: employee.Name,
+						Count:        employee.Duties[dutyType.Name].Count,
+						Load:         p.base[d][solution[k]],
+						LastDuty:     employee.lastDuty(s.cooldownGroup(dutyType)),
+						Preference:   preferenceNames[employee.preference(dutyType.Name, date)],
+						Chosen:       true,
+					}},
+				})
+				employee.recordDuty(dutyType, date)
+				assignments = append(assignments, Assignment{
+					Duty:         dutyType.Name,
+					Date:         date,
+					EmployeeId:   employee.Id,
+					EmployeeName: employee.Name,
+				})
+			}
+		}
+	}
+
+	for n := range decisions {
+		if decisions[n].Rule == RuleOptimized {
+			decisions[n].Overridden = s.optimizedOverrides(*employees, decisions[n], storage.History, assignments, weekStart)
+		}
+	}
+
+	schedule := s.WeekSchedule(weekStart, assignments)
+	schedule.Decisions = decisions
+	return schedule, nil
+}
+
+// optimizedOverrides возвращает пожелания, которые оптимизатор не учел в слоте decision: выбранный
+// просил избегать дежурства или дня, или предпочитавший дежурство сотрудник не назначен. Для него
+// указывается причина, по которой его нельзя было назначить, если она есть.
+func (s *Scheduler) optimizedOverrides(employees []Employee, decision SlotDecision, history []DutyHistory, assignments []Assignment, weekStart time.Time) []string {
+	dutyType, _ := s.dutyType(decision.Duty)
+	sl := s.newSlot(dutyType, decision.Date, weekStart)
+	candidates := append([]Candidate(nil), decision.Candidates...)
+	for _, employee := range employees {
+		if employee.Status == StatusFired || employee.preference(decision.Duty, decision.Date) != preferencePreferred {
+			continue
+		}
+		// назначения недели без самого слота: предпочитающий мог занять любое из его мест
+		var others []Assignment
+		inSlot := false
+		for _, assignment := range assignments {
+			if assignment.Duty == decision.Duty && assignment.Date.Equal(decision.Date) {
+				inSlot = inSlot || assignment.EmployeeId == employee.Id
+				continue
+			}
+			others = append(others, assignment)
+		}
+		if inSlot {
+			continue
+		}
+		candidate := Candidate{EmployeeId: employee.Id, EmployeeName: employee.Name, Preference: preferenceNames[preferencePreferred]}
+		if missing := employee.missingSkills(dutyType); len(missing) > 0 {
+			candidate.Reason = ReasonUnqualified
+		} else {
+			candidate.Reason, _ = s.exclusion(sl, employee, history, others)
+		}
+		candidates = append(candidates, candidate)
+	}
+	return overriddenPreferences(candidates)
+}
+
+// ScoreWeek оценивает назначения assignments недели, следующей за текущей датой планировщика,
+// теми же ограничениями и штрафами, что и оптимизатор. employees и storage - данные до формирования
+// этой недели, они не меняются.
+func (s *Scheduler) ScoreWeek(employees *[]Employee, storage *DutyHistoryStorage, assignments []Assignment) (WeekScore, error) {
+	week := s.nextMonday()
+	if err := s.loadTeamDuties(); err != nil {
+		return WeekScore{}, err
+	}
+	state, err := CloneState(&State{Employees: employees, History: storage})
+	if err != nil {
+		return WeekScore{}, err
+	}
+	pinned, err := s.pinnedAssignments(*state.Employees, storage.PinsFor(week), week)
+	if err != nil {
+		return WeekScore{}, err
+	}
+	s.recordPinned(state.Employees, pinned)
+	p := s.newWeekProblem(*state.Employees, state.History, pinned, week)
+
+	rest := append([]Assignment(nil), assignments...)
+	take := func(match func(Assignment) bool) (Assignment, bool) {
+		for n, assignment := range rest {
+			if match(assignment) {
+				rest = append(rest[:n], rest[n+1:]...)
+				return assignment, true
+			}
+		}
+		return Assignment{}, false
+	}
+	for _, pin := range pinned {
+		take(func(a Assignment) bool { return a == pin })
+	}
+
+	solution := make([]int, len(p.slots))
+	var missing []string
+	for k, sl := range p.slots {
+		solution[k] = -1
+		assignment, ok := take(func(a Assignment) bool { return a.Duty == sl.dutyType.Name && a.Date.Equal(sl.date) })
+		if !ok {
+			missing = append(missing, fmt.Sprintf("%s %s не заполнено", sl.dutyType.Title, sl.date.Format("2006-01-02")))
+			continue
+		}
+		if i, ok := findEmployeeIndex(*state.Employees, assignment.EmployeeId); ok {
+			solution[k] = i
+		}
+	}
+
+	score := p.evaluate(solution, true)
+	score.Hard += len(missing)
+	score.Violations = append(score.Violations, missing...)
+	return score, nil
+}
+
+// SolverResult - расписание недели, составленное одним из движков, и его оценка.
+type SolverResult struct {
+	Engine   string
+	Schedule WeekSchedule
+	Score    WeekScore
+	Err      error // движок не смог составить расписание
+}
+
+// CompareSolvers составляет расписание на неделю, следующую за текущей датой планировщика, жадным
+// подбором и оптимизатором и оценивает оба одинаково. Данные не меняются: если неделя уже есть
+// в истории, оба движка составляют ее заново.
+func (s *Scheduler) CompareSolvers(employees *[]Employee, storage *DutyHistoryStorage) ([]SolverResult, error) {
+	week := s.nextMonday()
+	if err := s.loadTeamDuties(); err != nil {
+		return nil, err
+	}
+	baseline, err := CloneState(&State{Employees: employees, History: storage})
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := baseline.History.FindWeek(week); ok {
+		if err := s.DiscardWeek(baseline.Employees, baseline.History, week); err != nil {
+			return nil, err
+		}
+	}
+
+	var results []SolverResult
+	for _, engine := range []string{SolverGreedy, SolverOptimize} {
+		state, err := CloneState(baseline)
+		if err != nil {
+			return nil, err
+		}
+		engineScheduler := *s
+		engineScheduler.Solver.Engine = engine
+
+		result := SolverResult{Engine: engine}
+		result.Schedule, result.Err = engineScheduler.GetSchedule(state.Employees, state.History)
+		if result.Err == nil {
+			if result.Score, err = s.ScoreWeek(baseline.Employees, baseline.History, result.Schedule.Assignments); err != nil {
+				return nil, err
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
@@ -0,0 +1,218 @@
+package pkg
+
+import (
+	"testing"
+)
+
+// cooldownEmployees готовит сотрудников, у которых перерыв по группе release к четвергу testMonday
+// прошел только у Анны: ее Express было за 14 дней до релиза, но всего за 8 дней до пятницы подбора.
+// У остальных релиз был в прошлую пятницу, поэтому на Instances перерыв не прошел ни у кого.
+func cooldownEmployees() ([]Employee, *DutyHistoryStorage) {
+	employees := testEmployees("Анна", "Борис", "Вера", "Глеб", "Дина", "Ефим")
+	release := testMonday.AddDate(0, 0, -11)
+	employees[0].Duties["express"] = DutyStats{Count: 2, LastDuty: release}
+	for i := 1; i < len(employees); i++ {
+		employees[i].Duties["instances"] = DutyStats{LastDuty: testMonday.AddDate(0, 0, -3)}
+	}
+	storage := &DutyHistoryStorage{History: []DutyHistory{{Date: WeekStart(release), Status: HistoryConfirmed, Assignments: []Assignment{
+		{Duty: "express", Date: release, EmployeeId: 1, EmployeeName: "Анна"},
+	}}}}
+	return employees, storage
+}
+
+func TestCooldownSameInBothEngines(t *testing.T) {
+	friday := testMonday.AddDate(0, 0, -3)
+	for _, engine := range []string{SolverGreedy, SolverOptimize} {
+		t.Run(engine, func(t *testing.T) {
+			employees, storage := cooldownEmployees()
+			scheduler := NewScheduler(FixedClock(friday), nil)
+			scheduler.Solver.Engine = engine
+
+			baseline, err := CloneState(&State{Employees: &employees, History: storage})
+			if err != nil {
+				t.Fatal(err)
+			}
+			schedule, err := scheduler.GetSchedule(&employees, storage)
+			if err != nil {
+				t.Fatalf("GetSchedule: %v", err)
+			}
+
+			got := assignmentsByDate(schedule.Assignments)
+			// перерыв считается от дня релиза, а не от даты подбора
+			if got["express 2026-10-22"] != "Анна" {
+				t.Errorf("на Express назначен %q, ожидалась Анна", got["express 2026-10-22"])
+			}
+			// на Instances перерыв не прошел ни у кого: слот все равно заполняется
+			if name := got["instances 2026-10-22"]; name == "" || name == "Анна" {
+				t.Errorf("на Instances назначен %q", name)
+			}
+
+			score, err := scheduler.ScoreWeek(baseline.Employees, baseline.History, schedule.Assignments)
+			if err != nil {
+				t.Fatalf("ScoreWeek: %v", err)
+			}
+			if score.Hard != 0 || score.Cooldown != 1 || len(score.CooldownBreak) != 1 {
+				t.Errorf("оценка: нарушений %d %v, в обход перерыва %d %v", score.Hard, score.Violations, score.Cooldown, score.CooldownBreak)
+			}
+		})
+	}
+}
+
+func TestOptimizeWeek(t *testing.T) {
+	employees := testEmployees("Анна", "Борис", "Вера", "Глеб", "Дина", "Ефим")
+	employees[1].Preferences = &Preferences{Avoided: []string{"express"}}
+	employees[2].Absences = []Absence{{Kind: StatusVacation, Start: testMonday.AddDate(0, 0, 1), End: testMonday.AddDate(0, 0, 1)}}
+	storage := &DutyHistoryStorage{Pins: []Pin{{Week: testMonday, Duty: "support", Date: testMonday, EmployeeId: 6}}}
+	scheduler := NewScheduler(FixedClock(testMonday), nil)
+	scheduler.Solver.Engine = SolverOptimize
+
+	baseline, err := CloneState(&State{Employees: &employees, History: storage})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schedule, err := scheduler.GetSchedule(&employees, storage)
+	if err != nil {
+		t.Fatalf("GetSchedule: %v", err)
+	}
+
+	got := assignmentsByDate(schedule.Assignments)
+	if len(got) != 7 {
+		t.Fatalf("назначений %d, ожидалось 7: %v", len(got), got)
+	}
+	if got["support 2026-10-19"] != "Ефим" {
+		t.Errorf("закрепление не учтено: %v", got)
+	}
+	if got["express 2026-10-22"] == "Борис" || got["express 2026-10-22"] == "Вера" {
+		t.Errorf("на Express назначен %q вопреки пожеланию или отпуску", got["express 2026-10-22"])
+	}
+	if got["support 2026-10-20"] == "Вера" {
+		t.Errorf("Вера назначена на день отпуска")
+	}
+	perEmployee := map[string]int{}
+	for _, name := range got {
+		perEmployee[name]++
+	}
+	for name, n := range perEmployee {
+		if n > 2 {
+			t.Errorf("у %s %d дежурств на неделе", name, n)
+		}
+	}
+	for _, decision := range schedule.Decisions {
+		if decision.Rule != RuleOptimized && decision.Rule != RulePinned {
+			t.Errorf("%s %s: правило %q", decision.Duty, decision.Date.Format("2006-01-02"), decision.Rule)
+		}
+	}
+
+	// собственная оценка оптимизатора совпадает с ScoreWeek для его расписания
+	score, err := scheduler.ScoreWeek(baseline.Employees, baseline.History, schedule.Assignments)
+	if err != nil {
+		t.Fatalf("ScoreWeek: %v", err)
+	}
+	if score.Hard != 0 || score.Cooldown != 0 || score.Preference != 0 {
+		t.Errorf("оценка: %+v", score)
+	}
+}
+
+func TestScoreWeek(t *testing.T) {
+	week := testMonday
+	valid := []Assignment{
+		{Duty: "express", Date: week.AddDate(0, 0, 3), EmployeeId: 1},
+		{Duty: "instances", Date: week.AddDate(0, 0, 3), EmployeeId: 2},
+		{Duty: "support", Date: week, EmployeeId: 1},
+		{Duty: "support", Date: week.AddDate(0, 0, 1), EmployeeId: 2},
+		{Duty: "support", Date: week.AddDate(0, 0, 2), EmployeeId: 3},
+		{Duty: "support", Date: week.AddDate(0, 0, 3), EmployeeId: 4},
+		{Duty: "support", Date: week.AddDate(0, 0, 4), EmployeeId: 5},
+	}
+	replace := func(n int, employeeId int) []Assignment {
+		assignments := append([]Assignment(nil), valid...)
+		assignments[n].EmployeeId = employeeId
+		return assignments
+	}
+
+	tests := []struct {
+		name        string
+		prepare     func(employees []Employee)
+		assignments []Assignment
+		hard        int
+		cooldown    int
+	}{
+		{name: "без нарушений", assignments: valid},
+		{name: "не заполнен слот", assignments: valid[:6], hard: 1},
+		{name: "Express и Instances у одного сотрудника", assignments: replace(1, 1), hard: 1},
+		{
+			name:        "назначен уволенный",
+			prepare:     func(employees []Employee) { employees[2].Status = StatusFired },
+			assignments: valid,
+			hard:        1,
+		},
+		{
+			name: "не прошел перерыв",
+			prepare: func(employees []Employee) {
+				employees[4].Duties["support"] = DutyStats{LastDuty: week.AddDate(0, 0, -1)}
+			},
+			assignments: valid,
+			cooldown:    1,
+		},
+		{name: "Support дважды за неделю", assignments: replace(6, 1), hard: 1, cooldown: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			employees := testEmployees("Анна", "Борис", "Вера", "Глеб", "Дина", "Ефим")
+			if tt.prepare != nil {
+				tt.prepare(employees)
+			}
+			storage := &DutyHistoryStorage{}
+			score, err := NewScheduler(FixedClock(week), nil).ScoreWeek(&employees, storage, tt.assignments)
+			if err != nil {
+				t.Fatalf("ScoreWeek: %v", err)
+			}
+			if score.Hard != tt.hard || len(score.Violations) != tt.hard {
+				t.Errorf("нарушений %d %v, ожидалось %d", score.Hard, score.Violations, tt.hard)
+			}
+			if score.Cooldown != tt.cooldown || len(score.CooldownBreak) != tt.cooldown {
+				t.Errorf("в обход перерыва %d %v, ожидалось %d", score.Cooldown, score.CooldownBreak, tt.cooldown)
+			}
+		})
+	}
+}
+
+func TestCompareSolvers(t *testing.T) {
+	employees := testEmployees("Анна", "Борис", "Вера", "Глеб", "Дина", "Ефим")
+	storage := &DutyHistoryStorage{}
+	scheduler := NewScheduler(FixedClock(testMonday), nil)
+	// уже сформированная неделя составляется обоими движками заново
+	if _, _, err := scheduler.GenerateWeek(&employees, storage); err != nil {
+		t.Fatal(err)
+	}
+	before, err := CloneState(&State{Employees: &employees, History: storage})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := scheduler.CompareSolvers(&employees, storage)
+	if err != nil {
+		t.Fatalf("CompareSolvers: %v", err)
+	}
+	if len(results) != 2 || results[0].Engine != SolverGreedy || results[1].Engine != SolverOptimize {
+		t.Fatalf("результаты: %+v", results)
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			t.Errorf("%s: %v", result.Engine, result.Err)
+			continue
+		}
+		if len(result.Schedule.Assignments) != 7 {
+			t.Errorf("%s: назначений %d", result.Engine, len(result.Schedule.Assignments))
+		}
+		if result.Score.Hard != 0 || result.Score.Cooldown != 0 {
+			t.Errorf("%s: оценка %+v", result.Engine, result.Score)
+		}
+	}
+	// оптимизатор не хуже жадного подбора по собственной оценке
+	if results[1].Score.cost() > results[0].Score.cost()+1e-9 {
+		t.Errorf("штраф оптимизатора %g больше жадного %g", results[1].Score.cost(), results[0].Score.cost())
+	}
+	assertSameState(t, before, &State{Employees: &employees, History: storage})
+}
@@ -1,8 +1,10 @@
 package pkg
 
 import (
+	"bufio"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 )
 
@@ -46,8 +48,8 @@ func SaveEmployees(filePath string, employees *[]Employee) error {
 		return errors.New("не удалось закодировать в JSON: " + err.Error())
 	}
 
-	// Запись закодированных данных в файл
-	if err := os.WriteFile(filePath, jsonData, 0644); err != nil {
+	// Запись закодированных данных в файл через временный файл, чтобы сбой не обрезал employees.json
+	if err := writeFileAtomic(filePath, jsonData, 0644); err != nil {
 		return errors.New("не удалось сохранить данные в файл: " + err.Error())
 	}
 
@@ -89,7 +91,7 @@ func SaveDutyHistory(filePath string, storage *DutyHistoryStorage) error {
 		return err
 	}
 
-	err = os.WriteFile(filePath, data, 0644)
+	err = writeFileAtomic(filePath, data, 0644)
 	if err != nil {
 		return err
 	}
@@ -104,10 +106,70 @@ func SaveShedule(filePath string, storage *DutyHistory) error {
 		return err
 	}
 
-	err = os.WriteFile(filePath, data, 0644)
+	err = writeFileAtomic(filePath, data, 0644)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// AppendEvents дописывает события в конец файла журнала, по одному JSON-объекту на строку.
+// Файл открывается только на дозапись, поэтому прежние события не переписываются.
+func AppendEvents(filePath string, events []Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	var data []byte
+	for _, event := range events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return errors.New("не удалось открыть журнал: " + err.Error())
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return errors.New("не удалось записать журнал: " + err.Error())
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// LoadEvents читает события журнала, подходящие под filter, в порядке записи.
+func LoadEvents(filePath string, filter EventFilter) ([]Event, error) {
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("не удалось открыть журнал: " + err.Error())
+	}
+	defer file.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("журнал %s, строка %d: %w", filePath, line, err)
+		}
+		if filter.Match(event) {
+			events = append(events, event)
+		}
+	}
+	return events, scanner.Err()
+}
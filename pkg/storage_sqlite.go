@@ -0,0 +1,693 @@
+package pkg
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	_ "modernc.org/sqlite" // драйвер SQLite на чистом Go
+)
+
+// sqliteMigrations последовательно приводят схему базы к актуальной версии.
+// Номер примененной миграции хранится в PRAGMA user_version.
+var sqliteMigrations = []string{
+	`CREATE TABLE employees (
+		id                   INTEGER PRIMARY KEY,
+		name                 TEXT    NOT NULL,
+		status               TEXT    NOT NULL,
+		support_last_duty    TEXT,
+		release_last_duty    TEXT,
+		support_duty_count   INTEGER NOT NULL DEFAULT 0,
+		express_duty_count   INTEGER NOT NULL DEFAULT 0,
+		instances_duty_count INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE TABLE absences (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		employee_id INTEGER NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
+		kind        TEXT    NOT NULL,
+		start_date  TEXT    NOT NULL,
+		end_date    TEXT
+	);
+	CREATE TABLE weeks (
+		week_start TEXT    PRIMARY KEY,
+		position   INTEGER NOT NULL
+	);
+	CREATE TABLE assignments (
+		id            INTEGER PRIMARY KEY AUTOINCREMENT,
+		week_start    TEXT    NOT NULL REFERENCES weeks(week_start) ON DELETE CASCADE,
+		position      INTEGER NOT NULL,
+		employee_id   INTEGER NOT NULL,
+		employee_name TEXT    NOT NULL,
+		duty          TEXT    NOT NULL,
+		duty_date     TEXT    NOT NULL
+	);
+	CREATE INDEX assignments_employee ON assignments(employee_id, duty_date);
+	CREATE TABLE resets (
+		id       INTEGER PRIMARY KEY AUTOINCREMENT,
+		reset_at TEXT    NOT NULL
+	);`,
+	// счетчики по видам дежурств вместо отдельных колонок под Support, Express и Instances
+	`CREATE TABLE employee_duties (
+		employee_id INTEGER NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
+		duty        TEXT    NOT NULL,
+		count       INTEGER NOT NULL DEFAULT 0,
+		last_duty   TEXT,
+		PRIMARY KEY (employee_id, duty)
+	);
+	INSERT INTO employee_duties (employee_id, duty, count, last_duty)
+		SELECT id, 'support', support_duty_count, support_last_duty FROM employees;
+	INSERT INTO employee_duties (employee_id, duty, count, last_duty)
+		SELECT id, 'express', express_duty_count, release_last_duty FROM employees;
+	INSERT INTO employee_duties (employee_id, duty, count, last_duty)
+		SELECT id, 'instances', instances_duty_count, release_last_duty FROM employees;
+	ALTER TABLE employees DROP COLUMN support_last_duty;
+	ALTER TABLE employees DROP COLUMN release_last_duty;
+	ALTER TABLE employees DROP COLUMN support_duty_count;
+	ALTER TABLE employees DROP COLUMN express_duty_count;
+	ALTER TABLE employees DROP COLUMN instances_duty_count;`,
+	// статус недели (запланирована или утверждена) и отметка о публикации
+	`ALTER TABLE weeks ADD COLUMN status TEXT NOT NULL DEFAULT '';
+	ALTER TABLE weeks ADD COLUMN published_at TEXT;
+	ALTER TABLE weeks ADD COLUMN published_to TEXT NOT NULL DEFAULT '';`,
+	// привязка сотрудника к пользователю Telegram
+	`ALTER TABLE employees ADD COLUMN telegram_user_id INTEGER NOT NULL DEFAULT 0;`,
+	// разбор подбора дежурных недели в JSON
+	`ALTER TABLE weeks ADD COLUMN decisions TEXT NOT NULL DEFAULT '';`,
+	// закрепления сотрудников за слотами до формирования расписания
+	`CREATE TABLE pins (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		week_start  TEXT    NOT NULL,
+		duty        TEXT    NOT NULL,
+		duty_date   TEXT    NOT NULL,
+		employee_id INTEGER NOT NULL,
+		forced      INTEGER NOT NULL DEFAULT 0
+	);`,
+	// журнал обменов и замен дежурных недели в JSON
+	`ALTER TABLE weeks ADD COLUMN changes TEXT NOT NULL DEFAULT '';`,
+	// журнал изменений; записи только добавляются
+	`CREATE TABLE events (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		at         TEXT    NOT NULL,
+		type       TEXT    NOT NULL,
+		actor      TEXT    NOT NULL,
+		reason     TEXT    NOT NULL DEFAULT '',
+		week_start TEXT,
+		employees  TEXT    NOT NULL DEFAULT '',
+		before     TEXT,
+		after      TEXT
+	);
+	CREATE INDEX events_at ON events(at);`,
+	// пожелания сотрудника к дежурствам в JSON
+	`ALTER TABLE employees ADD COLUMN preferences TEXT NOT NULL DEFAULT '';`,
+	// навыки сотрудника, его смены стажером и стажеры недели в JSON
+	`ALTER TABLE employees ADD COLUMN skills TEXT NOT NULL DEFAULT '';
+	ALTER TABLE employees ADD COLUMN shadow_shifts TEXT NOT NULL DEFAULT '';
+	ALTER TABLE weeks ADD COLUMN shadows TEXT NOT NULL DEFAULT '';`,
+	// ключ человека, общий для его записей в разных командах
+	`ALTER TABLE employees ADD COLUMN member TEXT NOT NULL DEFAULT '';`,
+}
+
+// SQLiteRepository хранит сотрудников, назначения и сбросы счетчиков в базе SQLite.
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// OpenSQLiteRepository открывает (или создает) базу по пути path и применяет недостающие миграции.
+func OpenSQLiteRepository(path string) (*SQLiteRepository, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть базу %s: %w", path, err)
+	}
+	// у SQLite один писатель, так проще избежать SQLITE_BUSY внутри процесса
+	db.SetMaxOpenConns(1)
+
+	repo := &SQLiteRepository{db: db}
+	if err := repo.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return repo, nil
+}
+
+// OpenSQLiteReader открывает базу другой команды только для чтения: без миграций и без создания файла.
+// Если базы нет или ее схема старее текущей (команду еще не открывали этой версией программы),
+// возвращается nil без ошибки: общих сотрудников в такой базе быть не может.
+func OpenSQLiteReader(path string) (*SQLiteRepository, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть базу %s: %w", path, err)
+	}
+	db.SetMaxOpenConns(1)
+
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("не удалось прочитать версию схемы %s: %w", path, err)
+	}
+	switch {
+	case version < len(sqliteMigrations):
+		db.Close()
+		return nil, nil
+	case version > len(sqliteMigrations):
+		db.Close()
+		return nil, fmt.Errorf("база %s создана более новой версией программы", path)
+	}
+	return &SQLiteRepository{db: db}, nil
+}
+
+func (r *SQLiteRepository) migrate() error {
+	var version int
+	if err := r.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("не удалось прочитать версию схемы: %w", err)
+	}
+
+	for i := version; i < len(sqliteMigrations); i++ {
+		tx, err := r.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(sqliteMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("не удалось применить миграцию %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) Close() error {
+	return r.db.Close()
+}
+
+// Empty сообщает, что в базе еще нет ни сотрудников, ни истории.
+func (r *SQLiteRepository) Empty() (bool, error) {
+	var count int
+	err := r.db.QueryRow("SELECT (SELECT COUNT(*) FROM employees) + (SELECT COUNT(*) FROM weeks)").Scan(&count)
+	return count == 0, err
+}
+
+func (r *SQLiteRepository) LoadEmployees() (*[]Employee, error) {
+	rows, err := r.db.Query("SELECT id, name, status, telegram_user_id, member, preferences, skills, shadow_shifts FROM employees ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("не удалось загрузить сотрудников: %w", err)
+	}
+	defer rows.Close()
+
+	employees := []Employee{}
+	index := map[int]int{}
+	for rows.Next() {
+		employee := Employee{Duties: map[string]DutyStats{}}
+		var preferences, skills, shadowShifts string
+		if err := rows.Scan(&employee.Id, &employee.Name, &employee.Status, &employee.TelegramUserId, &employee.Member, &preferences, &skills, &shadowShifts); err != nil {
+			return nil, err
+		}
+		if preferences != "" {
+			if err := json.Unmarshal([]byte(preferences), &employee.Preferences); err != nil {
+				return nil, fmt.Errorf("неверные пожелания сотрудника %s в базе: %w", employee.Name, err)
+			}
+		}
+		if skills != "" {
+			if err := json.Unmarshal([]byte(skills), &employee.Skills); err != nil {
+				return nil, fmt.Errorf("неверные навыки сотрудника %s в базе: %w", employee.Name, err)
+			}
+		}
+		if shadowShifts != "" {
+			if err := json.Unmarshal([]byte(shadowShifts), &employee.ShadowShifts); err != nil {
+				return nil, fmt.Errorf("неверные смены стажером сотрудника %s в базе: %w", employee.Name, err)
+			}
+		}
+		index[employee.Id] = len(employees)
+		employees = append(employees, employee)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	duties, err := r.db.Query("SELECT employee_id, duty, count, last_duty FROM employee_duties")
+	if err != nil {
+		return nil, fmt.Errorf("не удалось загрузить счетчики дежурств: %w", err)
+	}
+	defer duties.Close()
+
+	for duties.Next() {
+		var employeeId int
+		var duty string
+		var stats DutyStats
+		var lastDuty sql.NullString
+		if err := duties.Scan(&employeeId, &duty, &stats.Count, &lastDuty); err != nil {
+			return nil, err
+		}
+		if stats.LastDuty, err = parseSQLiteTime(lastDuty); err != nil {
+			return nil, err
+		}
+		if i, ok := index[employeeId]; ok {
+			employees[i].Duties[duty] = stats
+		}
+	}
+	if err := duties.Err(); err != nil {
+		return nil, err
+	}
+
+	absences, err := r.db.Query("SELECT employee_id, kind, start_date, end_date FROM absences ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("не удалось загрузить отсутствия: %w", err)
+	}
+	defer absences.Close()
+
+	for absences.Next() {
+		var employeeId int
+		var absence Absence
+		var start, end sql.NullString
+		if err := absences.Scan(&employeeId, &absence.Kind, &start, &end); err != nil {
+			return nil, err
+		}
+		if absence.Start, err = parseSQLiteTime(start); err != nil {
+			return nil, err
+		}
+		if absence.End, err = parseSQLiteTime(end); err != nil {
+			return nil, err
+		}
+		if i, ok := index[employeeId]; ok {
+			employees[i].Absences = append(employees[i].Absences, absence)
+		}
+	}
+
+	return &employees, absences.Err()
+}
+
+func (r *SQLiteRepository) SaveEmployees(employees *[]Employee) error {
+	return r.transaction(func(tx *sql.Tx) error {
+		return saveSQLiteEmployees(tx, employees)
+	})
+}
+
+func saveSQLiteEmployees(tx *sql.Tx, employees *[]Employee) error {
+	if _, err := tx.Exec("DELETE FROM absences"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM employee_duties"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM employees"); err != nil {
+		return err
+	}
+
+	for _, employee := range *employees {
+		n := 0
+		if employee.Preferences != nil && !employee.Preferences.Empty() {
+			n = 1
+		}
+		preferences, err := marshalSQLiteJSON(employee.Preferences, n)
+		if err != nil {
+			return err
+		}
+		skills, err := marshalSQLiteJSON(employee.Skills, len(employee.Skills))
+		if err != nil {
+			return err
+		}
+		shadowShifts, err := marshalSQLiteJSON(employee.ShadowShifts, len(employee.ShadowShifts))
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("INSERT INTO employees (id, name, status, telegram_user_id, member, preferences, skills, shadow_shifts) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			employee.Id, employee.Name, employee.Status, employee.TelegramUserId, employee.Member, preferences, skills, shadowShifts)
+		if err != nil {
+			return fmt.Errorf("не удалось сохранить сотрудника %s: %w", employee.Name, err)
+		}
+
+		for duty, stats := range employee.Duties {
+			_, err := tx.Exec("INSERT INTO employee_duties (employee_id, duty, count, last_duty) VALUES (?, ?, ?, ?)",
+				employee.Id, duty, stats.Count, formatSQLiteTime(stats.LastDuty))
+			if err != nil {
+				return fmt.Errorf("не удалось сохранить счетчики сотрудника %s: %w", employee.Name, err)
+			}
+		}
+
+		for _, absence := range employee.Absences {
+			_, err := tx.Exec("INSERT INTO absences (employee_id, kind, start_date, end_date) VALUES (?, ?, ?, ?)",
+				employee.Id, absence.Kind, formatSQLiteTime(absence.Start), formatSQLiteTime(absence.End))
+			if err != nil {
+				return fmt.Errorf("не удалось сохранить отсутствие сотрудника %s: %w", employee.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *SQLiteRepository) LoadDutyHistory() (*DutyHistoryStorage, error) {
+	storage := &DutyHistoryStorage{}
+
+	var lastReset sql.NullString
+	err := r.db.QueryRow("SELECT reset_at FROM resets ORDER BY id DESC LIMIT 1").Scan(&lastReset)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return storage, fmt.Errorf("не удалось загрузить дату сброса счетчиков: %w", err)
+	}
+	if storage.LastResetDate, err = parseSQLiteTime(lastReset); err != nil {
+		return storage, err
+	}
+
+	rows, err := r.db.Query(`SELECT w.week_start, w.status, w.published_at, w.published_to, w.decisions, w.changes, w.shadows,
+			a.employee_id, a.employee_name, a.duty, a.duty_date
+		FROM weeks w LEFT JOIN assignments a ON a.week_start = w.week_start
+		ORDER BY w.position, a.position`)
+	if err != nil {
+		return storage, fmt.Errorf("не удалось загрузить историю: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var weekStart, status, publishedAt, publishedTo, decisions, changes, shadows, employeeName, duty, dutyDate sql.NullString
+		var employeeId sql.NullInt64
+		if err := rows.Scan(&weekStart, &status, &publishedAt, &publishedTo, &decisions, &changes, &shadows, &employeeId, &employeeName, &duty, &dutyDate); err != nil {
+			return storage, err
+		}
+
+		week, err := parseSQLiteTime(weekStart)
+		if err != nil {
+			return storage, err
+		}
+		if len(storage.History) == 0 || !storage.History[len(storage.History)-1].Date.Equal(week) {
+			record := DutyHistory{Date: week, Status: status.String}
+			if publishedAt.Valid {
+				published, err := parseSQLiteTime(publishedAt)
+				if err != nil {
+					return storage, err
+				}
+				record.Publication = &Publication{PublishedAt: published, Target: publishedTo.String}
+			}
+			if decisions.String != "" {
+				if err := json.Unmarshal([]byte(decisions.String), &record.Decisions); err != nil {
+					return storage, fmt.Errorf("неверный разбор недели %s в базе: %w", week.Format("2006-01-02"), err)
+				}
+			}
+			if changes.String != "" {
+				if err := json.Unmarshal([]byte(changes.String), &record.Changes); err != nil {
+					return storage, fmt.Errorf("неверный журнал изменений недели %s в базе: %w", week.Format("2006-01-02"), err)
+				}
+			}
+			if shadows.String != "" {
+				if err := json.Unmarshal([]byte(shadows.String), &record.Shadows); err != nil {
+					return storage, fmt.Errorf("неверные стажеры недели %s в базе: %w", week.Format("2006-01-02"), err)
+				}
+			}
+			storage.History = append(storage.History, record)
+		}
+		if !employeeId.Valid {
+			continue
+		}
+
+		date, err := parseSQLiteTime(dutyDate)
+		if err != nil {
+			return storage, err
+		}
+		record := &storage.History[len(storage.History)-1]
+		record.Assignments = append(record.Assignments, Assignment{
+			Duty:         duty.String,
+			Date:         date,
+			EmployeeId:   int(employeeId.Int64),
+			EmployeeName: employeeName.String,
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return storage, err
+	}
+
+	pins, err := r.db.Query("SELECT week_start, duty, duty_date, employee_id, forced FROM pins ORDER BY id")
+	if err != nil {
+		return storage, fmt.Errorf("не удалось загрузить закрепления: %w", err)
+	}
+	defer pins.Close()
+
+	for pins.Next() {
+		var pin Pin
+		var week, date sql.NullString
+		if err := pins.Scan(&week, &pin.Duty, &date, &pin.EmployeeId, &pin.Force); err != nil {
+			return storage, err
+		}
+		if pin.Week, err = parseSQLiteTime(week); err != nil {
+			return storage, err
+		}
+		if pin.Date, err = parseSQLiteTime(date); err != nil {
+			return storage, err
+		}
+		storage.Pins = append(storage.Pins, pin)
+	}
+
+	return storage, pins.Err()
+}
+
+func (r *SQLiteRepository) SaveDutyHistory(storage *DutyHistoryStorage) error {
+	return r.transaction(func(tx *sql.Tx) error {
+		return saveSQLiteHistory(tx, storage)
+	})
+}
+
+func saveSQLiteHistory(tx *sql.Tx, storage *DutyHistoryStorage) error {
+	if _, err := tx.Exec("DELETE FROM assignments"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM weeks"); err != nil {
+		return err
+	}
+
+	for position, record := range storage.History {
+		week := formatSQLiteTime(record.Date)
+		publishedAt, publishedTo := sql.NullString{}, ""
+		if record.Publication != nil {
+			publishedAt, publishedTo = formatSQLiteTime(record.Publication.PublishedAt), record.Publication.Target
+		}
+		decisions, err := marshalSQLiteJSON(record.Decisions, len(record.Decisions))
+		if err != nil {
+			return err
+		}
+		changes, err := marshalSQLiteJSON(record.Changes, len(record.Changes))
+		if err != nil {
+			return err
+		}
+		shadows, err := marshalSQLiteJSON(record.Shadows, len(record.Shadows))
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("INSERT INTO weeks (week_start, position, status, published_at, published_to, decisions, changes, shadows) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			week, position, record.Status, publishedAt, publishedTo, decisions, changes, shadows)
+		if err != nil {
+			return fmt.Errorf("не удалось сохранить неделю %s: %w", record.Date.Format("2006-01-02"), err)
+		}
+
+		for i, assignment := range record.Assignments {
+			_, err := tx.Exec(`INSERT INTO assignments (week_start, position, employee_id, employee_name, duty, duty_date)
+				VALUES (?, ?, ?, ?, ?, ?)`, week, i, assignment.EmployeeId, assignment.EmployeeName, assignment.Duty, formatSQLiteTime(assignment.Date))
+			if err != nil {
+				return fmt.Errorf("не удалось сохранить назначение %s: %w", assignment.EmployeeName, err)
+			}
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM pins"); err != nil {
+		return err
+	}
+	for _, pin := range storage.Pins {
+		_, err := tx.Exec("INSERT INTO pins (week_start, duty, duty_date, employee_id, forced) VALUES (?, ?, ?, ?, ?)",
+			formatSQLiteTime(pin.Week), pin.Duty, formatSQLiteTime(pin.Date), pin.EmployeeId, pin.Force)
+		if err != nil {
+			return fmt.Errorf("не удалось сохранить закрепление на %s: %w", pin.Date.Format("2006-01-02"), err)
+		}
+	}
+
+	// сбросы счетчиков только дописываются, чтобы сохранялась их история
+	if !storage.LastResetDate.IsZero() {
+		var lastReset sql.NullString
+		err := tx.QueryRow("SELECT reset_at FROM resets ORDER BY id DESC LIMIT 1").Scan(&lastReset)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if current := formatSQLiteTime(storage.LastResetDate); !lastReset.Valid || lastReset.String != current.String {
+			if _, err := tx.Exec("INSERT INTO resets (reset_at) VALUES (?)", current); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// marshalSQLiteJSON кодирует значение для текстовой колонки с JSON; пустой список хранится пустой строкой.
+func marshalSQLiteJSON(value interface{}, n int) (string, error) {
+	if n == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(value)
+	return string(data), err
+}
+
+func (r *SQLiteRepository) AppendEvents(events []Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return r.transaction(func(tx *sql.Tx) error {
+		return appendSQLiteEvents(tx, events)
+	})
+}
+
+// Save сохраняет сотрудников, историю и события журнала одной транзакцией.
+func (r *SQLiteRepository) Save(state *State, events []Event) error {
+	return r.transaction(func(tx *sql.Tx) error {
+		if err := saveSQLiteEmployees(tx, state.Employees); err != nil {
+			return err
+		}
+		if err := saveSQLiteHistory(tx, state.History); err != nil {
+			return err
+		}
+		return appendSQLiteEvents(tx, events)
+	})
+}
+
+// transaction выполняет fn в транзакции и фиксирует ее, если fn не вернула ошибку.
+func (r *SQLiteRepository) transaction(fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func appendSQLiteEvents(tx *sql.Tx, events []Event) error {
+	for _, event := range events {
+		var week sql.NullString
+		if event.Week != nil {
+			week = formatSQLiteTime(*event.Week)
+		}
+		employees, err := marshalSQLiteJSON(event.Employees, len(event.Employees))
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO events (at, type, actor, reason, week_start, employees, before, after)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`, formatSQLiteTime(event.At), event.Type, event.Actor, event.Reason,
+			week, employees, nullRawJSON(event.Before), nullRawJSON(event.After))
+		if err != nil {
+			return fmt.Errorf("не удалось записать событие %s в журнал: %w", event.Type, err)
+		}
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) LoadEvents(filter EventFilter) ([]Event, error) {
+	rows, err := r.db.Query("SELECT at, type, actor, reason, week_start, employees, before, after FROM events ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("не удалось загрузить журнал: %w", err)
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var event Event
+		var at, week, before, after sql.NullString
+		var employees string
+		if err := rows.Scan(&at, &event.Type, &event.Actor, &event.Reason, &week, &employees, &before, &after); err != nil {
+			return nil, err
+		}
+		if event.At, err = parseSQLiteTime(at); err != nil {
+			return nil, err
+		}
+		if week.Valid {
+			date, err := parseSQLiteTime(week)
+			if err != nil {
+				return nil, err
+			}
+			event.Week = &date
+		}
+		if employees != "" {
+			if err := json.Unmarshal([]byte(employees), &event.Employees); err != nil {
+				return nil, fmt.Errorf("неверный список сотрудников события в базе: %w", err)
+			}
+		}
+		if before.Valid {
+			event.Before = json.RawMessage(before.String)
+		}
+		if after.Valid {
+			event.After = json.RawMessage(after.String)
+		}
+		if filter.Match(event) {
+			events = append(events, event)
+		}
+	}
+	return events, rows.Err()
+}
+
+func nullRawJSON(data json.RawMessage) sql.NullString {
+	if len(data) == 0 {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: string(data), Valid: true}
+}
+
+func formatSQLiteTime(t time.Time) sql.NullString {
+	if t.IsZero() {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: t.Format(time.RFC3339Nano), Valid: true}
+}
+
+func parseSQLiteTime(value sql.NullString) (time.Time, error) {
+	if !value.Valid || value.String == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value.String)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("неверная дата в базе %q: %w", value.String, err)
+	}
+	return t, nil
+}
+
+// MigrateJSONToSQLite переносит сотрудников, историю и журнал изменений из JSON-хранилища в базу SQLite.
+func MigrateJSONToSQLite(from *JSONRepository, to *SQLiteRepository) error {
+	employees, err := from.LoadEmployees()
+	if err != nil {
+		return err
+	}
+	storage, err := from.LoadDutyHistory()
+	if err != nil {
+		return fmt.Errorf("не удалось загрузить историю дежурств: %w", err)
+	}
+
+	events, err := from.LoadEvents(EventFilter{})
+	if err != nil {
+		return err
+	}
+
+	if err := to.SaveEmployees(employees); err != nil {
+		return err
+	}
+	if err := to.SaveDutyHistory(storage); err != nil {
+		return err
+	}
+
+	// журнал переносится только в пустую базу, чтобы повторная миграция не задвоила события
+	var count int
+	if err := to.db.QueryRow("SELECT COUNT(*) FROM events").Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return to.AppendEvents(events)
+}
@@ -0,0 +1,183 @@
+package pkg
+
+import (
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testState формирует неделю по testEmployees и заполняет все поля, которые хранят репозитории.
+func testState(t *testing.T) *State {
+	employees := testEmployees("Анна", "Борис", "Вера", "Глеб", "Дина", "Ефим")
+	employees[0].TelegramUserId = 555
+	employees[0].Member = "anna"
+	employees[1].Absences = []Absence{{Kind: StatusVacation, Start: testMonday.AddDate(0, 0, 14), End: testMonday.AddDate(0, 0, 20)}}
+	employees[2].Preferences = &Preferences{Blackouts: []string{"friday"}, Avoided: []string{"express"}, MaxPerMonth: 4}
+	employees[3].Skills = []string{"release"}
+	employees[4].ShadowShifts = map[string]int{"express": 1}
+
+	storage := &DutyHistoryStorage{}
+	scheduler := NewScheduler(FixedClock(testMonday.AddDate(0, 0, -3)), nil)
+	if _, _, err := scheduler.GenerateWeek(&employees, storage); err != nil {
+		t.Fatalf("GenerateWeek: %v", err)
+	}
+	record := &storage.History[0]
+	record.Publication = &Publication{PublishedAt: testMonday.Add(-time.Hour), Target: "hooks.example.com"}
+	record.Changes = []WeekChange{{At: testMonday, Kind: ChangeReplace, RequestedBy: "test", Moves: []AssignmentMove{}}}
+	record.Shadows = []ShadowShift{{Duty: "express", Date: testMonday.AddDate(0, 0, 3), EmployeeId: 5, EmployeeName: "Дина", Shift: 1}}
+	storage.Pins = []Pin{{Week: testMonday.AddDate(0, 0, 7), Duty: "support", Date: testMonday.AddDate(0, 0, 7), EmployeeId: 2, Force: true}}
+	return &State{Employees: &employees, History: storage}
+}
+
+// assertSameState сравнивает состояния по JSON, как их видят остальные части программы.
+func assertSameState(t *testing.T, got, want *State) {
+	t.Helper()
+	if !sameJSON(got.Employees, want.Employees) {
+		gotJSON, _ := json.Marshal(got.Employees)
+		wantJSON, _ := json.Marshal(want.Employees)
+		t.Errorf("сотрудники после загрузки:\n%s\nожидались:\n%s", gotJSON, wantJSON)
+	}
+	if !sameJSON(got.History, want.History) {
+		gotJSON, _ := json.Marshal(got.History)
+		wantJSON, _ := json.Marshal(want.History)
+		t.Errorf("история после загрузки:\n%s\nожидалась:\n%s", gotJSON, wantJSON)
+	}
+}
+
+func loadState(t *testing.T, repo Repository) *State {
+	t.Helper()
+	employees, err := repo.LoadEmployees()
+	if err != nil {
+		t.Fatalf("LoadEmployees: %v", err)
+	}
+	storage, err := repo.LoadDutyHistory()
+	if err != nil {
+		t.Fatalf("LoadDutyHistory: %v", err)
+	}
+	return &State{Employees: employees, History: storage}
+}
+
+func openTestSQLite(t *testing.T) *SQLiteRepository {
+	t.Helper()
+	repo, err := OpenSQLiteRepository(filepath.Join(t.TempDir(), "schedule.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLiteRepository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+func TestSQLiteMigrationsFromFirstSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedule.db")
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(sqliteMigrations[0] + `
+		PRAGMA user_version = 1;
+		INSERT INTO employees (id, name, status, support_last_duty, release_last_duty, support_duty_count, express_duty_count, instances_duty_count)
+			VALUES (1, 'Анна', 'available', '2026-10-12T00:00:00Z', '2026-10-01T00:00:00Z', 3, 2, 1);`)
+	db.Close()
+	if err != nil {
+		t.Fatalf("схема первой версии: %v", err)
+	}
+
+	repo, err := OpenSQLiteRepository(path)
+	if err != nil {
+		t.Fatalf("OpenSQLiteRepository: %v", err)
+	}
+
+	var version int
+	if err := repo.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if version != len(sqliteMigrations) {
+		t.Errorf("версия схемы %d, ожидалась %d", version, len(sqliteMigrations))
+	}
+
+	employees, err := repo.LoadEmployees()
+	if err != nil {
+		t.Fatalf("LoadEmployees: %v", err)
+	}
+	if len(*employees) != 1 {
+		t.Fatalf("сотрудников %d, ожидался 1", len(*employees))
+	}
+	release := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	expected := map[string]DutyStats{
+		"support":   {Count: 3, LastDuty: time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)},
+		"express":   {Count: 2, LastDuty: release},
+		"instances": {Count: 1, LastDuty: release},
+	}
+	for duty, stats := range expected {
+		got := (*employees)[0].Duties[duty]
+		if got.Count != stats.Count || !got.LastDuty.Equal(stats.LastDuty) {
+			t.Errorf("%s: счетчики %+v, ожидались %+v", duty, got, stats)
+		}
+	}
+
+	// повторное открытие не применяет миграции заново
+	repo.Close()
+	reopened, err := OpenSQLiteRepository(path)
+	if err != nil {
+		t.Fatalf("повторное открытие: %v", err)
+	}
+	reopened.Close()
+}
+
+func TestMigrateJSONToSQLite(t *testing.T) {
+	state := testState(t)
+	events := DiffEvents(&State{Employees: &[]Employee{}, History: &DutyHistoryStorage{}}, state, Actor{Name: "test"}, FixedClock(testMonday))
+
+	jsonRepo := NewJSONRepository(t.TempDir())
+	if err := jsonRepo.Save(state, events); err != nil {
+		t.Fatalf("сохранение в JSON: %v", err)
+	}
+	sqliteRepo := openTestSQLite(t)
+
+	for i := 0; i < 2; i++ {
+		if err := MigrateJSONToSQLite(jsonRepo, sqliteRepo); err != nil {
+			t.Fatalf("миграция %d: %v", i+1, err)
+		}
+	}
+
+	assertSameState(t, loadState(t, sqliteRepo), state)
+	migrated, err := sqliteRepo.LoadEvents(EventFilter{})
+	if err != nil {
+		t.Fatalf("LoadEvents: %v", err)
+	}
+	// повторная миграция не задваивает журнал
+	if !sameJSON(migrated, events) {
+		t.Errorf("журнал после миграции: %d событий, ожидалось %d", len(migrated), len(events))
+	}
+}
+
+func TestSQLiteSaveIsAtomic(t *testing.T) {
+	repo := openTestSQLite(t)
+	state := testState(t)
+	if err := repo.Save(state, nil); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	saved := loadState(t, repo)
+
+	changed, err := CloneState(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	(*changed.Employees)[0].Name = "Анна Петрова"
+	// две записи об одной неделе нарушают первичный ключ weeks, история не сохранится
+	changed.History.History = append(changed.History.History, changed.History.History[0])
+	events := DiffEvents(state, changed, Actor{Name: "test"}, FixedClock(testMonday))
+	if len(events) == 0 {
+		t.Fatal("нет событий об изменении")
+	}
+
+	if err := repo.Save(changed, events); err == nil {
+		t.Fatal("Save сохранил историю с повторяющейся неделей")
+	}
+	assertSameState(t, loadState(t, repo), saved)
+	if journal, err := repo.LoadEvents(EventFilter{}); err != nil || len(journal) != 0 {
+		t.Errorf("журнал после неудачного сохранения: %d событий, ошибка %v", len(journal), err)
+	}
+}
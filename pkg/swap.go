@@ -0,0 +1,278 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Виды исправлений недели после формирования (WeekChange.Kind).
+const (
+	ChangeSwap    = "swap"    // два сотрудника поменялись дежурствами
+	ChangeReplace = "replace" // дежурство передано другому сотруднику
+)
+
+// SlotRef указывает на назначение сотрудника в неделе. Нулевая дата подходит к любому дню,
+// если у сотрудника на неделе одно такое дежурство.
+type SlotRef struct {
+	Duty       string
+	Date       time.Time
+	EmployeeId int
+}
+
+// AssignmentMove - назначение, переданное от одного сотрудника другому.
+type AssignmentMove struct {
+	Duty     string    `json:"duty"`
+	Date     time.Time `json:"date"`
+	FromId   int       `json:"from_id"`
+	FromName string    `json:"from_name"`
+	ToId     int       `json:"to_id"`
+	ToName   string    `json:"to_name"`
+}
+
+// WeekChange - запись журнала исправлений недели: кто и когда попросил изменить расписание.
+type WeekChange struct {
+	At          time.Time        `json:"at"`
+	Kind        string           `json:"kind"` // ChangeSwap или ChangeReplace
+	RequestedBy string           `json:"requested_by"`
+	Moves       []AssignmentMove `json:"moves"`
+}
+
+// findAssignment возвращает индекс назначения недели, на которое указывает ref.
+func (s *Scheduler) findAssignment(record *DutyHistory, ref SlotRef) (int, error) {
+	found := -1
+	for i, assignment := range record.Assignments {
+		if assignment.EmployeeId != ref.EmployeeId || assignment.Duty != ref.Duty {
+			continue
+		}
+		if !ref.Date.IsZero() && !dayOf(assignment.Date).Equal(dayOf(ref.Date)) {
+			continue
+		}
+		if found >= 0 {
+			return -1, fmt.Errorf("у сотрудника с Id: %d несколько дежурств %s на неделе с %s, укажите день",
+				ref.EmployeeId, s.DutyTitle(ref.Duty), record.Date.Format("2006-01-02"))
+		}
+		found = i
+	}
+	if found < 0 {
+		day := ""
+		if !ref.Date.IsZero() {
+			day = " " + ref.Date.Format("2006-01-02")
+		}
+		return -1, fmt.Errorf("у сотрудника с Id: %d нет дежурства %s%s на неделе с %s",
+			ref.EmployeeId, s.DutyTitle(ref.Duty), day, record.Date.Format("2006-01-02"))
+	}
+	return found, nil
+}
+
+// checkIncoming проверяет, может ли сотрудник принять назначение: он не уволен, не отсутствует
+// в дни слота (на еженедельном дежурстве - все рабочие дни недели, как при подборе), еще не стоит
+// на этом же дежурстве в этот день и на несовместимом дежурстве на этой неделе, и у него не набран
+// предел дежурств в месяц по истории history.
+func (s *Scheduler) checkIncoming(record *DutyHistory, history []DutyHistory, assignment Assignment, employee *Employee) error {
+	if employee.Status == StatusFired {
+		return fmt.Errorf("сотрудник %s уволен", employee.Name)
+	}
+	dutyType, known := s.dutyType(assignment.Duty)
+	sl := slot{dutyType: dutyType, date: assignment.Date, from: assignment.Date, to: assignment.Date}
+	if known {
+		sl = s.newSlot(dutyType, assignment.Date, record.Date)
+	}
+	if absence, ok := employee.AbsenceDuring(sl.from, sl.to); ok {
+		days := sl.from.Format("2006-01-02")
+		if !sl.to.Equal(sl.from) {
+			days = fmt.Sprintf("с %s по %s", days, sl.to.Format("2006-01-02"))
+		}
+		return fmt.Errorf("сотрудник %s отсутствует %s (%s)", employee.Name, days, absence.Kind)
+	}
+	if known {
+		if missing := employee.missingSkills(dutyType); len(missing) > 0 {
+			return fmt.Errorf("сотрудник %s не допущен к дежурству %s (нет навыков: %s)", employee.Name, dutyType.Title, strings.Join(missing, ", "))
+		}
+	}
+	if err := s.loadTeamDuties(); err != nil {
+		return err
+	}
+	if duty, ok := s.teamDuty(*employee, assignment.Date); ok {
+		return fmt.Errorf("сотрудник %s %s дежурит в команде %s (%s)", employee.Name, assignment.Date.Format("2006-01-02"), TeamTitle(duty.Team), duty.Duty)
+	}
+	if employee.blackout(assignment.Date) {
+		return fmt.Errorf("сотрудник %s не дежурит по дням %s", employee.Name, weekdaysRu[assignment.Date.Weekday()])
+	}
+	for _, other := range record.Assignments {
+		if other.EmployeeId == employee.Id && other.Duty == assignment.Duty && other.Date.Equal(assignment.Date) {
+			return fmt.Errorf("сотрудник %s уже дежурит %s %s", employee.Name, s.DutyTitle(assignment.Duty), assignment.Date.Format("2006-01-02"))
+		}
+		if other.EmployeeId == employee.Id && other.Duty != assignment.Duty && s.incompatible(other.Duty, assignment.Duty) {
+			return fmt.Errorf("сотрудник %s на этой неделе дежурит %s %s, это дежурство несовместимо с %s",
+				employee.Name, s.DutyTitle(other.Duty), other.Date.Format("2006-01-02"), s.DutyTitle(assignment.Duty))
+		}
+	}
+	if employee.Preferences != nil && employee.Preferences.MaxPerMonth > 0 {
+		n := monthlyDuties(employee.Id, assignment.Date, history, record.Assignments, record.Date)
+		if n >= employee.Preferences.MaxPerMonth {
+			return fmt.Errorf("у сотрудника %s в этом месяце уже набран предел дежурств (%d из %d)", employee.Name, n, employee.Preferences.MaxPerMonth)
+		}
+	}
+	return nil
+}
+
+// SwapAssignments меняет местами дежурства двух сотрудников на неделе week и записывает изменение
+// в журнал недели от имени requestedBy. Счетчики и даты последних дежурств переносятся между ними.
+func (s *Scheduler) SwapAssignments(employees *[]Employee, storage *DutyHistoryStorage, week time.Time, first, second SlotRef, requestedBy string) (WeekChange, error) {
+	if first.EmployeeId == second.EmployeeId {
+		return WeekChange{}, fmt.Errorf("сотрудник не может поменяться дежурством сам с собой")
+	}
+	record, err := s.changedWeek(storage, week, requestedBy)
+	if err != nil {
+		return WeekChange{}, err
+	}
+
+	i, err := s.findAssignment(record, first)
+	if err != nil {
+		return WeekChange{}, err
+	}
+	j, err := s.findAssignment(record, second)
+	if err != nil {
+		return WeekChange{}, err
+	}
+	firstEmployee, secondEmployee, err := findPair(employees, first.EmployeeId, second.EmployeeId)
+	if err != nil {
+		return WeekChange{}, err
+	}
+
+	// проверяем на неделе без меняющихся назначений, чтобы обмен одинаковыми дежурствами
+	// в разные дни не считался повтором
+	rest := *record
+	rest.Assignments = nil
+	for k, assignment := range record.Assignments {
+		if k != i && k != j {
+			rest.Assignments = append(rest.Assignments, assignment)
+		}
+	}
+	if err := s.checkIncoming(&rest, storage.History, record.Assignments[i], secondEmployee); err != nil {
+		return WeekChange{}, err
+	}
+	if err := s.checkIncoming(&rest, storage.History, record.Assignments[j], firstEmployee); err != nil {
+		return WeekChange{}, err
+	}
+
+	change := WeekChange{At: s.now(), Kind: ChangeSwap, RequestedBy: requestedBy}
+	change.Moves = s.moveAssignments(employees, storage, record, []int{i, j}, []*Employee{secondEmployee, firstEmployee})
+	record.Changes = append(record.Changes, change)
+	return change, nil
+}
+
+// ReplaceAssignment передает дежурство ref другому сотруднику и записывает изменение в журнал
+// недели от имени requestedBy. Счетчик и дата последнего дежурства переносятся на нового дежурного.
+func (s *Scheduler) ReplaceAssignment(employees *[]Employee, storage *DutyHistoryStorage, week time.Time, ref SlotRef, employeeId int, requestedBy string) (WeekChange, error) {
+	if ref.EmployeeId == employeeId {
+		return WeekChange{}, fmt.Errorf("дежурство уже назначено этому сотруднику")
+	}
+	record, err := s.changedWeek(storage, week, requestedBy)
+	if err != nil {
+		return WeekChange{}, err
+	}
+
+	i, err := s.findAssignment(record, ref)
+	if err != nil {
+		return WeekChange{}, err
+	}
+	_, replacement, err := findPair(employees, ref.EmployeeId, employeeId)
+	if err != nil {
+		return WeekChange{}, err
+	}
+	if err := s.checkIncoming(record, storage.History, record.Assignments[i], replacement); err != nil {
+		return WeekChange{}, err
+	}
+
+	change := WeekChange{At: s.now(), Kind: ChangeReplace, RequestedBy: requestedBy}
+	change.Moves = s.moveAssignments(employees, storage, record, []int{i}, []*Employee{replacement})
+	record.Changes = append(record.Changes, change)
+	return change, nil
+}
+
+// changedWeek возвращает неделю истории, которую собираются исправить.
+func (s *Scheduler) changedWeek(storage *DutyHistoryStorage, week time.Time, requestedBy string) (*DutyHistory, error) {
+	if requestedBy == "" {
+		return nil, fmt.Errorf("не указано, кто попросил изменить расписание")
+	}
+	record, ok := storage.FindWeek(week)
+	if !ok {
+		return nil, fmt.Errorf("неделя с %s не найдена в истории", week.Format("2006-01-02"))
+	}
+	return record, nil
+}
+
+// findPair возвращает двух сотрудников по Id.
+func findPair(employees *[]Employee, firstId, secondId int) (*Employee, *Employee, error) {
+	first := FindEmployeeById(employees, firstId)
+	if first == nil {
+		return nil, nil, fmt.Errorf("сотрудник с Id: %d не найден", firstId)
+	}
+	second := FindEmployeeById(employees, secondId)
+	if second == nil {
+		return nil, nil, fmt.Errorf("сотрудник с Id: %d не найден", secondId)
+	}
+	return first, second, nil
+}
+
+// moveAssignments передает назначения недели с индексами indexes сотрудникам to и переносит
+// счетчики. Даты последних дежурств пересчитываются, когда все назначения уже переписаны:
+// у отдавшего дежурство дата восстанавливается по истории, у принявшего сдвигается вперед.
+func (s *Scheduler) moveAssignments(employees *[]Employee, storage *DutyHistoryStorage, record *DutyHistory, indexes []int, to []*Employee) []AssignmentMove {
+	moves := make([]AssignmentMove, len(indexes))
+	for k, i := range indexes {
+		assignment := &record.Assignments[i]
+		moves[k] = AssignmentMove{
+			Duty:     assignment.Duty,
+			Date:     assignment.Date,
+			FromId:   assignment.EmployeeId,
+			FromName: assignment.EmployeeName,
+			ToId:     to[k].Id,
+			ToName:   to[k].Name,
+		}
+	}
+	for k, i := range indexes {
+		record.Assignments[i].EmployeeId = moves[k].ToId
+		record.Assignments[i].EmployeeName = moves[k].ToName
+	}
+
+	for _, move := range moves {
+		weight := 1
+		if dutyType, ok := s.dutyType(move.Duty); ok {
+			weight = dutyType.Weight
+		}
+
+		if from := FindEmployeeById(employees, move.FromId); from != nil {
+			stats := from.Duties[move.Duty]
+			stats.Count -= weight
+			if stats.Count < 0 {
+				stats.Count = 0
+			}
+			if stats.LastDuty.Equal(move.Date) {
+				stats.LastDuty = storage.lastAssignmentDate(move.FromId, move.Duty)
+			}
+			if from.Duties == nil {
+				from.Duties = map[string]DutyStats{}
+			}
+			from.Duties[move.Duty] = stats
+		}
+	}
+	for _, move := range moves {
+		dutyType, ok := s.dutyType(move.Duty)
+		if !ok {
+			dutyType = DutyType{Name: move.Duty, Weight: 1}
+		}
+		employee := FindEmployeeById(employees, move.ToId)
+		lastDuty := employee.Duties[move.Duty].LastDuty
+		employee.recordDuty(dutyType, move.Date)
+		if lastDuty.After(move.Date) {
+			// у принявшего есть дежурства позже этой недели
+			stats := employee.Duties[move.Duty]
+			stats.LastDuty = lastDuty
+			employee.Duties[move.Duty] = stats
+		}
+	}
+	return moves
+}
@@ -0,0 +1,186 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// testWeek формирует и утверждает неделю testMonday: Express - Анна, Instances - Борис, Support
+// по дням - Анна, Борис, Вера, Глеб, Дина. Ефим на неделе свободен.
+func testWeek(t *testing.T) (*Scheduler, []Employee, *DutyHistoryStorage) {
+	t.Helper()
+	scheduler := NewScheduler(FixedClock(testMonday.AddDate(0, 0, -3).Add(15*time.Hour)), nil)
+	employees := testEmployees("Анна", "Борис", "Вера", "Глеб", "Дина", "Ефим")
+	storage := &DutyHistoryStorage{}
+	if _, _, err := scheduler.GenerateWeek(&employees, storage); err != nil {
+		t.Fatalf("GenerateWeek: %v", err)
+	}
+	return scheduler, employees, storage
+}
+
+func TestReplaceAssignment(t *testing.T) {
+	scheduler, employees, storage := testWeek(t)
+	thursday := testMonday.AddDate(0, 0, 3)
+
+	change, err := scheduler.ReplaceAssignment(&employees, storage, testMonday, SlotRef{Duty: "express", EmployeeId: 1}, 6, "Анна")
+	if err != nil {
+		t.Fatalf("ReplaceAssignment: %v", err)
+	}
+
+	if got := assignmentsByDate(storage.History[0].Assignments)["express 2026-10-22"]; got != "Ефим" {
+		t.Errorf("Express назначен %q, ожидался Ефим", got)
+	}
+	if !change.At.Equal(scheduler.now()) || change.Kind != ChangeReplace || change.RequestedBy != "Анна" {
+		t.Errorf("запись об изменении %+v", change)
+	}
+	if len(storage.History[0].Changes) != 1 {
+		t.Errorf("записей об изменениях недели %d, ожидалась 1", len(storage.History[0].Changes))
+	}
+	// вес Express - 2: счетчик переходит целиком
+	if stats := employees[0].Duties["express"]; stats.Count != 0 || !stats.LastDuty.IsZero() {
+		t.Errorf("Анна после передачи дежурства: %+v", stats)
+	}
+	if stats := employees[5].Duties["express"]; stats.Count != 2 || !stats.LastDuty.Equal(thursday) {
+		t.Errorf("Ефим после приема дежурства: %+v", stats)
+	}
+}
+
+func TestReplaceAssignmentKeepsLaterLastDuty(t *testing.T) {
+	scheduler, employees, storage := testWeek(t)
+	later := testMonday.AddDate(0, 0, 14)
+	employees[5].Duties["support"] = DutyStats{Count: 2, LastDuty: later}
+
+	if _, err := scheduler.ReplaceAssignment(&employees, storage, testMonday, SlotRef{Duty: "support", Date: testMonday, EmployeeId: 1}, 6, "Анна"); err != nil {
+		t.Fatalf("ReplaceAssignment: %v", err)
+	}
+	if stats := employees[5].Duties["support"]; stats.Count != 4 || !stats.LastDuty.Equal(later) {
+		t.Errorf("Ефим после приема дежурства: %+v, последнее дежурство должно остаться %s", stats, later)
+	}
+	// у Анны остается Express на этой неделе, а Support - только переданный
+	if stats := employees[0].Duties["support"]; stats.Count != 0 {
+		t.Errorf("Анна после передачи дежурства: %+v", stats)
+	}
+}
+
+func TestSwapAssignments(t *testing.T) {
+	scheduler, employees, storage := testWeek(t)
+	friday := testMonday.AddDate(0, 0, 4)
+
+	change, err := scheduler.SwapAssignments(&employees, storage, testMonday,
+		SlotRef{Duty: "support", EmployeeId: 1}, SlotRef{Duty: "support", EmployeeId: 5}, "Дина")
+	if err != nil {
+		t.Fatalf("SwapAssignments: %v", err)
+	}
+
+	got := assignmentsByDate(storage.History[0].Assignments)
+	if got["support 2026-10-19"] != "Дина" || got["support 2026-10-23"] != "Анна" {
+		t.Errorf("после обмена: %v", got)
+	}
+	if !change.At.Equal(scheduler.now()) || change.Kind != ChangeSwap || len(change.Moves) != 2 {
+		t.Errorf("запись об изменении %+v", change)
+	}
+	if stats := employees[0].Duties["support"]; stats.Count != 2 || !stats.LastDuty.Equal(friday) {
+		t.Errorf("Анна после обмена: %+v", stats)
+	}
+	if stats := employees[4].Duties["support"]; stats.Count != 2 || !stats.LastDuty.Equal(testMonday) {
+		t.Errorf("Дина после обмена: %+v", stats)
+	}
+}
+
+func TestChangeRejected(t *testing.T) {
+	mondayOff := []Absence{{Kind: StatusVacation, Start: testMonday, End: testMonday}}
+	tests := []struct {
+		name    string
+		prepare func(employees []Employee)
+		change  func(scheduler *Scheduler, employees *[]Employee, storage *DutyHistoryStorage) error
+		err     string // подстрока ошибки, пустая - изменение допустимо
+	}{
+		{
+			name:    "отсутствие в любой рабочий день недели еженедельного дежурства",
+			prepare: func(employees []Employee) { employees[5].Absences = mondayOff },
+			change: func(scheduler *Scheduler, employees *[]Employee, storage *DutyHistoryStorage) error {
+				_, err := scheduler.ReplaceAssignment(employees, storage, testMonday, SlotRef{Duty: "express", EmployeeId: 1}, 6, "Анна")
+				return err
+			},
+			err: "отсутствует с 2026-10-19 по 2026-10-23",
+		},
+		{
+			name:    "отсутствие в другой день не мешает ежедневному дежурству",
+			prepare: func(employees []Employee) { employees[5].Absences = mondayOff },
+			change: func(scheduler *Scheduler, employees *[]Employee, storage *DutyHistoryStorage) error {
+				_, err := scheduler.ReplaceAssignment(employees, storage, testMonday, SlotRef{Duty: "support", EmployeeId: 4}, 6, "Глеб")
+				return err
+			},
+		},
+		{
+			name:    "отсутствие в день ежедневного дежурства",
+			prepare: func(employees []Employee) { employees[5].Absences = mondayOff },
+			change: func(scheduler *Scheduler, employees *[]Employee, storage *DutyHistoryStorage) error {
+				_, err := scheduler.ReplaceAssignment(employees, storage, testMonday, SlotRef{Duty: "support", EmployeeId: 1}, 6, "Анна")
+				return err
+			},
+			err: "отсутствует 2026-10-19",
+		},
+		{
+			name:    "уволенный сотрудник",
+			prepare: func(employees []Employee) { employees[5].Status = StatusFired },
+			change: func(scheduler *Scheduler, employees *[]Employee, storage *DutyHistoryStorage) error {
+				_, err := scheduler.ReplaceAssignment(employees, storage, testMonday, SlotRef{Duty: "support", EmployeeId: 1}, 6, "Анна")
+				return err
+			},
+			err: "уволен",
+		},
+		{
+			name: "несовместимые релизы",
+			change: func(scheduler *Scheduler, employees *[]Employee, storage *DutyHistoryStorage) error {
+				_, err := scheduler.ReplaceAssignment(employees, storage, testMonday, SlotRef{Duty: "instances", EmployeeId: 2}, 1, "Борис")
+				return err
+			},
+			err: "несовместимо",
+		},
+		{
+			name: "обмен с самим собой",
+			change: func(scheduler *Scheduler, employees *[]Employee, storage *DutyHistoryStorage) error {
+				_, err := scheduler.SwapAssignments(employees, storage, testMonday, SlotRef{Duty: "support", EmployeeId: 1}, SlotRef{Duty: "express", EmployeeId: 1}, "Анна")
+				return err
+			},
+			err: "сам с собой",
+		},
+		{
+			name: "без автора изменения",
+			change: func(scheduler *Scheduler, employees *[]Employee, storage *DutyHistoryStorage) error {
+				_, err := scheduler.ReplaceAssignment(employees, storage, testMonday, SlotRef{Duty: "support", EmployeeId: 1}, 6, "")
+				return err
+			},
+			err: "кто попросил",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduler, employees, storage := testWeek(t)
+			if tt.prepare != nil {
+				tt.prepare(employees)
+			}
+			before, err := CloneState(&State{Employees: &employees, History: storage})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = tt.change(scheduler, &employees, storage)
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("изменение отклонено: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("ошибка %v, ожидалась с %q", err, tt.err)
+			}
+			if !sameJSON(employees, *before.Employees) || !sameJSON(storage, before.History) {
+				t.Error("отклоненное изменение затронуло данные")
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TeamsDir - подкаталог каталога данных с командами. У каждой команды свой каталог с теми же файлами,
+// что и у каталога данных: config.json (дежурства, каналы публикации), employees.json, history.json,
+// events.jsonl или schedule.db. Сам каталог данных - команда по умолчанию с пустым именем.
+const TeamsDir = "teams"
+
+var teamNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ValidTeamName проверяет имя команды: оно же имя ее каталога.
+func ValidTeamName(name string) error {
+	if !teamNamePattern.MatchString(name) {
+		return fmt.Errorf("неверное имя команды %q: латинские буквы в нижнем регистре, цифры, - и _", name)
+	}
+	return nil
+}
+
+// TeamDir возвращает каталог команды name в каталоге данных dataDir.
+func TeamDir(dataDir, name string) string {
+	if name == "" {
+		return dataDir
+	}
+	return filepath.Join(dataDir, TeamsDir, name)
+}
+
+// TeamTitle возвращает название команды для сообщений.
+func TeamTitle(name string) string {
+	if name == "" {
+		return "основная"
+	}
+	return name
+}
+
+// ListTeams возвращает имена команд каталога данных по алфавиту, первой - команду по умолчанию ("").
+func ListTeams(dataDir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(dataDir, TeamsDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return []string{""}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать список команд: %w", err)
+	}
+	teams := []string{""}
+	for _, entry := range entries {
+		if entry.IsDir() && ValidTeamName(entry.Name()) == nil {
+			teams = append(teams, entry.Name())
+		}
+	}
+	sort.Strings(teams[1:])
+	return teams, nil
+}
+
+// Teams - команды одного каталога данных. Планировщику они нужны, чтобы общий сотрудник
+// (Employee.Member) не дежурил в двух командах в один день.
+type Teams struct {
+	DataDir string
+	Current string // команда, для которой составляется расписание
+	// Open открывает данные каталога команды только для чтения; nil без ошибки - у команды еще нет данных.
+	// Данные других команд читаются без их блокировки: файлы и база меняются целиком или в транзакции.
+	Open func(dir string) (TeamReader, error)
+}
+
+// TeamReader читает сотрудников и историю команды, ничего не меняя в ее каталоге.
+type TeamReader interface {
+	LoadEmployees() (*[]Employee, error)
+	LoadDutyHistory() (*DutyHistoryStorage, error)
+	Close() error
+}
+
+// OpenTeamJSON открывает JSON-файлы команды только для чтения, если в ее каталоге уже есть сотрудники.
+// Отсутствующие файлы, в отличие от LoadEmployees и LoadDutyHistory, не создаются.
+func OpenTeamJSON(dir string) (TeamReader, error) {
+	repo := NewJSONRepository(dir)
+	if _, err := os.Stat(repo.EmployeesPath); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return teamJSON{repo}, nil
+}
+
+type teamJSON struct {
+	repo *JSONRepository
+}
+
+func (t teamJSON) LoadEmployees() (*[]Employee, error) {
+	var employees []Employee
+	if err := readJSONFile(t.repo.EmployeesPath, &employees); err != nil {
+		return nil, err
+	}
+	return &employees, nil
+}
+
+func (t teamJSON) LoadDutyHistory() (*DutyHistoryStorage, error) {
+	var storage DutyHistoryStorage
+	if err := readJSONFile(t.repo.HistoryPath, &storage); err != nil {
+		return nil, err
+	}
+	return &storage, nil
+}
+
+func (t teamJSON) Close() error {
+	return nil
+}
+
+// readJSONFile декодирует файл в v. Отсутствующий или пустой файл оставляет v пустым.
+func readJSONFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) || len(data) == 0 && err == nil {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("не удалось прочитать файл: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("не удалось декодировать %s: %w", path, err)
+	}
+	return nil
+}
+
+// TeamDuty - дежурство общего сотрудника в одной из команд.
+type TeamDuty struct {
+	Team         string
+	Member       string
+	EmployeeName string
+	Duty         string // DutyType.Name в этой команде
+	Date         time.Time
+}
+
+// Duties загружает дежурства общих сотрудников всех команд, с текущей или без нее.
+func (t *Teams) Duties(withCurrent bool) ([]TeamDuty, error) {
+	names, err := ListTeams(t.DataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var duties []TeamDuty
+	for _, name := range names {
+		if name == t.Current && !withCurrent {
+			continue
+		}
+		teamDuties, err := t.load(name)
+		if err != nil {
+			return nil, fmt.Errorf("команда %s: %w", TeamTitle(name), err)
+		}
+		duties = append(duties, teamDuties...)
+	}
+	return duties, nil
+}
+
+func (t *Teams) load(name string) ([]TeamDuty, error) {
+	open := t.Open
+	if open == nil {
+		open = OpenTeamJSON
+	}
+	repo, err := open(TeamDir(t.DataDir, name))
+	if err != nil || repo == nil {
+		return nil, err
+	}
+	defer repo.Close()
+
+	employees, err := repo.LoadEmployees()
+	if err != nil {
+		return nil, err
+	}
+	members := map[int]Employee{}
+	for _, employee := range *employees {
+		if employee.Member != "" {
+			members[employee.Id] = employee
+		}
+	}
+	if len(members) == 0 {
+		return nil, nil
+	}
+
+	storage, err := repo.LoadDutyHistory()
+	if err != nil {
+		return nil, err
+	}
+	var duties []TeamDuty
+	for _, record := range storage.History {
+		for _, assignment := range record.Assignments {
+			if employee, ok := members[assignment.EmployeeId]; ok {
+				duties = append(duties, TeamDuty{
+					Team:         name,
+					Member:       employee.Member,
+					EmployeeName: employee.Name,
+					Duty:         assignment.Duty,
+					Date:         assignment.Date,
+				})
+			}
+		}
+	}
+	return duties, nil
+}
+
+// loadTeamDuties загружает дежурства общих сотрудников в других командах, если их еще не загружали.
+func (s *Scheduler) loadTeamDuties() error {
+	if s.Teams == nil || s.teamDuties != nil {
+		return nil
+	}
+	duties, err := s.Teams.Duties(false)
+	if err != nil {
+		return err
+	}
+	s.teamDuties = append([]TeamDuty{}, duties...)
+	return nil
+}
+
+// teamDuty возвращает дежурство сотрудника в другой команде в день date.
+func (s *Scheduler) teamDuty(employee Employee, date time.Time) (TeamDuty, bool) {
+	if employee.Member == "" {
+		return TeamDuty{}, false
+	}
+	for _, duty := range s.teamDuties {
+		if duty.Member == employee.Member && dayOf(duty.Date).Equal(dayOf(date)) {
+			return duty, true
+		}
+	}
+	return TeamDuty{}, false
+}
+
+// TeamConflict - общий сотрудник дежурит в нескольких командах в один день.
+type TeamConflict struct {
+	Member string
+	Date   time.Time
+	Duties []TeamDuty
+}
+
+// TeamConflicts находит дни, в которые общий сотрудник дежурит больше чем в одной команде,
+// начиная с from (нулевая дата - за всю историю). Конфликты упорядочены по дате.
+func TeamConflicts(duties []TeamDuty, from time.Time) []TeamConflict {
+	type key struct {
+		member string
+		day    time.Time
+	}
+	groups := map[key][]TeamDuty{}
+	var keys []key
+	for _, duty := range duties {
+		if !from.IsZero() && dayOf(duty.Date).Before(dayOf(from)) {
+			continue
+		}
+		k := key{duty.Member, dayOf(duty.Date)}
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], duty)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].day.Equal(keys[j].day) {
+			return keys[i].day.Before(keys[j].day)
+		}
+		return keys[i].member < keys[j].member
+	})
+
+	var conflicts []TeamConflict
+	for _, k := range keys {
+		teams := map[string]bool{}
+		for _, duty := range groups[k] {
+			teams[duty.Team] = true
+		}
+		if len(teams) > 1 {
+			conflicts = append(conflicts, TeamConflict{Member: k.member, Date: k.day, Duties: groups[k]})
+		}
+	}
+	return conflicts
+}
+
+// FormatTeamConflicts возвращает конфликты в виде текста, по строке на конфликт.
+func FormatTeamConflicts(conflicts []TeamConflict) string {
+	var b strings.Builder
+	for _, conflict := range conflicts {
+		parts := make([]string, len(conflict.Duties))
+		for i, duty := range conflict.Duties {
+			parts[i] = fmt.Sprintf("%s: %s (%s)", TeamTitle(duty.Team), duty.Duty, duty.EmployeeName)
+		}
+		fmt.Fprintf(&b, "%s | %s | %s\n", conflict.Date.Format("2006-01-02"), conflict.Member, strings.Join(parts, "; "))
+	}
+	return b.String()
+}
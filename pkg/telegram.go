@@ -0,0 +1,177 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// TelegramTokenEnv - переменная окружения с токеном бота, чтобы не хранить его в config.json.
+const TelegramTokenEnv = "SCHEDULE_TELEGRAM_TOKEN"
+
+// TelegramConfig - раздел telegram в config.json.
+type TelegramConfig struct {
+	Token string `json:"token"` // токен бота, переопределяется SCHEDULE_TELEGRAM_TOKEN
+	// Admins - администраторы по чатам: id чата -> id пользователей, которым в этом чате
+	// доступны формирование и публикация расписания и смена статуса других сотрудников.
+	// Сообщения из чатов, которых здесь нет, бот игнорирует.
+	Admins             map[string][]int64 `json:"admins"`
+	Template           string             `json:"template"`             // шаблон расписания, по умолчанию telegram
+	PollTimeoutSeconds int                `json:"poll_timeout_seconds"` // таймаут long polling, по умолчанию 30
+	APIURL             string             `json:"api_url"`              // адрес Bot API, по умолчанию https://api.telegram.org
+}
+
+// TelegramUpdate - входящее обновление Bot API. Бот обрабатывает только сообщения.
+type TelegramUpdate struct {
+	UpdateId int64            `json:"update_id"`
+	Message  *TelegramMessage `json:"message"`
+}
+
+type TelegramMessage struct {
+	MessageId int64         `json:"message_id"`
+	From      *TelegramUser `json:"from"`
+	Chat      TelegramChat  `json:"chat"`
+	Text      string        `json:"text"`
+}
+
+type TelegramUser struct {
+	Id        int64  `json:"id"`
+	Username  string `json:"username"`
+	FirstName string `json:"first_name"`
+}
+
+type TelegramChat struct {
+	Id int64 `json:"id"`
+}
+
+// TelegramAPI - методы Bot API, которыми пользуется бот.
+type TelegramAPI interface {
+	// GetUpdates ждет новые обновления не дольше timeout (long polling).
+	GetUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]TelegramUpdate, error)
+	// SendMessage отправляет сообщение в чат. parseMode - "MarkdownV2", "HTML" или пусто для простого текста.
+	SendMessage(ctx context.Context, chatId int64, text, parseMode string) error
+}
+
+// TelegramClient - клиент Bot API поверх HTTP.
+type TelegramClient struct {
+	BaseURL string // https://api.telegram.org или адрес тестового сервера
+	Token   string
+	HTTP    *http.Client
+}
+
+// NewTelegramClient создает клиент Bot API. Пустой baseURL означает официальный сервер.
+func NewTelegramClient(baseURL, token string) *TelegramClient {
+	if baseURL == "" {
+		baseURL = "https://api.telegram.org"
+	}
+	return &TelegramClient{BaseURL: baseURL, Token: token, HTTP: &http.Client{}}
+}
+
+// call вызывает метод Bot API и декодирует поле result ответа в result.
+func (c *TelegramClient) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/bot"+c.Token+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := c.HTTP.Do(request)
+	if err != nil {
+		// в тексте ошибки net/http есть адрес запроса вместе с токеном
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("telegram %s: запрос не выполнен", method)
+	}
+	defer response.Body.Close()
+
+	var decoded struct {
+		Ok          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&decoded); err != nil {
+		return fmt.Errorf("telegram %s: не удалось декодировать ответ (%s): %w", method, response.Status, err)
+	}
+	if !decoded.Ok {
+		return fmt.Errorf("telegram %s: %s", method, decoded.Description)
+	}
+	if result != nil {
+		return json.Unmarshal(decoded.Result, result)
+	}
+	return nil
+}
+
+func (c *TelegramClient) GetUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]TelegramUpdate, error) {
+	// запрос живет чуть дольше, чем сервер держит long polling
+	ctx, cancel := context.WithTimeout(ctx, timeout+10*time.Second)
+	defer cancel()
+
+	params := map[string]interface{}{
+		"offset":          offset,
+		"timeout":         int(timeout / time.Second),
+		"allowed_updates": []string{"message"},
+	}
+	var updates []TelegramUpdate
+	err := c.call(ctx, "getUpdates", params, &updates)
+	return updates, err
+}
+
+func (c *TelegramClient) SendMessage(ctx context.Context, chatId int64, text, parseMode string) error {
+	params := map[string]interface{}{
+		"chat_id": chatId,
+		"text":    text,
+	}
+	if parseMode != "" {
+		params["parse_mode"] = parseMode
+	}
+	return c.call(ctx, "sendMessage", params, nil)
+}
+
+// TelegramPublisher публикует расписание в чат Telegram.
+type TelegramPublisher struct {
+	API       TelegramAPI
+	ChatId    int64
+	ParseMode string
+}
+
+func (p *TelegramPublisher) Publish(ctx context.Context, message string) error {
+	return p.API.SendMessage(ctx, p.ChatId, message, p.ParseMode)
+}
+
+func (p *TelegramPublisher) Target() string {
+	return "telegram:" + strconv.FormatInt(p.ChatId, 10)
+}
+
+// telegramParseModes - режимы разметки Telegram для встроенных шаблонов, размеченных под него.
+var telegramParseModes = map[string]string{
+	"telegram": "MarkdownV2",
+	"html":     "HTML",
+}
+
+// TelegramRenderer возвращает рендерер сообщений для Telegram и режим разметки. Шаблон берется
+// из telegram.template, по умолчанию встроенный telegram. Режим выбирается по шаблону: telegram
+// отправляется в MarkdownV2, html - в HTML, остальные шаблоны и свои файлы - без разметки.
+func TelegramRenderer(config TelegramConfig, configDir string) (*Renderer, string, error) {
+	template := config.Template
+	if template == "" {
+		template = "telegram"
+	}
+	renderer, err := NewRenderer(template, configDir)
+	if err != nil {
+		return nil, "", err
+	}
+	parseMode := ""
+	if containsString(BuiltinTemplates(), template) {
+		parseMode = telegramParseModes[template]
+	}
+	return renderer, parseMode, nil
+}
@@ -1,46 +1,65 @@
 package pkg
 
 import (
-	"errors"
 	"fmt"
-	"time"
+	"strings"
 )
 
-// UpdateEmployeeStatus обновляет статус сотрудника по его имени.
-func UpdateEmployeeStatus(employees *[]Employee, id int, status string) error {
-	for i, employee := range *employees {
-		if employee.Id == id {
-			(*employees)[i].Status = status
-			return nil
-		}
+// ValidStatus проверяет, что статус является одним из известных значений.
+func ValidStatus(status string) bool {
+	switch status {
+	case StatusAvailable, StatusSick, StatusVacation, StatusFired:
+		return true
 	}
-	return fmt.Errorf("сотрудник с Id: %d не найден", id)
+	return false
 }
 
-// UpdateEmployeeInList обновляет информацию о сотруднике в списке сотрудников.
-func updateEmployeeInList(employees *[]Employee, updatedEmployee *Employee) error {
-	for i, emp := range *employees {
-		if emp.Name == updatedEmployee.Name {
-			(*employees)[i] = *updatedEmployee
-			return nil
+// UpdateEmployeeStatus обновляет статус сотрудника по его Id.
+// Статусы sick и vacation заводят бессрочное отсутствие с сегодняшнего дня,
+// available закрывает все бессрочные отсутствия, fired увольняет сотрудника.
+func (s *Scheduler) UpdateEmployeeStatus(employees *[]Employee, id int, status string) error {
+	if !ValidStatus(status) {
+		return fmt.Errorf("неизвестный статус: %s", status)
+	}
+
+	for i, employee := range *employees {
+		if employee.Id != id {
+			continue
+		}
+
+		today := dayOf(s.now())
+		switch status {
+		case StatusFired:
+			(*employees)[i].Status = StatusFired
+		case StatusAvailable:
+			closeOpenAbsences(&(*employees)[i], today)
+			(*employees)[i].Status = StatusAvailable
+		default:
+			// новое бессрочное отсутствие заменяет предыдущее
+			closeOpenAbsences(&(*employees)[i], today)
+			(*employees)[i].Status = StatusAvailable
+			(*employees)[i].Absences = append((*employees)[i].Absences, Absence{Kind: status, Start: today})
 		}
+		return nil
 	}
-	return errors.New("сотрудник с именем " + updatedEmployee.Name + " не найден")
+	return fmt.Errorf("сотрудник с Id: %d не найден", id)
 }
 
 // AddScheduleToHistory добавляет расписание на неделю в DutyHistoryStorage, чтобы сохранить исторические данные.
-// В эту функцию надо передавать слайс только с теми дежурными, кто дежурит на предстоящей неделе.
-func AddScheduleToHistory(employees *[]Employee, storage *DutyHistoryStorage) {
-	// Сохраняем текущие значения счетчиков в истории
+// Вместе с назначениями сохраняется разбор их подбора. Если неделя уже есть в истории, ее запись заменяется.
+func (s *Scheduler) AddScheduleToHistory(schedule WeekSchedule, storage *DutyHistoryStorage, status string) {
 	currentHistory := DutyHistory{
-		Date:      nextMonday(), // Дата начала недели для которой сформировали расписание
-		Employees: make([]Employee, len(*employees)),
+		Date:        s.nextMonday(), // Дата начала недели для которой сформировали расписание
+		Status:      status,
+		Assignments: make([]Assignment, len(schedule.Assignments)),
+		Decisions:   schedule.Decisions,
+		Shadows:     schedule.Shadows,
 	}
-	copy(currentHistory.Employees, *employees)
+	copy(currentHistory.Assignments, schedule.Assignments)
 
 	// проверить, есть ли в storage.History.Dates дата начала недели для которой сформировали расписание и если есть, то удаляем этот элемент
 	for i, history := range storage.History {
-		if history.Date == currentHistory.Date {
+		if history.Date.Equal(currentHistory.Date) {
 			storage.History = append(storage.History[:i], storage.History[i+1:]...)
 			break
 		}
@@ -48,7 +67,12 @@ func AddScheduleToHistory(employees *[]Employee, storage *DutyHistoryStorage) {
 	storage.History = append(storage.History, currentHistory)
 }
 
-func ResetDutyCounters(employees *[]Employee, storage *DutyHistoryStorage) bool {
+// ResetDutyCounters сбрасывает счетчики дежурств с ResetCounters, если с последнего сброса прошло
+// 90 дней. Счетчики остальных дежурств не сбрасываются: их нагрузка считается по окну справедливости.
+func (s *Scheduler) ResetDutyCounters(employees *[]Employee, storage *DutyHistoryStorage) bool {
+	if !s.resetsCounters() {
+		return false
+	}
 	reseted := false
 
 	// проверить существует ли storage и storage.LastResetDate
@@ -58,23 +82,60 @@ func ResetDutyCounters(employees *[]Employee, storage *DutyHistoryStorage) bool
 
 	// Если storage.LastResetDate не установлена, то устанавливаем ее на текущую дату
 	if storage.LastResetDate.IsZero() {
-		storage.LastResetDate = time.Now()
+		storage.LastResetDate = s.now()
 	}
 
 	// Проверяем, прошло ли 90 дней с момента последнего сброса счетчиков
-	if time.Since(storage.LastResetDate).Hours() >= 90*24 {
-		// Сброс счетчиков для каждого сотрудника
-		for i := range *employees {
-			(*employees)[i].SupportDutyCount = 0
-			(*employees)[i].ExpressDutyCount = 0
-			(*employees)[i].InstancesDutyCount = 0
+	if s.now().Sub(storage.LastResetDate).Hours() >= 90*24 {
+		reseted = s.ForceResetDutyCounters(employees, storage)
+	}
+
+	return reseted
+}
+
+// ForceResetDutyCounters сбрасывает счетчики дежурств с ResetCounters независимо от даты последнего сброса.
+// Уже сформированные недели с дежурствами не раньше дня сброса остаются в счетчиках: они входят
+// в новый период, как их посчитает RecomputeStats. Возвращает false, если таких дежурств нет.
+func (s *Scheduler) ForceResetDutyCounters(employees *[]Employee, storage *DutyHistoryStorage) bool {
+	if !s.resetsCounters() {
+		return false
+	}
+	for i := range *employees {
+		for name, stats := range (*employees)[i].Duties {
+			if dutyType, ok := s.dutyType(name); ok && dutyType.ResetCounters {
+				stats.Count = 0
+				(*employees)[i].Duties[name] = stats
+			}
 		}
+	}
 
-		storage.LastResetDate = time.Now()
-		reseted = true
+	storage.LastResetDate = s.now()
+	for _, record := range storage.History {
+		for _, assignment := range record.Assignments {
+			dutyType, ok := s.dutyType(assignment.Duty)
+			employee := FindEmployeeById(employees, assignment.EmployeeId)
+			if !ok || !dutyType.ResetCounters || employee == nil || dayOf(assignment.Date).Before(dayOf(storage.LastResetDate)) {
+				continue
+			}
+			if employee.Duties == nil {
+				employee.Duties = map[string]DutyStats{}
+			}
+			stats := employee.Duties[assignment.Duty]
+			stats.Count += dutyType.Weight
+			employee.Duties[assignment.Duty] = stats
+		}
 	}
+	return true
+}
 
-	return reseted
+// resetsCounters сообщает, что хотя бы у одного дежурства счетчики сбрасываются раз в 90 дней.
+func (s *Scheduler) resetsCounters() bool {
+	for _, dutyType := range s.DutyTypes {
+		if dutyType.ResetCounters {
+			return true
+		}
+	}
+	return false
 }
 
 func getMaxId(employees *[]Employee) int {
@@ -87,17 +148,64 @@ func getMaxId(employees *[]Employee) int {
 	return maxId
 }
 
-func AddNewEmployee(employees *[]Employee, name string) {
+// AddNewEmployee добавляет сотрудника. Дата последнего дежурства ставится на 14 дней назад,
+// чтобы новичка можно было назначать сразу, но не раньше тех, кто давно не дежурил.
+func (s *Scheduler) AddNewEmployee(employees *[]Employee, name string) {
+	lastDuty := startOfDay(s.now()).AddDate(0, 0, -14)
+
 	newEmployee := Employee{
-		Id:                 getMaxId(employees) + 1,
-		Name:               name,
-		SupportLastDuty:    time.Now().Add(-14 * 24 * time.Hour).Truncate(24 * time.Hour), // устанавливаем на 14 дней назад
-		ReleaseLastDuty:    time.Now().Add(-14 * 24 * time.Hour).Truncate(24 * time.Hour), // устанавливаем на 14 дней назад
-		Status:             StatusAvailable,
-		SupportDutyCount:   0,
-		ExpressDutyCount:   0,
-		InstancesDutyCount: 0,
+		Id:     getMaxId(employees) + 1,
+		Name:   name,
+		Status: StatusAvailable,
+		Duties: map[string]DutyStats{},
+	}
+	for _, dutyType := range s.DutyTypes {
+		newEmployee.Duties[dutyType.Name] = DutyStats{LastDuty: lastDuty}
 	}
 
 	*employees = append(*employees, newEmployee)
 }
+
+// LinkEmployeeTelegram привязывает сотрудника к пользователю Telegram. Один пользователь
+// может быть привязан только к одному сотруднику, 0 снимает привязку.
+func LinkEmployeeTelegram(employees *[]Employee, id int, telegramUserId int64) error {
+	if other, ok := FindEmployeeByTelegram(employees, telegramUserId); ok && other.Id != id {
+		return fmt.Errorf("пользователь Telegram %d уже привязан к сотруднику %s", telegramUserId, other.Name)
+	}
+	employee := FindEmployeeById(employees, id)
+	if employee == nil {
+		return fmt.Errorf("сотрудник с Id: %d не найден", id)
+	}
+	employee.TelegramUserId = telegramUserId
+	return nil
+}
+
+// LinkEmployeeMember связывает сотрудника с записями того же человека в других командах по ключу member.
+// Пустой ключ снимает связь. В одной команде ключ принадлежит одному сотруднику.
+func LinkEmployeeMember(employees *[]Employee, id int, member string) error {
+	member = strings.TrimSpace(member)
+	for _, other := range *employees {
+		if member != "" && other.Member == member && other.Id != id {
+			return fmt.Errorf("ключ %q уже у сотрудника %s", member, other.Name)
+		}
+	}
+	employee := FindEmployeeById(employees, id)
+	if employee == nil {
+		return fmt.Errorf("сотрудник с Id: %d не найден", id)
+	}
+	employee.Member = member
+	return nil
+}
+
+// FindEmployeeByTelegram ищет сотрудника по id пользователя Telegram.
+func FindEmployeeByTelegram(employees *[]Employee, telegramUserId int64) (*Employee, bool) {
+	if telegramUserId == 0 {
+		return nil, false
+	}
+	for i := range *employees {
+		if (*employees)[i].TelegramUserId == telegramUserId && (*employees)[i].Status != StatusFired {
+			return &(*employees)[i], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,539 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed web
+var webFiles embed.FS
+
+// webTemplates - страницы веб-интерфейса. Шаблоны встроены в программу, поэтому разбираются всегда.
+var webTemplates = template.Must(template.New("").Funcs(template.FuncMap{
+	"date": func(t time.Time) string { return t.Format("02.01.2006") },
+	"weekBlock": func(title string, week webWeek) interface{} {
+		return struct {
+			Title string
+			Week  webWeek
+		}{title, week}
+	},
+}).ParseFS(webFiles, "web/*.html"))
+
+// webCookie - cookie с токеном доступа к веб-интерфейсу.
+const webCookie = "schedule_token"
+
+// WebUI - веб-интерфейс поверх Service: текущая и следующая недели, календарь прошлых дежурств,
+// сотрудники со счетчиками и формирование следующей недели с предпросмотром изменений.
+// Доступ - по тем же токенам, что и у API: с ролью read только просмотр, с ролью admin - изменения.
+type WebUI struct {
+	Service *Service
+	Tokens  []APIToken
+	Clock   Clock
+}
+
+// NewWebUI создает веб-интерфейс.
+func NewWebUI(service *Service, tokens []APIToken) *WebUI {
+	return &WebUI{Service: service, Tokens: tokens, Clock: SystemClock{}}
+}
+
+// webPage - общие данные всех страниц.
+type webPage struct {
+	Title   string
+	Admin   bool
+	Message string // сообщение об успешном действии
+	Error   string
+	actor   Actor // автор изменений для журнала
+}
+
+func (ui *WebUI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/static/") {
+		static, _ := fs.Sub(webFiles, "web")
+		http.FileServer(http.FS(static)).ServeHTTP(w, r)
+		return
+	}
+	if r.URL.Path == "/login" {
+		ui.login(w, r)
+		return
+	}
+
+	token, ok := ui.authenticate(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	page := webPage{Admin: token.Role == RoleAdmin, Message: r.URL.Query().Get("msg")}
+	page.actor = Actor{Name: "web:" + token.Name, Reason: r.Method + " " + r.URL.Path}
+
+	if r.Method == http.MethodPost {
+		if !sameOrigin(r) {
+			ui.fail(w, page, &apiError{status: http.StatusForbidden, err: errors.New("запрос отправлен с другого сайта")})
+			return
+		}
+		if r.URL.Path == "/logout" {
+			http.SetCookie(w, &http.Cookie{Name: webCookie, Path: "/", MaxAge: -1})
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		if !page.Admin {
+			ui.fail(w, page, &apiError{status: http.StatusForbidden, err: errors.New("для изменений нужен токен с ролью admin")})
+			return
+		}
+	}
+
+	var err error
+	switch {
+	case r.URL.Path == "/" && r.Method == http.MethodGet:
+		err = ui.index(w, page)
+	case r.URL.Path == "/history" && r.Method == http.MethodGet:
+		err = ui.history(w, r, page)
+	case r.URL.Path == "/generate" && r.Method == http.MethodGet:
+		err = ui.preview(w, page, "")
+	case r.URL.Path == "/generate" && r.Method == http.MethodPost:
+		err = ui.generate(w, r, page)
+	case strings.HasPrefix(r.URL.Path, "/employees/") && r.Method == http.MethodPost:
+		err = ui.employeeAction(w, r, page)
+	default:
+		err = notFound("страница %s не найдена", r.URL.Path)
+	}
+	if err != nil {
+		ui.fail(w, page, err)
+	}
+}
+
+// authenticate берет токен из cookie или заголовка Authorization (за обратным прокси).
+func (ui *WebUI) authenticate(r *http.Request) (APIToken, bool) {
+	if cookie, err := r.Cookie(webCookie); err == nil {
+		return findToken(ui.Tokens, cookie.Value)
+	}
+	return findToken(ui.Tokens, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+}
+
+// sameOrigin проверяет, что форма отправлена со страницы этого же сервера. Cookie с SameSite=Strict
+// уже не уходит с чужих сайтов, проверка Origin защищает старые браузеры.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || origin == "null" {
+		return r.Header.Get("Sec-Fetch-Site") != "cross-site"
+	}
+	parsed, err := url.Parse(origin)
+	return err == nil && parsed.Host == r.Host
+}
+
+func (ui *WebUI) login(w http.ResponseWriter, r *http.Request) {
+	page := webPage{Title: "Вход"}
+
+	if r.Method == http.MethodPost {
+		if _, ok := findToken(ui.Tokens, r.PostFormValue("token")); ok {
+			http.SetCookie(w, &http.Cookie{
+				Name:     webCookie,
+				Value:    r.PostFormValue("token"),
+				Path:     "/",
+				HttpOnly: true,
+				Secure:   r.TLS != nil,
+				SameSite: http.SameSiteStrictMode,
+			})
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+		page.Error = "Неверный токен"
+		w.WriteHeader(http.StatusUnauthorized)
+	}
+	ui.render(w, "login", page)
+}
+
+// fail показывает страницу с ошибкой и HTTP-статусом из apiError.
+func (ui *WebUI) fail(w http.ResponseWriter, page webPage, err error) {
+	status := http.StatusInternalServerError
+	var apiErr *apiError
+	switch {
+	case errors.As(err, &apiErr):
+		status = apiErr.status
+	case errors.Is(err, ErrLocked):
+		status = http.StatusConflict
+	}
+	page.Title = "Ошибка"
+	page.Error = err.Error()
+	w.WriteHeader(status)
+	ui.render(w, "error", page)
+}
+
+func (ui *WebUI) render(w http.ResponseWriter, name string, data interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := webTemplates.ExecuteTemplate(w, name, data); err != nil {
+		// заголовки уже отправлены, остается оборвать страницу
+		fmt.Fprintf(w, "<p>ошибка шаблона: %s</p>", template.HTMLEscapeString(err.Error()))
+	}
+}
+
+// redirect возвращает на страницу с сообщением об успешном действии.
+func redirect(w http.ResponseWriter, r *http.Request, path, message string) {
+	http.Redirect(w, r, path+"?msg="+url.QueryEscape(message), http.StatusSeeOther)
+}
+
+// webWeek - неделя из истории для показа.
+type webWeek struct {
+	Schedule  WeekSchedule
+	Found     bool
+	Planned   bool
+	Published *Publication
+}
+
+// webEmployee - строка таблицы сотрудников.
+type webEmployee struct {
+	Employee
+	StatusToday string
+	Counts      []int // по порядку видов дежурств
+}
+
+func (ui *WebUI) scheduler() *Scheduler {
+	return ui.Service.Scheduler(ui.Clock)
+}
+
+func (ui *WebUI) week(storage *DutyHistoryStorage, start time.Time) webWeek {
+	s := ui.scheduler()
+	week := webWeek{Schedule: s.WeekSchedule(start, nil)}
+	if record, ok := storage.FindWeek(start); ok {
+		week = webWeek{Schedule: s.HistorySchedule(*record), Found: true, Planned: record.Planned(), Published: record.Publication}
+	}
+	return week
+}
+
+func (ui *WebUI) employees(employees *[]Employee) []webEmployee {
+	var result []webEmployee
+	today := ui.Clock.Now()
+	for _, employee := range *employees {
+		if employee.Status == StatusFired {
+			continue
+		}
+		row := webEmployee{Employee: employee, StatusToday: employee.StatusOn(today)}
+		for _, dutyType := range ui.Service.Config.DutyTypes {
+			row.Counts = append(row.Counts, employee.Duties[dutyType.Name].Count)
+		}
+		result = append(result, row)
+	}
+	return result
+}
+
+func (ui *WebUI) index(w http.ResponseWriter, page webPage) error {
+	data := struct {
+		webPage
+		Current, Next webWeek
+		DutyTypes     []DutyType
+		Employees     []webEmployee
+		Statuses      []string
+		Today         string
+	}{webPage: page, DutyTypes: ui.Service.Config.DutyTypes, Statuses: []string{StatusAvailable, StatusSick, StatusVacation}}
+	data.Title = "Дежурства"
+	data.Today = ui.Clock.Now().Format("2006-01-02")
+
+	err := ui.Service.View(func(state *State) error {
+		data.Current = ui.week(state.History, WeekStart(ui.Clock.Now()))
+		data.Next = ui.week(state.History, ui.scheduler().NextWeek())
+		data.Employees = ui.employees(state.Employees)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	ui.render(w, "index", data)
+	return nil
+}
+
+// calendarDay - клетка календаря.
+type calendarDay struct {
+	Date        time.Time
+	Holiday     string
+	Assignments []ScheduleLine
+}
+
+// calendarWeek - строка календаря.
+type calendarWeek struct {
+	Start   time.Time
+	Planned bool
+	Days    [7]calendarDay
+}
+
+// history показывает календарь дежурств за последние недели (?weeks=N, по умолчанию 8).
+func (ui *WebUI) history(w http.ResponseWriter, r *http.Request, page webPage) error {
+	weeks := 8
+	if value := r.URL.Query().Get("weeks"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 520 {
+			return badRequest("weeks: ожидается число от 1 до 520")
+		}
+		weeks = n
+	}
+
+	data := struct {
+		webPage
+		Weeks    []calendarWeek
+		Weekdays []string
+		Shown    int
+	}{webPage: page, Weekdays: []string{"Пн", "Вт", "Ср", "Чт", "Пт", "Сб", "Вс"}, Shown: weeks}
+	data.Title = "Календарь дежурств"
+
+	s := ui.scheduler()
+	from := WeekStart(ui.Clock.Now()).AddDate(0, 0, -7*(weeks-1))
+	err := ui.Service.View(func(state *State) error {
+		records := append([]DutyHistory(nil), state.History.History...)
+		sort.Slice(records, func(i, j int) bool { return records[i].Date.After(records[j].Date) })
+		for _, record := range records {
+			if record.Date.Before(from) {
+				continue
+			}
+			week := calendarWeek{Start: record.Date, Planned: record.Planned()}
+			for i := range week.Days {
+				week.Days[i].Date = record.Date.AddDate(0, 0, i)
+				if holiday, ok := s.Calendar.Holiday(week.Days[i].Date); ok {
+					week.Days[i].Holiday = holiday.Name
+				}
+			}
+			for _, section := range s.HistorySchedule(record).Sections {
+				for _, line := range section.Lines {
+					if line.DayOff {
+						continue
+					}
+					day := int(dayOf(line.Date).Sub(record.Date).Hours() / 24)
+					if day >= 0 && day < 7 {
+						week.Days[day].Assignments = append(week.Days[day].Assignments, line)
+					}
+				}
+			}
+			data.Weeks = append(data.Weeks, week)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	ui.render(w, "history", data)
+	return nil
+}
+
+// slotChange - изменение одного места в расписании недели.
+type slotChange struct {
+	DutyTitle string
+	Date      time.Time
+	Before    string // пусто - места не было
+	After     string // пусто - место убрано
+}
+
+// counterChange - изменение счетчика сотрудника.
+type counterChange struct {
+	Name          string
+	DutyTitle     string
+	Before, After int
+}
+
+// weekPreview - предпросмотр формирования следующей недели.
+type weekPreview struct {
+	Schedule    WeekSchedule
+	Replaces    bool // неделя уже есть в истории и будет перегенерирована
+	Published   bool // прошлый вариант уже опубликован
+	Slots       []slotChange
+	Counters    []counterChange
+	Reseted     bool
+	Fingerprint string
+}
+
+// previewWeek формирует следующую неделю на копии данных и сравнивает результат с тем, что уже есть.
+func (ui *WebUI) previewWeek(state *State) (weekPreview, error) {
+	s := ui.scheduler()
+	var preview weekPreview
+	var old []Assignment
+	if record, ok := state.History.FindWeek(s.NextWeek()); ok {
+		old = record.Assignments
+		preview.Replaces = true
+		preview.Published = record.Publication != nil
+	}
+	before := map[int]map[string]int{}
+	for _, employee := range *state.Employees {
+		before[employee.Id] = map[string]int{}
+		for name, stats := range employee.Duties {
+			before[employee.Id][name] = stats.Count
+		}
+	}
+
+	schedule, reseted, err := s.GenerateWeek(state.Employees, state.History)
+	if err != nil {
+		return preview, invalid(err)
+	}
+	preview.Schedule = schedule
+	preview.Reseted = reseted
+	preview.Slots = diffAssignments(s, old, schedule.Assignments)
+	preview.Fingerprint = fingerprint(schedule.Assignments)
+
+	for _, employee := range *state.Employees {
+		for _, dutyType := range s.DutyTypes {
+			was, now := before[employee.Id][dutyType.Name], employee.Duties[dutyType.Name].Count
+			if was != now {
+				preview.Counters = append(preview.Counters, counterChange{Name: employee.Name, DutyTitle: dutyType.Title, Before: was, After: now})
+			}
+		}
+	}
+	return preview, nil
+}
+
+// diffAssignments сравнивает назначения по местам: вид дежурства, день и номер места в этот день.
+func diffAssignments(s *Scheduler, old, new []Assignment) []slotChange {
+	type slotKey struct {
+		duty  string
+		date  time.Time
+		place int
+	}
+	index := func(assignments []Assignment) (map[slotKey]string, []slotKey) {
+		names := map[slotKey]string{}
+		var keys []slotKey
+		places := map[string]int{}
+		for _, assignment := range assignments {
+			counter := assignment.Duty + assignment.Date.Format("20060102")
+			key := slotKey{duty: assignment.Duty, date: dayOf(assignment.Date), place: places[counter]}
+			places[counter]++
+			names[key] = assignment.EmployeeName
+			keys = append(keys, key)
+		}
+		return names, keys
+	}
+
+	oldNames, oldKeys := index(old)
+	newNames, newKeys := index(new)
+	var changes []slotChange
+	for _, key := range newKeys {
+		changes = append(changes, slotChange{DutyTitle: s.DutyTitle(key.duty), Date: key.date, Before: oldNames[key], After: newNames[key]})
+	}
+	for _, key := range oldKeys {
+		if _, ok := newNames[key]; !ok {
+			changes = append(changes, slotChange{DutyTitle: s.DutyTitle(key.duty), Date: key.date, Before: oldNames[key]})
+		}
+	}
+	return changes
+}
+
+// fingerprint - отпечаток назначений, чтобы утвердить именно то расписание, которое было в предпросмотре.
+func fingerprint(assignments []Assignment) string {
+	hash := sha256.New()
+	for _, assignment := range assignments {
+		fmt.Fprintf(hash, "%s|%s|%d\n", assignment.Duty, assignment.Date.Format("2006-01-02"), assignment.EmployeeId)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+func (ui *WebUI) preview(w http.ResponseWriter, page webPage, notice string) error {
+	data := struct {
+		webPage
+		Preview weekPreview
+	}{webPage: page}
+	data.Title = "Следующая неделя"
+	if notice != "" {
+		data.Error = notice
+	}
+
+	err := ui.Service.View(func(state *State) error {
+		var err error
+		data.Preview, err = ui.previewWeek(state)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	ui.render(w, "preview", data)
+	return nil
+}
+
+// errStalePreview - данные изменились после предпросмотра.
+var errStalePreview = errors.New("расписание изменилось после предпросмотра")
+
+// generate утверждает следующую неделю, если она совпадает с показанной в предпросмотре.
+func (ui *WebUI) generate(w http.ResponseWriter, r *http.Request, page webPage) error {
+	expected := r.PostFormValue("fingerprint")
+	err := ui.Service.Update(page.actor, func(state *State) error {
+		preview, err := ui.previewWeek(state)
+		if err != nil {
+			return err
+		}
+		if preview.Fingerprint != expected {
+			return errStalePreview
+		}
+		return nil
+	})
+	if errors.Is(err, errStalePreview) {
+		return ui.preview(w, page, "Пока вы смотрели предпросмотр, данные изменились. Проверьте новое расписание и утвердите еще раз.")
+	}
+	if err != nil {
+		return err
+	}
+	redirect(w, r, "/", "Расписание на следующую неделю сохранено")
+	return nil
+}
+
+// employeeAction выполняет формы сотрудника: /employees/N/status, /employees/N/absences,
+// /employees/N/absences/I/delete.
+func (ui *WebUI) employeeAction(w http.ResponseWriter, r *http.Request, page webPage) error {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 3 {
+		return notFound("страница %s не найдена", r.URL.Path)
+	}
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return notFound("неверный Id сотрудника: %s", parts[1])
+	}
+
+	var message string
+	var action func(state *State) error
+	switch {
+	case len(parts) == 3 && parts[2] == "status":
+		status := r.PostFormValue("status")
+		if !ValidStatus(status) {
+			return badRequest("неизвестный статус %q", status)
+		}
+		action = func(state *State) error {
+			return invalid(ui.scheduler().UpdateEmployeeStatus(state.Employees, id, status))
+		}
+		message = "Статус изменен"
+	case len(parts) == 3 && parts[2] == "absences":
+		absence := Absence{Kind: r.PostFormValue("kind")}
+		if absence.Start, err = parseAPIDate("start", r.PostFormValue("start"), true); err != nil {
+			return err
+		}
+		if absence.End, err = parseAPIDate("end", r.PostFormValue("end"), false); err != nil {
+			return err
+		}
+		action = func(state *State) error {
+			return invalid(AddEmployeeAbsence(state.Employees, id, absence))
+		}
+		message = "Отсутствие добавлено"
+	case len(parts) == 5 && parts[2] == "absences" && parts[4] == "delete":
+		index, err := strconv.Atoi(parts[3])
+		if err != nil {
+			return notFound("неверный номер отсутствия: %s", parts[3])
+		}
+		action = func(state *State) error {
+			return invalid(RemoveEmployeeAbsence(state.Employees, id, index))
+		}
+		message = "Отсутствие удалено"
+	default:
+		return notFound("страница %s не найдена", r.URL.Path)
+	}
+
+	if err := ui.Service.Update(page.actor, func(state *State) error {
+		if FindEmployeeById(state.Employees, id) == nil {
+			return notFound("сотрудник с Id: %d не найден", id)
+		}
+		return action(state)
+	}); err != nil {
+		return err
+	}
+	redirect(w, r, "/", message)
+	return nil
+}